@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DeprecatedAlias is a former name of an Option which is still honored after the option is renamed, so deployments can
+// migrate to the new name at their own pace. Using an alias produces a warning, reported by Registry.Warnings.
+type DeprecatedAlias struct {
+	// Name is the former name of the variable
+	Name string
+	// RemovalDate is the date after which using the alias is a configuration error rather than a warning. The zero
+	// value means the alias is honored indefinitely.
+	RemovalDate time.Time
+	// Translate converts a value of the alias into a value of the option, for renames which also change the format of
+	// the value. It's optional, and values are used unchanged when it's nil.
+	Translate func(value string) (string, error)
+}
+
+// AddDeprecatedAlias registers a former name of the option which is still honored. Setting more than one of the
+// option's names is a configuration error. See DeprecatedAlias for more information.
+func (opt *Option) AddDeprecatedAlias(alias DeprecatedAlias) {
+	// Copy the aliases so options copied from this one don't share additions
+	opt.deprecatedAliases = append(append([]DeprecatedAlias{}, opt.deprecatedAliases...), alias)
+}
+
+// WithDeprecatedAlias returns a copy of the option with the passed former name. See AddDeprecatedAlias for more
+// information.
+func (opt Option) WithDeprecatedAlias(alias DeprecatedAlias) Option {
+	opt.AddDeprecatedAlias(alias)
+	return opt
+}
+
+// WithDeprecatedAlias returns a copy of the option with the passed former name. See Option.AddDeprecatedAlias for more
+// information.
+func (opt TypedOption[T]) WithDeprecatedAlias(alias DeprecatedAlias) TypedOption[T] {
+	opt.AddDeprecatedAlias(alias)
+	return opt
+}
+
+// lookupDeprecatedAlias finds the value of an option under one of its deprecated aliases, for use when the option's
+// own name isn't set. It returns a warning about the alias if one was used. The returned InvalidVariable is non-nil if
+// several names are set or the alias used is past its removal date.
+func lookupDeprecatedAlias(option Option, layeredValues map[string]resolvedValue, now time.Time) (resolvedValue, bool, string, *InvalidVariable) {
+	var setNames []string
+	if _, optionPresent := layeredValues[option.envName]; optionPresent {
+		setNames = append(setNames, option.envName)
+	}
+	var usedAlias *DeprecatedAlias
+	for aliasIndex, alias := range option.deprecatedAliases {
+		if _, aliasPresent := layeredValues[alias.Name]; aliasPresent {
+			setNames = append(setNames, alias.Name)
+			usedAlias = &option.deprecatedAliases[aliasIndex]
+		}
+	}
+
+	if len(setNames) > 1 {
+		return resolvedValue{}, false, "", &InvalidVariable{
+			Name:            option.envName,
+			ValidationError: fmt.Errorf("%v are all set, but they're names of the same option and only one may be used", strings.Join(setNames, ", ")),
+		}
+	}
+	if usedAlias == nil || len(setNames) == 0 || setNames[0] == option.envName {
+		return resolvedValue{}, false, "", nil
+	}
+
+	if !usedAlias.RemovalDate.IsZero() && now.After(usedAlias.RemovalDate) {
+		return resolvedValue{}, false, "", &InvalidVariable{
+			Name: usedAlias.Name,
+			ValidationError: fmt.Errorf("%v was removed on %v, use %v instead", usedAlias.Name,
+				usedAlias.RemovalDate.Format(time.DateOnly), option.envName),
+		}
+	}
+
+	resolved := layeredValues[usedAlias.Name]
+	if usedAlias.Translate != nil {
+		translatedValue, translateErr := usedAlias.Translate(resolved.value)
+		if translateErr != nil {
+			return resolvedValue{}, false, "", &InvalidVariable{Name: usedAlias.Name, ValidationError: translateErr}
+		}
+		resolved.value = translatedValue
+	}
+	resolved.sourceName = fmt.Sprintf("%v via deprecated %v", resolved.sourceName, usedAlias.Name)
+
+	warning := fmt.Sprintf("%v is deprecated, use %v instead", usedAlias.Name, option.envName)
+	if !usedAlias.RemovalDate.IsZero() {
+		warning += fmt.Sprintf(". %v will stop working after %v", usedAlias.Name, usedAlias.RemovalDate.Format(time.DateOnly))
+	}
+	return resolved, true, warning, nil
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AliasSuite struct {
+	suite.Suite
+}
+
+func TestAliasSuite(t *testing.T) {
+	suite.Run(t, new(AliasSuite))
+}
+
+func (suite *AliasSuite) build(option AnyOption, environment map[string]string) (Registry, error) {
+	builder := NewMockRegistryBuilder(environment)
+	builder.AddOption(option)
+	return builder.VerifyAndBuild()
+}
+
+func (suite *AliasSuite) TestAliasIsHonoredWithWarning() {
+	option := NewIntOption("LISTEN_PORT", true).WithDeprecatedAlias(DeprecatedAlias{Name: "PORT"})
+	registry, buildErr := suite.build(option, map[string]string{"PORT": "9090"})
+	suite.Require().NoError(buildErr)
+
+	suite.Assert().Equal(9090, GetRequiredTyped(registry, option))
+	sourceName, _ := registry.SourceOf(option)
+	suite.Assert().Equal("mock via deprecated PORT", sourceName)
+	suite.Assert().Equal([]string{"PORT is deprecated, use LISTEN_PORT instead"}, registry.Warnings())
+}
+
+func (suite *AliasSuite) TestNewNameDoesNotWarn() {
+	option := NewIntOption("LISTEN_PORT", true).WithDeprecatedAlias(DeprecatedAlias{Name: "PORT"})
+	registry, buildErr := suite.build(option, map[string]string{"LISTEN_PORT": "9090"})
+	suite.Require().NoError(buildErr)
+
+	suite.Assert().Empty(registry.Warnings())
+}
+
+func (suite *AliasSuite) TestBothNamesAreInvalid() {
+	option := NewIntOption("LISTEN_PORT", true).WithDeprecatedAlias(DeprecatedAlias{Name: "PORT"})
+	_, buildErr := suite.build(option, map[string]string{"LISTEN_PORT": "9090", "PORT": "9091"})
+
+	var configErr ErrIncorrectConfiguration
+	suite.Require().ErrorAs(buildErr, &configErr)
+	suite.Require().Len(configErr.InvalidVariables, 1)
+	suite.Assert().Equal("LISTEN_PORT", configErr.InvalidVariables[0].Name)
+	suite.Assert().Empty(configErr.MissingRequiredVariables)
+}
+
+func (suite *AliasSuite) TestRemovalDate() {
+	suite.Run("Before removal", func() {
+		removalDate := time.Now().AddDate(0, 1, 0)
+		option := NewOption("NEW_NAME", true).WithDeprecatedAlias(DeprecatedAlias{Name: "OLD_NAME", RemovalDate: removalDate})
+		registry, buildErr := suite.build(option, map[string]string{"OLD_NAME": "value"})
+		suite.Require().NoError(buildErr)
+
+		suite.Require().Len(registry.Warnings(), 1)
+		suite.Assert().True(strings.HasSuffix(registry.Warnings()[0], removalDate.Format(time.DateOnly)))
+	})
+
+	suite.Run("After removal", func() {
+		option := NewOption("NEW_NAME", true).
+			WithDeprecatedAlias(DeprecatedAlias{Name: "OLD_NAME", RemovalDate: time.Now().AddDate(0, -1, 0)})
+		_, buildErr := suite.build(option, map[string]string{"OLD_NAME": "value"})
+
+		var configErr ErrIncorrectConfiguration
+		suite.Require().ErrorAs(buildErr, &configErr)
+		suite.Require().Len(configErr.InvalidVariables, 1)
+		suite.Assert().Equal("OLD_NAME", configErr.InvalidVariables[0].Name)
+	})
+}
+
+func (suite *AliasSuite) TestValuesAreTranslated() {
+	option := NewEnumOption("APP_MODE", true, flavor("fast"), flavor("safe")).WithDeprecatedAlias(DeprecatedAlias{
+		Name: "SAFE_MODE",
+		Translate: func(value string) (string, error) {
+			if value == "true" {
+				return "safe", nil
+			}
+			return "fast", nil
+		},
+	})
+	registry, buildErr := suite.build(option, map[string]string{"SAFE_MODE": "true"})
+	suite.Require().NoError(buildErr)
+
+	suite.Assert().Equal(flavor("safe"), GetRequiredTyped(registry, option))
+}
+
+func (suite *AliasSuite) TestAliasesAppearInSchema() {
+	option := NewOption("NEW_NAME", false).WithDeprecatedAlias(DeprecatedAlias{Name: "OLD_NAME"})
+	builder := NewMockRegistryBuilder(nil)
+	builder.AddOption(option)
+
+	suite.Assert().Equal([]string{"OLD_NAME"}, builder.Schema()[0].DeprecatedAliases)
+	suite.Assert().Contains(GenerateMarkdown(builder.Schema()), "Formerly `OLD_NAME`")
+}
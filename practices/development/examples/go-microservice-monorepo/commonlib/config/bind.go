@@ -0,0 +1,421 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jellydator/validation"
+	"github.com/jellydator/validation/is"
+)
+
+// Struct tags read by Bind and AddStruct
+const (
+	// envTag names the variable holding a field, optionally followed by ",required"
+	envTag = "env"
+	// defaultTag declares the default value of a field
+	defaultTag = "default"
+	// validateTag lists comma-separated validation rules for a field
+	validateTag = "validate"
+	// descriptionTag describes what a field is for
+	descriptionTag = "desc"
+	// prefixTag prefixes the variable names of every field in a nested struct
+	prefixTag = "envPrefix"
+)
+
+// Types which bound fields get special treatment for
+var (
+	secretType          = reflect.TypeOf(Secret(""))
+	durationType        = reflect.TypeOf(time.Duration(0))
+	urlType             = reflect.TypeOf(&url.URL{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// boundField is a struct field bound to a configuration option
+type boundField struct {
+	// index is the path of field indices leading from the top-level struct to the field
+	index []int
+	// option is the option derived from the field's tags
+	option Option
+}
+
+// Bind registers an option for every tagged field of the struct pointed to by target, verifies the options against the
+// process environment, and populates the struct with the verified values. Fields are tagged like this:
+//
+//	type ServiceConfig struct {
+//		Host    string        `env:"SERVICE_HOST,required" validate:"host" desc:"Where the service is hosted"`
+//		Timeout time.Duration `env:"SERVICE_TIMEOUT" default:"5s" validate:"min=1s"`
+//		APIKey  config.Secret `env:"SERVICE_API_KEY,required"`
+//	}
+//
+// See AddStruct for the supported field types and tags. An ErrIncorrectConfiguration is returned if the configuration
+// is invalid, and a different error if the struct itself can't be bound.
+func Bind(target any) error {
+	builder := NewRegistryBuilder()
+	_, bindErr := BindWith(&builder, target)
+	return bindErr
+}
+
+// BindWith is like Bind, except the options are registered with the passed builder, so they're read from the builder's
+// sources and verified alongside any options and rules already registered with it. The built registry is returned so
+// other registered options remain available.
+func BindWith(builder *RegistryBuilder, target any) (Registry, error) {
+	if addErr := builder.AddStruct(target); addErr != nil {
+		return Registry{}, addErr
+	}
+
+	registry, buildErr := builder.VerifyAndBuild()
+	if buildErr != nil {
+		return Registry{}, buildErr
+	}
+
+	return registry, Populate(registry, target)
+}
+
+// AddStruct registers an option for every field of the struct pointed to by target which has an env tag. The tags are:
+//
+//   - env: the name of the variable, optionally followed by ",required"
+//   - default: the default value of the variable
+//   - validate: comma-separated validation rules, which are "host", "port", "oneof=a|b|c", "min=N" and "max=N". For
+//     numbers and durations, min and max bound the value; for strings and lists, they bound its length.
+//   - desc: a description of the variable for the registry schema
+//
+// Supported field types are strings, integers, booleans, time.Duration, *url.URL, []string, Secret, types implementing
+// encoding.TextUnmarshaler, and types derived from strings and integers. A pointer to one of these, except *url.URL,
+// is left nil when the variable is absent. Fields of nested structs without an env tag are bound as well. An envPrefix
+// tag on a nested struct prefixes the variable names of its fields like Option.WithPrefix, so the same struct can
+// configure several instances of a subsystem, such as two API client settings structs tagged envPrefix:"REPORTING".
+func (rb *RegistryBuilder) AddStruct(target any) error {
+	fields, fieldsErr := boundFieldsOf(target)
+	if fieldsErr != nil {
+		return fieldsErr
+	}
+
+	for _, field := range fields {
+		rb.AddOption(field.option)
+	}
+	return nil
+}
+
+// Populate sets every tagged field of the struct pointed to by target to the value of its option in the passed
+// registry. Fields of absent options are set to their zero value. The struct's options must have been registered with
+// the builder of the registry, which Bind, BindWith and AddStruct do. Populate is handy for refreshing a bound struct
+// from a ReloadableRegistry subscription. An ErrIncorrectConfiguration is returned if a value is out of the range of its
+// field's type, such as 300 for an int8 field, leaving that field unchanged.
+func Populate(registry Registry, target any) error {
+	fields, fieldsErr := boundFieldsOf(target)
+	if fieldsErr != nil {
+		return fieldsErr
+	}
+
+	var variableIssues ErrIncorrectConfiguration
+	targetValue := reflect.ValueOf(target).Elem()
+	for _, field := range fields {
+		option := registry.mustBeRegistered(field.option)
+		fieldValue := targetValue.FieldByIndex(field.index)
+
+		resolved, isPresent := registry.values[option.envName]
+		if !isPresent {
+			fieldValue.Set(reflect.Zero(fieldValue.Type()))
+			continue
+		}
+
+		var value reflect.Value
+		if resolved.parsed != nil {
+			value = reflect.ValueOf(resolved.parsed)
+		} else {
+			value = reflect.ValueOf(resolved.value)
+		}
+		if setErr := setFieldValue(fieldValue, value); setErr != nil {
+			variableIssues.InvalidVariables = append(variableIssues.InvalidVariables, InvalidVariable{
+				Name:            option.envName,
+				ValidationError: setErr,
+			})
+		}
+	}
+
+	if variableIssues.errorsPresent() {
+		return variableIssues
+	}
+	return nil
+}
+
+// setFieldValue sets a field to a parsed option value, converting the value to the field's type and allocating a
+// pointer for optional fields. An error is returned, and the field left unchanged, if the value is out of the range of
+// the field's type.
+func setFieldValue(fieldValue reflect.Value, value reflect.Value) error {
+	fieldType := fieldValue.Type()
+	if fieldType.Kind() == reflect.Pointer && fieldType != urlType {
+		pointer := reflect.New(fieldType.Elem())
+		if overflowErr := checkOverflow(pointer.Elem(), value); overflowErr != nil {
+			return overflowErr
+		}
+		pointer.Elem().Set(value.Convert(fieldType.Elem()))
+		fieldValue.Set(pointer)
+		return nil
+	}
+
+	if overflowErr := checkOverflow(fieldValue, value); overflowErr != nil {
+		return overflowErr
+	}
+	fieldValue.Set(value.Convert(fieldType))
+	return nil
+}
+
+// checkOverflow returns an error if a numeric value doesn't fit in the type of the field it's about to be set to, as
+// converting it would silently truncate it
+func checkOverflow(fieldValue reflect.Value, value reflect.Value) error {
+	overflows := false
+	switch {
+	case fieldValue.CanInt() && value.CanInt():
+		overflows = fieldValue.OverflowInt(value.Int())
+	case fieldValue.CanUint() && value.CanUint():
+		overflows = fieldValue.OverflowUint(value.Uint())
+	case fieldValue.CanFloat() && value.CanFloat():
+		overflows = fieldValue.OverflowFloat(value.Float())
+	}
+	if overflows {
+		return fmt.Errorf("%v is out of the range of %v", value, fieldValue.Type())
+	}
+	return nil
+}
+
+// boundFieldsOf derives the options bound to the fields of the struct pointed to by target
+func boundFieldsOf(target any) ([]boundField, error) {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Pointer || targetValue.IsNil() || targetValue.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("could not bind configuration to %T, it must be a non-nil pointer to a struct", target)
+	}
+
+	return boundFieldsOfStruct(targetValue.Elem().Type(), nil, "")
+}
+
+// boundFieldsOfStruct derives the options bound to the fields of a struct type, whose fields are found at the passed
+// index path from the top-level struct. Variable names are prefixed with the passed prefix.
+func boundFieldsOfStruct(structType reflect.Type, parentIndex []int, prefix string) ([]boundField, error) {
+	var fields []boundField
+	for fieldNumber := 0; fieldNumber < structType.NumField(); fieldNumber++ {
+		structField := structType.Field(fieldNumber)
+		index := append(append([]int{}, parentIndex...), fieldNumber)
+
+		envValue, hasEnvTag := structField.Tag.Lookup(envTag)
+		if !hasEnvTag || envValue == "-" {
+			if structField.IsExported() && structField.Type.Kind() == reflect.Struct && envValue != "-" {
+				nestedPrefix := prefix
+				if fieldPrefix := structField.Tag.Get(prefixTag); fieldPrefix != "" {
+					nestedPrefix = prefixedName(prefix, fieldPrefix)
+				}
+				nestedFields, nestedErr := boundFieldsOfStruct(structField.Type, index, nestedPrefix)
+				if nestedErr != nil {
+					return nil, nestedErr
+				}
+				fields = append(fields, nestedFields...)
+			}
+			continue
+		}
+		if !structField.IsExported() {
+			return nil, fmt.Errorf("could not bind configuration to unexported field %v", structField.Name)
+		}
+
+		option, optionErr := optionForField(structField, envValue, prefix)
+		if optionErr != nil {
+			return nil, fmt.Errorf("could not bind configuration to field %v: %w", structField.Name, optionErr)
+		}
+		fields = append(fields, boundField{index: index, option: option})
+	}
+
+	return fields, nil
+}
+
+// optionForField constructs the option bound to a struct field from the field's type and tags, prefixing its variable
+// name with the passed prefix
+func optionForField(structField reflect.StructField, envValue string, prefix string) (Option, error) {
+	envName, modifiers, _ := strings.Cut(envValue, ",")
+	if envName == "" {
+		return Option{}, fmt.Errorf("the %v tag must name a variable", envTag)
+	}
+	envName = prefixedName(prefix, envName)
+	required := false
+	for _, modifier := range strings.Split(modifiers, ",") {
+		switch modifier {
+		case "":
+		case "required":
+			required = true
+		default:
+			return Option{}, fmt.Errorf("unknown %v tag modifier %q", envTag, modifier)
+		}
+	}
+
+	option, valueType, optionErr := optionForType(structField.Type, envName, required)
+	if optionErr != nil {
+		return Option{}, optionErr
+	}
+
+	if defaultValue, hasDefault := structField.Tag.Lookup(defaultTag); hasDefault {
+		option.SetDefault(defaultValue)
+	}
+	if rules := structField.Tag.Get(validateTag); rules != "" {
+		validationFn, validationErr := validationForTag(rules, valueType)
+		if validationErr != nil {
+			return Option{}, validationErr
+		}
+		option.SetValidation(validationFn)
+	}
+	option.SetDescription(structField.Tag.Get(descriptionTag))
+
+	return option, nil
+}
+
+// optionForType constructs an option parsing values into the passed field type. The type values are parsed into,
+// which is the field type without any optional pointer, is returned as well.
+func optionForType(fieldType reflect.Type, envName string, required bool) (Option, reflect.Type, error) {
+	if fieldType == urlType {
+		return NewURLOption(envName, required).Option, fieldType, nil
+	}
+
+	valueType := fieldType
+	if valueType.Kind() == reflect.Pointer {
+		valueType = valueType.Elem()
+	}
+
+	switch {
+	case valueType == secretType:
+		return NewSecretOption(envName, required).Option, valueType, nil
+	case valueType == durationType:
+		return NewDurationOption(envName, required).Option, valueType, nil
+	case reflect.PointerTo(valueType).Implements(textUnmarshalerType):
+		return newTextOption(envName, required, valueType), valueType, nil
+	}
+
+	switch valueType.Kind() {
+	case reflect.String:
+		return NewOption(envName, required), valueType, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewIntOption(envName, required).Option, valueType, nil
+	case reflect.Bool:
+		return NewBoolOption(envName, required).Option, valueType, nil
+	case reflect.Slice:
+		if valueType.Elem().Kind() == reflect.String {
+			return NewStringListOption(envName, required).Option, valueType, nil
+		}
+	}
+
+	return Option{}, nil, fmt.Errorf("fields of type %v are not supported", fieldType)
+}
+
+// newTextOption constructs an option parsing values with the encoding.TextUnmarshaler implementation of a type
+func newTextOption(envName string, required bool, valueType reflect.Type) Option {
+	return NewTypedOption(envName, required, func(value string) (any, error) {
+		parsedValue := reflect.New(valueType)
+		unmarshalErr := parsedValue.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+		return parsedValue.Elem().Interface(), unmarshalErr
+	}).withTypeName(valueType.String()).Option
+}
+
+// validationForTag builds a validation function from the rules in a validate tag. Rules bounding numbers parse the raw
+// value first, leaving values which don't parse to be reported by the option's own parsing.
+func validationForTag(rules string, valueType reflect.Type) (func(string) error, error) {
+	var validationFns []func(string) error
+	for _, rule := range strings.Split(rules, ",") {
+		ruleName, argument, _ := strings.Cut(rule, "=")
+		switch ruleName {
+		case "host":
+			validationFns = append(validationFns, func(value string) error {
+				return validation.Validate(value, is.Host)
+			})
+		case "port":
+			validationFns = append(validationFns, func(value string) error {
+				return validation.Validate(value, is.Port)
+			})
+		case "oneof":
+			allowedValues := strings.Split(argument, "|")
+			validationFns = append(validationFns, func(value string) error {
+				return validation.Validate(value, validation.In(toAnySlice(allowedValues)...).
+					Error(fmt.Sprintf("value must be one of %v", strings.Join(allowedValues, ", "))))
+			})
+		case "min", "max":
+			boundFn, boundErr := boundValidation(ruleName == "min", argument, valueType)
+			if boundErr != nil {
+				return nil, boundErr
+			}
+			validationFns = append(validationFns, boundFn)
+		default:
+			return nil, fmt.Errorf("unknown %v rule %q", validateTag, rule)
+		}
+	}
+
+	return func(value string) error {
+		for _, validationFn := range validationFns {
+			if validationErr := validationFn(value); validationErr != nil {
+				return validationErr
+			}
+		}
+		return nil
+	}, nil
+}
+
+// boundValidation builds a validation function for a min or max rule. For durations and integers, the bound applies
+// to the value; for strings and lists, it applies to the length of the raw value or the number of list items.
+func boundValidation(isMinimum bool, argument string, valueType reflect.Type) (func(string) error, error) {
+	var measure func(value string) (int64, bool)
+	var bound int64
+	var boundErr error
+	switch {
+	case valueType == durationType:
+		var boundDuration time.Duration
+		boundDuration, boundErr = time.ParseDuration(argument)
+		bound = int64(boundDuration)
+		measure = func(value string) (int64, bool) {
+			duration, parseErr := time.ParseDuration(value)
+			return int64(duration), parseErr == nil
+		}
+	case valueType.Kind() >= reflect.Int && valueType.Kind() <= reflect.Int64:
+		bound, boundErr = strconv.ParseInt(argument, 10, 64)
+		measure = func(value string) (int64, bool) {
+			number, parseErr := strconv.ParseInt(value, 10, 64)
+			return number, parseErr == nil
+		}
+	case valueType.Kind() == reflect.String || valueType.Kind() == reflect.Slice:
+		bound, boundErr = strconv.ParseInt(argument, 10, 64)
+		isList := valueType.Kind() == reflect.Slice
+		measure = func(value string) (int64, bool) {
+			if isList {
+				items, _ := parseStringList(value)
+				return int64(len(items)), true
+			}
+			return int64(len(value)), true
+		}
+	default:
+		return nil, fmt.Errorf("min and max rules are not supported for %v values", valueType)
+	}
+	if boundErr != nil {
+		return nil, fmt.Errorf("invalid bound %q: %w", argument, boundErr)
+	}
+
+	return func(value string) error {
+		measured, measurable := measure(value)
+		if !measurable {
+			return nil
+		}
+		if isMinimum && measured < bound {
+			return fmt.Errorf("must be no less than %v", argument)
+		}
+		if !isMinimum && measured > bound {
+			return fmt.Errorf("must be no greater than %v", argument)
+		}
+		return nil
+	}, nil
+}
+
+// toAnySlice converts a slice of strings to a slice of empty interfaces, as accepted by validation.In
+func toAnySlice(values []string) []any {
+	anyValues := make([]any, 0, len(values))
+	for _, value := range values {
+		anyValues = append(anyValues, value)
+	}
+	return anyValues
+}
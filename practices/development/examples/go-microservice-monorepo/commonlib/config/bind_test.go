@@ -0,0 +1,163 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap/zapcore"
+)
+
+type BindSuite struct {
+	suite.Suite
+}
+
+func TestBindSuite(t *testing.T) {
+	suite.Run(t, new(BindSuite))
+}
+
+type boundDatabaseSettings struct {
+	Port        *int   `env:"DB_PORT" validate:"port"`
+	Connections int    `env:"DB_CONNECTIONS" default:"20" validate:"min=1,max=100"`
+	Schema      string `env:"DB_SCHEMA,required"`
+}
+
+type boundServiceConfig struct {
+	Host      string        `env:"HOST,required" validate:"host" desc:"Where the service is hosted"`
+	Timeout   time.Duration `env:"TIMEOUT" default:"5s" validate:"min=1s"`
+	Debug     bool          `env:"DEBUG"`
+	Origins   []string      `env:"ORIGINS"`
+	APIKey    Secret        `env:"API_KEY,required"`
+	Level     zapcore.Level `env:"LEVEL" default:"info"`
+	Flavor    flavor        `env:"FLAVOR" validate:"oneof=chocolate|vanilla"`
+	Database  boundDatabaseSettings
+	Unrelated string
+}
+
+func (suite *BindSuite) TestBindPopulatesStruct() {
+	builder := NewMockRegistryBuilder(map[string]string{
+		"HOST":      "example.com",
+		"DEBUG":     "true",
+		"ORIGINS":   "a,b",
+		"API_KEY":   "hunter2",
+		"LEVEL":     "debug",
+		"FLAVOR":    "vanilla",
+		"DB_SCHEMA": "test",
+	})
+	var serviceConfig boundServiceConfig
+	registry, bindErr := BindWith(&builder, &serviceConfig)
+	suite.Require().NoError(bindErr)
+
+	suite.Assert().Equal(boundServiceConfig{
+		Host:     "example.com",
+		Timeout:  5 * time.Second,
+		Debug:    true,
+		Origins:  []string{"a", "b"},
+		APIKey:   Secret("hunter2"),
+		Level:    zapcore.DebugLevel,
+		Flavor:   flavor("vanilla"),
+		Database: boundDatabaseSettings{Connections: 20, Schema: "test"},
+	}, serviceConfig)
+
+	// The options are registered like any other, so they show up in the schema
+	schema := registry.Schema()
+	suite.Require().Len(schema, 10)
+	suite.Assert().Equal("Where the service is hosted", schema[0].Description)
+	suite.Assert().True(schema[4].Secret)
+}
+
+func (suite *BindSuite) TestBindReportsInvalidConfiguration() {
+	builder := NewMockRegistryBuilder(map[string]string{
+		"HOST":           "not a host!",
+		"TIMEOUT":        "1ms",
+		"FLAVOR":         "mayonnaise",
+		"DB_PORT":        "99999",
+		"DB_CONNECTIONS": "0",
+		"DB_SCHEMA":      "test",
+	})
+	var serviceConfig boundServiceConfig
+	_, bindErr := BindWith(&builder, &serviceConfig)
+
+	var configErr ErrIncorrectConfiguration
+	suite.Require().ErrorAs(bindErr, &configErr)
+	suite.Assert().Equal([]string{"API_KEY"}, configErr.MissingRequiredVariables)
+	suite.Assert().Len(configErr.InvalidVariables, 5)
+}
+
+func (suite *BindSuite) TestBindReportsValuesOutOfRange() {
+	builder := NewMockRegistryBuilder(map[string]string{"RETRIES": "300", "WORKERS": "-129"})
+	var settings struct {
+		Retries int8  `env:"RETRIES"`
+		Workers *int8 `env:"WORKERS"`
+	}
+	_, bindErr := BindWith(&builder, &settings)
+
+	var configErr ErrIncorrectConfiguration
+	suite.Require().ErrorAs(bindErr, &configErr)
+	suite.Require().Len(configErr.InvalidVariables, 2)
+	suite.Assert().Equal("RETRIES", configErr.InvalidVariables[0].Name)
+	suite.Assert().EqualError(configErr.InvalidVariables[0].ValidationError, "300 is out of the range of int8")
+	suite.Assert().Equal("WORKERS", configErr.InvalidVariables[1].Name)
+	suite.Assert().Zero(settings.Retries)
+	suite.Assert().Nil(settings.Workers)
+}
+
+func (suite *BindSuite) TestPopulateRefreshesOptionalFields() {
+	builder := NewMockRegistryBuilder(map[string]string{"DB_PORT": "3306", "DB_SCHEMA": "test"})
+	var settings boundDatabaseSettings
+	_, bindErr := BindWith(&builder, &settings)
+	suite.Require().NoError(bindErr)
+	suite.Require().NotNil(settings.Port)
+	suite.Assert().Equal(3306, *settings.Port)
+
+	otherBuilder := NewMockRegistryBuilder(map[string]string{"DB_SCHEMA": "test"})
+	suite.Require().NoError(otherBuilder.AddStruct(&settings))
+	registry, buildErr := otherBuilder.VerifyAndBuild()
+	suite.Require().NoError(buildErr)
+	suite.Require().NoError(Populate(registry, &settings))
+	suite.Assert().Nil(settings.Port)
+}
+
+func (suite *BindSuite) TestBadStructsAreRejected() {
+	subtests := []struct {
+		testName string
+		target   any
+	}{
+		{testName: "Not a pointer", target: boundDatabaseSettings{}},
+		{testName: "Unsupported type", target: &struct {
+			Ratio float64 `env:"RATIO"`
+		}{}},
+		{testName: "Unknown modifier", target: &struct {
+			Name string `env:"NAME,optional"`
+		}{}},
+		{testName: "Unknown validation rule", target: &struct {
+			Name string `env:"NAME" validate:"shiny"`
+		}{}},
+	}
+
+	for _, subtest := range subtests {
+		suite.Run(subtest.testName, func() {
+			builder := NewMockRegistryBuilder(nil)
+			addErr := builder.AddStruct(subtest.target)
+			suite.Require().Error(addErr)
+			suite.Assert().NotErrorIs(addErr, ErrIncorrectConfiguration{})
+		})
+	}
+}
+
+func (suite *BindSuite) TestPrefixedNestedStructs() {
+	type twoDatabases struct {
+		Primary   boundDatabaseSettings
+		Reporting boundDatabaseSettings `envPrefix:"REPORTING"`
+	}
+	builder := NewMockRegistryBuilder(map[string]string{
+		"DB_SCHEMA":           "main",
+		"REPORTING_DB_SCHEMA": "reports",
+	})
+	var databases twoDatabases
+	_, bindErr := BindWith(&builder, &databases)
+	suite.Require().NoError(bindErr)
+
+	suite.Assert().Equal("main", databases.Primary.Schema)
+	suite.Assert().Equal("reports", databases.Reporting.Schema)
+}
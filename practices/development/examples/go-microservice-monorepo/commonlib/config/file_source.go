@@ -0,0 +1,138 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// fileSource is a Source which reads configuration options from a YAML or JSON file
+type fileSource struct {
+	path     string
+	required bool
+}
+
+// NewFileSource constructs a Source which reads configuration options from a YAML or JSON file, picking the format
+// from the file extension. Top-level keys are environment variable names, and nested objects are flattened by joining
+// their keys with an underscore, so "db: {host: localhost}" configures DB_HOST. Lists are joined with commas. If the
+// file isn't required, a missing file is treated as an empty source.
+func NewFileSource(path string, required bool) Source {
+	return fileSource{
+		path:     path,
+		required: required,
+	}
+}
+
+// Name implements Source for fileSource
+func (src fileSource) Name() string {
+	return "file " + src.path
+}
+
+// Load implements Source for fileSource
+func (src fileSource) Load() (map[string]string, error) {
+	contents, readErr := readSourceFile(src.path, src.required)
+	if readErr != nil || contents == nil {
+		return nil, readErr
+	}
+
+	var document map[string]any
+	var parseErr error
+	switch strings.ToLower(filepath.Ext(src.path)) {
+	case ".json":
+		parseErr = json.Unmarshal(contents, &document)
+	case ".yaml", ".yml":
+		parseErr = yaml.Unmarshal(contents, &document)
+	default:
+		return nil, fmt.Errorf("unsupported configuration file format for %v, expected .json, .yaml, or .yml", src.path)
+	}
+	if parseErr != nil {
+		return nil, fmt.Errorf("could not parse %v: %w", src.path, parseErr)
+	}
+
+	values := make(map[string]string)
+	flattenDocument("", document, values)
+	return values, nil
+}
+
+// flattenDocument writes every scalar in a parsed configuration document into values, naming nested keys by joining
+// them with an underscore and converting them to upper case to match environment variable naming.
+func flattenDocument(prefix string, document map[string]any, values map[string]string) {
+	for key, rawValue := range document {
+		name := strings.ToUpper(key)
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		switch value := rawValue.(type) {
+		case map[string]any:
+			flattenDocument(name, value, values)
+		case []any:
+			var listItems []string
+			for _, item := range value {
+				listItems = append(listItems, fmt.Sprint(item))
+			}
+			values[name] = strings.Join(listItems, ",")
+		case nil:
+			// A null value in a file is treated as if the key wasn't present
+		default:
+			values[name] = fmt.Sprint(value)
+		}
+	}
+}
+
+// dotEnvSource is a Source which reads configuration options from a .env file
+type dotEnvSource struct {
+	path     string
+	required bool
+}
+
+// NewDotEnvSource constructs a Source which reads configuration options from a .env file. Unlike godotenv.Load, the
+// values are never copied into the process environment. If the file isn't required, a missing file is treated as an
+// empty source.
+func NewDotEnvSource(path string, required bool) Source {
+	return dotEnvSource{
+		path:     path,
+		required: required,
+	}
+}
+
+// Name implements Source for dotEnvSource
+func (src dotEnvSource) Name() string {
+	return "dotenv " + src.path
+}
+
+// Load implements Source for dotEnvSource
+func (src dotEnvSource) Load() (map[string]string, error) {
+	contents, readErr := readSourceFile(src.path, src.required)
+	if readErr != nil || contents == nil {
+		return nil, readErr
+	}
+
+	values, parseErr := godotenv.UnmarshalBytes(contents)
+	if parseErr != nil {
+		return nil, fmt.Errorf("could not parse %v: %w", src.path, parseErr)
+	}
+
+	return values, nil
+}
+
+// readSourceFile reads the file backing a file-based Source. If the file doesn't exist and isn't required, both
+// return values are nil.
+func readSourceFile(path string, required bool) ([]byte, error) {
+	contents, readErr := os.ReadFile(path)
+	if readErr != nil {
+		if !required && errors.Is(readErr, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, readErr
+	}
+
+	return contents, nil
+}
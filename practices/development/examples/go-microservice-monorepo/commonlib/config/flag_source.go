@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// flagSource is a Source which reads configuration options from command-line flags
+type flagSource []string
+
+// NewFlagSource constructs a Source which reads configuration options from command-line arguments, such as
+// os.Args[1:]. Flags are written as "--db-host=localhost" or "--db-host localhost" and configure the option whose
+// environment variable name matches the flag name in upper case with dashes replaced by underscores (DB_HOST in this
+// example). A flag with no value, such as "--is-production", is set to "true".
+func NewFlagSource(args []string) Source {
+	return flagSource(args)
+}
+
+// Name implements Source for flagSource
+func (src flagSource) Name() string {
+	return "flags"
+}
+
+// Load implements Source for flagSource
+func (src flagSource) Load() (map[string]string, error) {
+	values := make(map[string]string)
+	for argIndex := 0; argIndex < len(src); argIndex++ {
+		arg := src[argIndex]
+		if !strings.HasPrefix(arg, "--") || arg == "--" {
+			return nil, fmt.Errorf("unexpected command-line argument %q, expected a flag in the form --option-name=value", arg)
+		}
+
+		flagName, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !hasValue {
+			// Use the next argument as the value unless it's another flag, in which case this is a boolean flag
+			if argIndex+1 < len(src) && !strings.HasPrefix(src[argIndex+1], "--") {
+				argIndex++
+				value = src[argIndex]
+			} else {
+				value = "true"
+			}
+		}
+
+		values[flagToVariableName(flagName)] = value
+	}
+
+	return values, nil
+}
+
+// flagToVariableName converts a command-line flag name such as "db-host" to its environment variable name, DB_HOST
+func flagToVariableName(flagName string) string {
+	return strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// OptionSchema describes a registered Option without its value: what it's called, what kind of value it holds, and
+// the rules it's verified against. The schema of a registry is the source for generated configuration documentation.
+type OptionSchema struct {
+	// Name is the name of the variable holding the option
+	Name string
+	// Type describes the kind of value the option holds, such as "string", "integer", or "secret"
+	Type string
+	// Required is true if the option must be supplied by a configuration source
+	Required bool
+	// Validated is true if the option has a validation function beyond what its type enforces
+	Validated bool
+	// HasDefault is true if the option declares a default value, which is stored in Default
+	HasDefault bool
+	// Default is the option's default value. It's redacted for secret options.
+	Default string
+	// Secret is true if the option holds a sensitive value, which is redacted when printed and may be read from the
+	// file named by SecretFileName
+	Secret bool
+	// SecretFileName is the name of the variable pointing to a file containing the option's value. It's only set for
+	// secret options.
+	SecretFileName string
+	// AllowedValues lists every value an enum option accepts. It's empty for other options.
+	AllowedValues []string
+	// Description explains what the option is for
+	Description string
+	// DeprecatedAliases lists former names of the option which are still honored
+	DeprecatedAliases []string
+	// ProfileDefaults holds the defaults which only apply while a particular profile is active, keyed by profile. Like
+	// Default, they're redacted for secret options.
+	ProfileDefaults map[Profile]string
+}
+
+// schemaOf produces the OptionSchema of an option
+func schemaOf(option Option) OptionSchema {
+	schema := OptionSchema{
+		Name:          option.envName,
+		Type:          option.typeName,
+		Required:      option.required,
+		Validated:     option.validationFn != nil,
+		Secret:        option.secret,
+		AllowedValues: option.allowedValues,
+		Description:   option.description,
+	}
+
+	if defaultValue, hasDefault := option.DefaultValue(); hasDefault {
+		schema.HasDefault = true
+		schema.Default = defaultValue
+		if option.secret {
+			schema.Default = RedactedText
+		}
+	}
+	for profile, profileDefault := range option.profileDefaults {
+		if schema.ProfileDefaults == nil {
+			schema.ProfileDefaults = make(map[Profile]string, len(option.profileDefaults))
+		}
+		schema.ProfileDefaults[profile] = profileDefault
+		if option.secret {
+			schema.ProfileDefaults[profile] = RedactedText
+		}
+	}
+	if option.secret {
+		schema.SecretFileName = secretFileVariableName(option)
+	}
+	for _, alias := range option.deprecatedAliases {
+		schema.DeprecatedAliases = append(schema.DeprecatedAliases, alias.Name)
+	}
+
+	return schema
+}
+
+// schemaOfAll produces the schema of every option in a list, in the order they were registered
+func schemaOfAll(options []Option) []OptionSchema {
+	schema := make([]OptionSchema, 0, len(options))
+	for _, option := range options {
+		schema = append(schema, schemaOf(option))
+	}
+
+	return schema
+}
+
+// Schema describes every option registered with the builder, in the order they were registered
+func (rb *RegistryBuilder) Schema() []OptionSchema {
+	return schemaOfAll(rb.allOptions)
+}
+
+// Schema describes every option registered with the registry, in the order they were registered
+func (reg Registry) Schema() []OptionSchema {
+	return schemaOfAll(reg.options)
+}
+
+// EffectiveValue is the value an option took in a Registry, along with the name of the source which supplied it
+type EffectiveValue struct {
+	// Name is the name of the variable holding the option
+	Name string
+	// Present is true if the option has a value. Value and Source are empty otherwise.
+	Present bool
+	// Value is the option's raw value. It's redacted for secret options.
+	Value string
+	// Source is the name of the Source which supplied the value, or DefaultSourceName for defaulted options
+	Source string
+}
+
+// Dump lists the effective value of every registered option, in the order they were registered. Secret values are
+// redacted, so the dump is safe to log or print when diagnosing configuration problems.
+func (reg Registry) Dump() []EffectiveValue {
+	dump := make([]EffectiveValue, 0, len(reg.options))
+	for _, option := range reg.options {
+		resolved, isPresent := reg.values[option.envName]
+		effectiveValue := EffectiveValue{
+			Name:    option.envName,
+			Present: isPresent,
+			Value:   resolved.value,
+			Source:  resolved.sourceName,
+		}
+		if isPresent && option.secret {
+			effectiveValue.Value = RedactedText
+		}
+		dump = append(dump, effectiveValue)
+	}
+
+	return dump
+}
+
+// CheckSource verifies the registered options against a single configuration source, such as a .env file, without
+// consulting the builder's own sources. It returns the names of variables in the source which don't belong to any
+// registered option, sorted alphabetically, and the same error VerifyAndBuild would return if the source were the
+// only one. Unrecognized variables aren't an error, since a file may legitimately hold variables for other tools.
+func (rb *RegistryBuilder) CheckSource(source Source) ([]string, error) {
+	sourceValues, loadErr := source.Load()
+	if loadErr != nil {
+		return nil, fmt.Errorf("could not load configuration from %v: %w", source.Name(), loadErr)
+	}
+
+	var unrecognizedNames []string
+	for name := range sourceValues {
+		if !rb.Recognizes(name) {
+			unrecognizedNames = append(unrecognizedNames, name)
+		}
+	}
+	sort.Strings(unrecognizedNames)
+
+	checkBuilder := NewLayeredRegistryBuilder(source)
+	checkBuilder.registeredOptions = rb.registeredOptions
+	checkBuilder.allOptions = rb.allOptions
+	checkBuilder.rules = rb.rules
+	_, buildErr := checkBuilder.VerifyAndBuild()
+	return unrecognizedNames, buildErr
+}
+
+// Recognizes reports whether a variable name belongs to a registered option, either as the option's own name, one of
+// its deprecated aliases, or the name of the variable pointing to a secret option's file
+func (rb *RegistryBuilder) Recognizes(name string) bool {
+	for _, option := range rb.allOptions {
+		if option.envName == name || (option.secret && secretFileVariableName(option) == name) {
+			return true
+		}
+		for _, alias := range option.deprecatedAliases {
+			if alias.Name == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type IntrospectionSuite struct {
+	suite.Suite
+	builder RegistryBuilder
+}
+
+func TestIntrospectionSuite(t *testing.T) {
+	suite.Run(t, new(IntrospectionSuite))
+}
+
+func (suite *IntrospectionSuite) SetupTest() {
+	suite.builder = NewMockRegistryBuilder(map[string]string{
+		"HOST":     "example.com",
+		"PASSWORD": "hunter2",
+	})
+	suite.builder.AddOptions([]AnyOption{
+		NewOption("HOST", true).WithDescription("Where to connect"),
+		NewIntOption("PORT", false).WithDefault("8080"),
+		NewSecretOption("PASSWORD", true).WithDefault("changeme"),
+		NewEnumOption("FLAVOR", false, flavor("chocolate"), flavor("vanilla")),
+	})
+}
+
+func (suite *IntrospectionSuite) TestSchemaDescribesOptions() {
+	schema := suite.builder.Schema()
+
+	suite.Require().Len(schema, 4)
+	suite.Assert().Equal(OptionSchema{
+		Name:        "HOST",
+		Type:        "string",
+		Required:    true,
+		Description: "Where to connect",
+	}, schema[0])
+	suite.Assert().Equal(OptionSchema{
+		Name:       "PORT",
+		Type:       "integer",
+		HasDefault: true,
+		Default:    "8080",
+	}, schema[1])
+	suite.Assert().Equal(OptionSchema{
+		Name:           "PASSWORD",
+		Type:           "secret",
+		Required:       true,
+		HasDefault:     true,
+		Default:        RedactedText,
+		Secret:         true,
+		SecretFileName: "PASSWORD_FILE",
+	}, schema[2])
+	suite.Assert().Equal([]string{"chocolate", "vanilla"}, schema[3].AllowedValues)
+}
+
+func (suite *IntrospectionSuite) TestDumpRedactsSecrets() {
+	registry, buildErr := suite.builder.VerifyAndBuild()
+	suite.Require().NoError(buildErr)
+
+	suite.Assert().Equal([]EffectiveValue{
+		{Name: "HOST", Present: true, Value: "example.com", Source: "mock"},
+		{Name: "PORT", Present: true, Value: "8080", Source: DefaultSourceName},
+		{Name: "PASSWORD", Present: true, Value: RedactedText, Source: "mock"},
+		{Name: "FLAVOR"},
+	}, registry.Dump())
+	suite.Assert().Equal(suite.builder.Schema(), registry.Schema())
+}
+
+func (suite *IntrospectionSuite) TestGeneratedDocumentation() {
+	markdown := GenerateMarkdown(suite.builder.Schema())
+	suite.Assert().Contains(markdown, "| `HOST` | string | Yes |  | Where to connect |")
+	suite.Assert().Contains(markdown, "One of: `chocolate`, `vanilla`.")
+	suite.Assert().NotContains(markdown, "changeme")
+
+	jsonSchema, generateErr := GenerateJSONSchema(suite.builder.Schema())
+	suite.Require().NoError(generateErr)
+	suite.Assert().NotContains(string(jsonSchema), "changeme")
+
+	var document map[string]any
+	suite.Require().NoError(json.Unmarshal(jsonSchema, &document))
+	suite.Assert().Equal([]any{"HOST"}, document["required"])
+	suite.Assert().Contains(document["properties"], "PASSWORD_FILE")
+}
+
+func (suite *IntrospectionSuite) TestCheckSource() {
+	envPath := filepath.Join(suite.T().TempDir(), ".env")
+
+	suite.Run("Valid file with extra variables", func() {
+		passwordPath := filepath.Join(suite.T().TempDir(), "password")
+		suite.Require().NoError(os.WriteFile(passwordPath, []byte("hunter2"), 0o600))
+		envContents := "HOST=localhost\nPASSWORD_FILE=" + passwordPath + "\nDATABASE_URL=x\n"
+		suite.Require().NoError(os.WriteFile(envPath, []byte(envContents), 0o600))
+
+		unrecognizedNames, checkErr := suite.builder.CheckSource(NewDotEnvSource(envPath, true))
+		suite.Require().NoError(checkErr)
+		suite.Assert().Equal([]string{"DATABASE_URL"}, unrecognizedNames)
+	})
+
+	suite.Run("Invalid file", func() {
+		suite.Require().NoError(os.WriteFile(envPath, []byte("PORT=eighty\n"), 0o600))
+
+		_, checkErr := suite.builder.CheckSource(NewDotEnvSource(envPath, true))
+		var configErr ErrIncorrectConfiguration
+		suite.Require().ErrorAs(checkErr, &configErr)
+		// The builder's own source supplies HOST, but the check only considers the file
+		suite.Assert().Equal([]string{"HOST"}, configErr.MissingRequiredVariables)
+		suite.Assert().Len(configErr.InvalidVariables, 1)
+	})
+}
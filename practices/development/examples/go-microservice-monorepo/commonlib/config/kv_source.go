@@ -0,0 +1,245 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables read by KVSourceFromEnvironment. They can't be configuration options themselves, since they're
+// needed to find the configuration.
+const (
+	// KVAddressVariable holds the base URL of the key/value store, such as http://consul:8500
+	KVAddressVariable = "CONFIG_KV_ADDRESS"
+	// KVPrefixVariable holds the key prefix configuration is stored under, such as config/shared
+	KVPrefixVariable = "CONFIG_KV_PREFIX"
+	// KVTokenVariable holds the token used to authenticate with the key/value store, if it requires one
+	KVTokenVariable = "CONFIG_KV_TOKEN"
+)
+
+// Defaults for unset KVSourceSettings
+const (
+	defaultKVRequestTimeout = 5 * time.Second
+	defaultKVStartupTimeout = 30 * time.Second
+	defaultKVCacheTTL       = 30 * time.Second
+	kvStartupRetryInterval  = 500 * time.Millisecond
+)
+
+// KVSourceSettings configures a KVSource
+type KVSourceSettings struct {
+	// Address is the base URL of the key/value store, such as http://consul:8500
+	Address string
+	// Prefix is the key prefix configuration is stored under, such as config/shared. Keys below the prefix are
+	// flattened into variable names like a configuration file's keys, so config/shared/db/host configures DB_HOST.
+	Prefix string
+	// Token authenticates with the store, if it requires authentication. It's sent in the X-Consul-Token header.
+	Token Secret
+	// RequestTimeout limits how long a single request to the store may take. It defaults to 5 seconds.
+	RequestTimeout time.Duration
+	// StartupTimeout limits how long the first load keeps retrying while the store is unreachable, since the store
+	// may be starting alongside the application. It defaults to 30 seconds.
+	StartupTimeout time.Duration
+	// CacheTTL is how long values are reused before the store is asked again. It defaults to 30 seconds.
+	CacheTTL time.Duration
+	// Required makes loading fail if the store can't be reached and no values were ever loaded from it. Otherwise,
+	// the source supplies no values in that case, and the configuration falls back to the other sources.
+	Required bool
+	// HTTPClient is the client used to reach the store. It defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// KVSource is a Source which reads configuration from an HTTP key/value store with a Consul-style API, where
+// GET /v1/kv/<prefix>?recurse returns every key below a prefix with a base64-encoded value. It's meant for settings
+// shared by many services, such as allowed CORS origins, so they can be changed in one place.
+//
+// Values are cached for the configured TTL so frequent reloads don't hammer the store. If the store becomes unreachable
+// after values were loaded, the last values are kept. If it was never reachable, the source supplies no values unless
+// it's required, so the other sources, such as the environment, take over.
+type KVSource struct {
+	settings KVSourceSettings
+
+	// cacheLock guards every field below it
+	cacheLock   sync.Mutex
+	cached      map[string]string
+	cachedAt    time.Time
+	everLoaded  bool
+	startupDone bool
+	lastErr     error
+}
+
+// NewKVSource constructs a KVSource from the passed settings, filling in defaults for unset timeouts
+func NewKVSource(settings KVSourceSettings) *KVSource {
+	if settings.RequestTimeout == 0 {
+		settings.RequestTimeout = defaultKVRequestTimeout
+	}
+	if settings.StartupTimeout == 0 {
+		settings.StartupTimeout = defaultKVStartupTimeout
+	}
+	if settings.CacheTTL == 0 {
+		settings.CacheTTL = defaultKVCacheTTL
+	}
+	if settings.HTTPClient == nil {
+		settings.HTTPClient = http.DefaultClient
+	}
+	settings.Address = strings.TrimSuffix(settings.Address, "/")
+	settings.Prefix = strings.Trim(settings.Prefix, "/")
+
+	return &KVSource{settings: settings}
+}
+
+// KVSourceFromEnvironment constructs a KVSource configured by the CONFIG_KV_ADDRESS, CONFIG_KV_PREFIX and
+// CONFIG_KV_TOKEN environment variables, with default timeouts. The second return value is false if CONFIG_KV_ADDRESS
+// isn't set, meaning no key/value store should be used.
+func KVSourceFromEnvironment() (*KVSource, bool) {
+	address, addressPresent := os.LookupEnv(KVAddressVariable)
+	if !addressPresent || address == "" {
+		return nil, false
+	}
+
+	return NewKVSource(KVSourceSettings{
+		Address: address,
+		Prefix:  os.Getenv(KVPrefixVariable),
+		Token:   Secret(os.Getenv(KVTokenVariable)),
+	}), true
+}
+
+// Name implements Source for KVSource
+func (src *KVSource) Name() string {
+	return fmt.Sprintf("kv %v/%v", src.settings.Address, src.settings.Prefix)
+}
+
+// Load implements Source for KVSource, returning cached values if they're fresh enough
+func (src *KVSource) Load() (map[string]string, error) {
+	src.cacheLock.Lock()
+	defer src.cacheLock.Unlock()
+
+	if src.everLoaded && time.Since(src.cachedAt) < src.settings.CacheTTL {
+		return maps.Clone(src.cached), nil
+	}
+
+	var values map[string]string
+	var fetchErr error
+	if src.startupDone {
+		values, fetchErr = src.fetch()
+	} else {
+		values, fetchErr = src.fetchDuringStartup()
+		src.startupDone = true
+	}
+
+	src.lastErr = fetchErr
+	if fetchErr == nil {
+		src.cached, src.cachedAt, src.everLoaded = values, time.Now(), true
+		return maps.Clone(values), nil
+	}
+
+	if src.everLoaded {
+		// Keep serving the last values rather than dropping shared settings because the store is briefly down
+		return maps.Clone(src.cached), nil
+	}
+	if src.settings.Required {
+		return nil, fetchErr
+	}
+	return map[string]string{}, nil
+}
+
+// LastError returns the error from the most recent attempt to reach the store, or nil if it succeeded. Since an
+// unreachable store doesn't fail Load unless the source is required, this is how to find out the store is down.
+func (src *KVSource) LastError() error {
+	src.cacheLock.Lock()
+	defer src.cacheLock.Unlock()
+
+	return src.lastErr
+}
+
+// fetchDuringStartup fetches values from the store, retrying until the startup timeout elapses
+func (src *KVSource) fetchDuringStartup() (map[string]string, error) {
+	deadline := time.Now().Add(src.settings.StartupTimeout)
+	for {
+		values, fetchErr := src.fetch()
+		if fetchErr == nil || time.Now().Add(kvStartupRetryInterval).After(deadline) {
+			return values, fetchErr
+		}
+		time.Sleep(kvStartupRetryInterval)
+	}
+}
+
+// kvEntry is a single key in the store's response. A nil value is a folder or an empty key.
+type kvEntry struct {
+	Key   string  `json:"Key"`
+	Value *string `json:"Value"`
+}
+
+// fetch reads every key below the prefix from the store once
+func (src *KVSource) fetch() (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), src.settings.RequestTimeout)
+	defer cancel()
+
+	// Asking for the prefix as a folder keeps keys which merely start with the same text, like config/shared-old, out
+	var escapedPrefix string
+	for _, segment := range strings.Split(src.settings.Prefix, "/") {
+		if segment != "" {
+			escapedPrefix += url.PathEscape(segment) + "/"
+		}
+	}
+	requestURL := fmt.Sprintf("%v/v1/kv/%v?recurse=true", src.settings.Address, escapedPrefix)
+	request, requestErr := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if requestErr != nil {
+		return nil, fmt.Errorf("could not create key/value store request: %w", requestErr)
+	}
+	if src.settings.Token != "" {
+		request.Header.Set("X-Consul-Token", src.settings.Token.Reveal())
+	}
+
+	response, responseErr := src.settings.HTTPClient.Do(request)
+	if responseErr != nil {
+		return nil, fmt.Errorf("could not reach key/value store: %w", responseErr)
+	}
+	defer response.Body.Close()
+
+	// The store responds with 404 when nothing is stored below the prefix
+	if response.StatusCode == http.StatusNotFound {
+		return map[string]string{}, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("key/value store responded with status %v", response.StatusCode)
+	}
+
+	var entries []kvEntry
+	if decodeErr := json.NewDecoder(response.Body).Decode(&entries); decodeErr != nil {
+		return nil, fmt.Errorf("could not decode key/value store response: %w", decodeErr)
+	}
+
+	return src.flattenEntries(entries)
+}
+
+// flattenEntries converts the store's entries into variable names and decoded values
+func (src *KVSource) flattenEntries(entries []kvEntry) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, entry := range entries {
+		relativeKey := strings.Trim(strings.TrimPrefix(entry.Key, src.settings.Prefix), "/")
+		if relativeKey == "" || strings.HasSuffix(entry.Key, "/") {
+			continue
+		}
+
+		var value string
+		if entry.Value != nil {
+			decodedValue, decodeErr := base64.StdEncoding.DecodeString(*entry.Value)
+			if decodeErr != nil {
+				return nil, fmt.Errorf("could not decode value of key %v: %w", entry.Key, decodeErr)
+			}
+			value = string(decodedValue)
+		}
+
+		values[strings.ToUpper(strings.ReplaceAll(relativeKey, "/", "_"))] = value
+	}
+
+	return values, nil
+}
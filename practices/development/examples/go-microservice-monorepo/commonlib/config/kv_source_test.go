@@ -0,0 +1,136 @@
+package config_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"example.com/sample/commonlib/config"
+	"example.com/sample/commonlib/config/testhelper"
+	"github.com/stretchr/testify/suite"
+)
+
+type KVSourceSuite struct {
+	suite.Suite
+	store *testhelper.FakeKVStore
+}
+
+func TestKVSourceSuite(t *testing.T) {
+	suite.Run(t, new(KVSourceSuite))
+}
+
+func (suite *KVSourceSuite) SetupTest() {
+	suite.store = testhelper.NewFakeKVStore()
+	suite.store.Set("config/shared/ALLOWED_CORS_ORIGINS", "https://example.com")
+	suite.store.Set("config/shared/db/max_connections", "40")
+	suite.store.Set("config/shared-old/IGNORED", "true")
+}
+
+func (suite *KVSourceSuite) TearDownTest() {
+	suite.store.Close()
+}
+
+func (suite *KVSourceSuite) newSource(cacheTTL time.Duration, required bool) *config.KVSource {
+	return config.NewKVSource(config.KVSourceSettings{
+		Address:        suite.store.URL(),
+		Prefix:         "config/shared",
+		RequestTimeout: time.Second,
+		StartupTimeout: time.Millisecond,
+		CacheTTL:       cacheTTL,
+		Required:       required,
+	})
+}
+
+func (suite *KVSourceSuite) TestValuesAreLoaded() {
+	values, loadErr := suite.newSource(time.Minute, true).Load()
+	suite.Require().NoError(loadErr)
+
+	suite.Assert().Equal(map[string]string{
+		"ALLOWED_CORS_ORIGINS": "https://example.com",
+		"DB_MAX_CONNECTIONS":   "40",
+	}, values)
+}
+
+func (suite *KVSourceSuite) TestValuesAreCached() {
+	source := suite.newSource(time.Minute, true)
+	_, loadErr := source.Load()
+	suite.Require().NoError(loadErr)
+
+	suite.store.Set("config/shared/ALLOWED_CORS_ORIGINS", "https://changed.example.com")
+	values, loadErr := source.Load()
+	suite.Require().NoError(loadErr)
+
+	suite.Assert().Equal("https://example.com", values["ALLOWED_CORS_ORIGINS"])
+	suite.Assert().Equal(1, suite.store.Requests())
+}
+
+func (suite *KVSourceSuite) TestLastValuesAreKeptDuringOutage() {
+	source := suite.newSource(time.Nanosecond, true)
+	_, loadErr := source.Load()
+	suite.Require().NoError(loadErr)
+
+	suite.store.SetUnavailable(true)
+	values, loadErr := source.Load()
+	suite.Require().NoError(loadErr)
+
+	suite.Assert().Equal("https://example.com", values["ALLOWED_CORS_ORIGINS"])
+	suite.Assert().Error(source.LastError())
+}
+
+func (suite *KVSourceSuite) TestUnreachableStoreFallsBackToOtherSources() {
+	suite.store.SetUnavailable(true)
+	option := config.NewOption("ALLOWED_CORS_ORIGINS", true)
+
+	suite.Run("Optional store", func() {
+		builder := config.NewLayeredRegistryBuilder(suite.newSource(time.Minute, false),
+			staticSource{"ALLOWED_CORS_ORIGINS": "http://localhost"})
+		builder.AddOption(option)
+		registry, buildErr := builder.VerifyAndBuild()
+		suite.Require().NoError(buildErr)
+
+		suite.Assert().Equal("http://localhost", registry.GetRequired(option))
+	})
+
+	suite.Run("Required store", func() {
+		_, loadErr := suite.newSource(time.Minute, true).Load()
+		suite.Assert().ErrorContains(loadErr, "status 503")
+	})
+}
+
+func (suite *KVSourceSuite) TestTokenIsSent() {
+	var receivedToken string
+	source := config.NewKVSource(config.KVSourceSettings{
+		Address: suite.store.URL(),
+		Prefix:  "config/shared",
+		Token:   config.Secret("hunter2"),
+		HTTPClient: &http.Client{Transport: roundTripFunc(func(request *http.Request) (*http.Response, error) {
+			receivedToken = request.Header.Get("X-Consul-Token")
+			return http.DefaultTransport.RoundTrip(request)
+		})},
+	})
+	_, loadErr := source.Load()
+	suite.Require().NoError(loadErr)
+
+	suite.Assert().Equal("hunter2", receivedToken)
+}
+
+// staticSource is a Source supplying a fixed set of values
+type staticSource map[string]string
+
+// Name implements config.Source for staticSource
+func (src staticSource) Name() string {
+	return "static"
+}
+
+// Load implements config.Source for staticSource
+func (src staticSource) Load() (map[string]string, error) {
+	return src, nil
+}
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(request *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper for roundTripFunc
+func (fn roundTripFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return fn(request)
+}
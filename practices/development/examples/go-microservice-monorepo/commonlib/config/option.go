@@ -1,11 +1,37 @@
 package config
 
+import "strings"
+
 // Option represents an environment variable which can be verified during construction of a configuration Registry
 // for its presence, or optionally validated by a provided function.
 type Option struct {
 	envName      string
 	required     bool
 	validationFn func(string) error
+	// defaultValue is the raw value used when no configuration source supplies the option, if one is declared
+	defaultValue *string
+	// secret options are redacted wherever they're printed, and can be read from a mounted file
+	secret bool
+	// parseFn converts the raw value into its typed representation. It is only set for options built as a TypedOption.
+	parseFn func(string) (any, error)
+	// typeName describes the kind of value the option holds in the registry schema
+	typeName string
+	// allowedValues lists the values an enum option accepts, for the registry schema
+	allowedValues []string
+	// description explains what the option is for in the registry schema and generated documentation
+	description string
+	// deprecatedAliases are former names of the option which are still honored
+	deprecatedAliases []DeprecatedAlias
+	// profileDefaults are defaults which only apply while a particular Profile is active
+	profileDefaults map[Profile]string
+	// isProfile marks the option selecting the active Profile
+	isProfile bool
+}
+
+// AnyOption is implemented by Option and every TypedOption, allowing options of any type to be registered with a
+// RegistryBuilder and looked up in a Registry.
+type AnyOption interface {
+	baseOption() Option
 }
 
 // NewOption constructs a new Option, accepting the name of the represented environment variable and whether
@@ -14,6 +40,7 @@ func NewOption(envName string, required bool) Option {
 	return Option{
 		envName:  envName,
 		required: required,
+		typeName: "string",
 	}
 }
 
@@ -24,6 +51,7 @@ func NewValidatedOption(envName string, required bool, validationFn func(value s
 		envName:      envName,
 		required:     required,
 		validationFn: validationFn,
+		typeName:     "string",
 	}
 }
 
@@ -32,7 +60,74 @@ func (opt *Option) SetValidation(validationFn func(value string) error) {
 	opt.validationFn = validationFn
 }
 
+// SetDefault declares the raw value the option takes when no configuration source supplies it. The default value is
+// validated and parsed just like a value from the environment would be.
+func (opt *Option) SetDefault(value string) {
+	opt.defaultValue = &value
+}
+
+// WithDefault returns a copy of the option with the passed default value. See SetDefault for more information.
+func (opt Option) WithDefault(value string) Option {
+	opt.SetDefault(value)
+	return opt
+}
+
+// DefaultValue returns the option's declared default value. The second return value is false if the option doesn't
+// have a default.
+func (opt *Option) DefaultValue() (string, bool) {
+	if opt.defaultValue == nil {
+		return "", false
+	}
+	return *opt.defaultValue, true
+}
+
+// alwaysPresent reports whether the option is guaranteed to have a value in a built Registry, either because it's
+// required or because it has a default
+func (opt Option) alwaysPresent() bool {
+	return opt.required || opt.defaultValue != nil
+}
+
+// SetDescription documents what the option is for. The description appears in the registry schema and in
+// documentation generated from it.
+func (opt *Option) SetDescription(description string) {
+	opt.description = description
+}
+
+// WithDescription returns a copy of the option with the passed description. See SetDescription for more information.
+func (opt Option) WithDescription(description string) Option {
+	opt.SetDescription(description)
+	return opt
+}
+
+// WithPrefix returns a copy of the option whose variable name starts with the passed prefix, separated by an
+// underscore. For example, DB_HOST with the prefix REPORTING becomes REPORTING_DB_HOST. This lets one option definition
+// configure several instances of a subsystem. An empty prefix leaves the name unchanged.
+func (opt Option) WithPrefix(prefix string) Option {
+	opt.envName = prefixedName(prefix, opt.envName)
+	return opt
+}
+
+// prefixedName joins a prefix and a variable name with an underscore, unless the prefix is empty or already ends with
+// one
+func prefixedName(prefix string, envName string) string {
+	if prefix == "" || strings.HasSuffix(prefix, "_") {
+		return prefix + envName
+	}
+
+	return prefix + "_" + envName
+}
+
+// IsSecret reports whether the option holds a secret value, such as a password, which must never be printed
+func (opt *Option) IsSecret() bool {
+	return opt.secret
+}
+
 // VariableName returns the name of the environment variable this option represents
 func (opt *Option) VariableName() string {
 	return opt.envName
 }
+
+// baseOption implements AnyOption for Option
+func (opt Option) baseOption() Option {
+	return opt
+}
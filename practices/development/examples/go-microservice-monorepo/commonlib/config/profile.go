@@ -0,0 +1,104 @@
+package config
+
+import "fmt"
+
+// Profile is the kind of environment the application runs in. Components use it to adjust their behavior, such as
+// hiding error details in production, and options can declare different defaults for each profile.
+type Profile string
+
+const (
+	// ProfileLocal is a developer's machine
+	ProfileLocal Profile = "local"
+	// ProfileTest is an automated test run
+	ProfileTest Profile = "test"
+	// ProfileStaging is a shared pre-production environment
+	ProfileStaging Profile = "staging"
+	// ProfileProduction is the environment serving real users
+	ProfileProduction Profile = "production"
+)
+
+// AllProfiles lists every Profile
+var AllProfiles = []Profile{ProfileLocal, ProfileTest, ProfileStaging, ProfileProduction}
+
+// IsProduction reports whether the profile is ProfileProduction
+func (profile Profile) IsProduction() bool {
+	return profile == ProfileProduction
+}
+
+// NewProfileOption constructs a required TypedOption selecting the active Profile, which must be one of AllProfiles.
+// Once it's registered, Registry.Profile reports the active profile and options' profile-specific defaults apply. Only
+// one profile option may be registered with a RegistryBuilder.
+func NewProfileOption(envName string) TypedOption[Profile] {
+	option := NewEnumOption(envName, true, AllProfiles...)
+	option.isProfile = true
+	return option
+}
+
+// SetProfileDefault declares a default value which only applies while the passed profile is active, taking precedence
+// over the default declared with SetDefault. The active profile is selected by the option built with NewProfileOption.
+func (opt *Option) SetProfileDefault(profile Profile, value string) {
+	// Copy the defaults so options copied from this one don't share additions
+	profileDefaults := make(map[Profile]string, len(opt.profileDefaults)+1)
+	for existingProfile, existingValue := range opt.profileDefaults {
+		profileDefaults[existingProfile] = existingValue
+	}
+	profileDefaults[profile] = value
+	opt.profileDefaults = profileDefaults
+}
+
+// WithProfileDefault returns a copy of the option with a default value for the passed profile. See SetProfileDefault
+// for more information.
+func (opt Option) WithProfileDefault(profile Profile, value string) Option {
+	opt.SetProfileDefault(profile, value)
+	return opt
+}
+
+// WithProfileDefault returns a copy of the option with a default value for the passed profile. See
+// Option.SetProfileDefault for more information.
+func (opt TypedOption[T]) WithProfileDefault(profile Profile, value string) TypedOption[T] {
+	opt.SetProfileDefault(profile, value)
+	return opt
+}
+
+// Profile returns the active Profile, as selected by the option built with NewProfileOption. This function will panic
+// if no profile option was registered when building the registry.
+func (reg Registry) Profile() Profile {
+	for _, option := range reg.options {
+		if option.isProfile {
+			// The profile option is required, so a built registry always has its value
+			return reg.values[option.envName].parsed.(Profile)
+		}
+	}
+
+	panic("No profile option is registered! Make sure to register one built with config.NewProfileOption when building the option registry.")
+}
+
+// profileOptionFirst orders options so the profile option, if one is registered, is verified before the others, whose
+// profile-specific defaults depend on it
+func profileOptionFirst(options []Option) []Option {
+	ordered := make([]Option, 0, len(options))
+	for _, option := range options {
+		if option.isProfile {
+			ordered = append(ordered, option)
+		}
+	}
+	for _, option := range options {
+		if !option.isProfile {
+			ordered = append(ordered, option)
+		}
+	}
+
+	return ordered
+}
+
+// mustBeOnlyProfileOption panics if a profile option is registered when another one already is
+func (rb *RegistryBuilder) mustBeOnlyProfileOption(newOption Option) {
+	if !newOption.isProfile {
+		return
+	}
+	for _, option := range rb.allOptions {
+		if option.isProfile {
+			panic(fmt.Sprintf("Registering option failed, %v and %v are both profile options but only one may be registered", option.envName, newOption.envName))
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ProfileSuite struct {
+	suite.Suite
+}
+
+func TestProfileSuite(t *testing.T) {
+	suite.Run(t, new(ProfileSuite))
+}
+
+func (suite *ProfileSuite) build(environment map[string]string, options ...AnyOption) (Registry, error) {
+	builder := NewMockRegistryBuilder(environment)
+	builder.AddOptions(options)
+	return builder.VerifyAndBuild()
+}
+
+func (suite *ProfileSuite) TestRegistryReportsProfile() {
+	profileOption := NewProfileOption("APP_ENV")
+	registry, buildErr := suite.build(map[string]string{"APP_ENV": "staging"}, profileOption)
+	suite.Require().NoError(buildErr)
+
+	suite.Assert().Equal(ProfileStaging, registry.Profile())
+	suite.Assert().False(registry.Profile().IsProduction())
+}
+
+func (suite *ProfileSuite) TestUnknownProfileIsInvalid() {
+	_, buildErr := suite.build(map[string]string{"APP_ENV": "qa"}, NewProfileOption("APP_ENV"))
+
+	var configErr ErrIncorrectConfiguration
+	suite.Require().ErrorAs(buildErr, &configErr)
+	suite.Require().Len(configErr.InvalidVariables, 1)
+	suite.Assert().Equal("APP_ENV", configErr.InvalidVariables[0].Name)
+}
+
+func (suite *ProfileSuite) TestProfileIsRequired() {
+	_, buildErr := suite.build(nil, NewProfileOption("APP_ENV"))
+
+	var configErr ErrIncorrectConfiguration
+	suite.Require().ErrorAs(buildErr, &configErr)
+	suite.Assert().Equal([]string{"APP_ENV"}, configErr.MissingRequiredVariables)
+}
+
+func (suite *ProfileSuite) TestProfileDefaults() {
+	timeoutOption := NewIntOption("TIMEOUT", false).WithDefault("30").WithProfileDefault(ProfileLocal, "300")
+
+	subtests := []struct {
+		testName        string
+		environment     map[string]string
+		expectedTimeout int
+	}{
+		{
+			testName:        "Profile default applies in its profile",
+			environment:     map[string]string{"APP_ENV": "local"},
+			expectedTimeout: 300,
+		},
+		{
+			testName:        "Plain default applies in other profiles",
+			environment:     map[string]string{"APP_ENV": "production"},
+			expectedTimeout: 30,
+		},
+		{
+			testName:        "Sources override profile defaults",
+			environment:     map[string]string{"APP_ENV": "local", "TIMEOUT": "5"},
+			expectedTimeout: 5,
+		},
+	}
+
+	for _, subtest := range subtests {
+		suite.Run(subtest.testName, func() {
+			// The option is registered before the profile option to check the order of registration doesn't matter
+			registry, buildErr := suite.build(subtest.environment, timeoutOption, NewProfileOption("APP_ENV"))
+			suite.Require().NoError(buildErr)
+
+			suite.Assert().Equal(subtest.expectedTimeout, GetRequiredTyped(registry, timeoutOption))
+		})
+	}
+}
+
+func (suite *ProfileSuite) TestProfileDefaultsAreCopied() {
+	baseOption := NewOption("MODE", false).WithProfileDefault(ProfileLocal, "fast")
+	derivedOption := baseOption.WithProfileDefault(ProfileTest, "safe")
+
+	suite.Assert().Len(baseOption.baseOption().profileDefaults, 1)
+	suite.Assert().Len(derivedOption.baseOption().profileDefaults, 2)
+}
+
+func (suite *ProfileSuite) TestProfileDefaultsForEveryProfileAreRequired() {
+	timeoutOption := NewIntOption("TIMEOUT", false)
+	for _, profile := range AllProfiles {
+		timeoutOption = timeoutOption.WithProfileDefault(profile, "30")
+	}
+	timeoutOption = timeoutOption.WithProfileDefault(ProfileLocal, "300")
+	partialOption := NewIntOption("RETRIES", false).WithProfileDefault(ProfileLocal, "3")
+
+	registry, buildErr := suite.build(map[string]string{"APP_ENV": "local"}, NewProfileOption("APP_ENV"), timeoutOption,
+		partialOption)
+	suite.Require().NoError(buildErr)
+
+	suite.Assert().Equal(300, GetRequiredTyped(registry, timeoutOption))
+	suite.Assert().Equal("300", registry.GetRequired(timeoutOption))
+	suite.Assert().Panics(func() {
+		GetRequiredTyped(registry, partialOption)
+	}, "Profiles without a default leave the option absent")
+
+	withoutProfile, buildErr := suite.build(nil, timeoutOption)
+	suite.Require().NoError(buildErr)
+	suite.Assert().Panics(func() {
+		GetRequiredTyped(withoutProfile, timeoutOption)
+	}, "Profile defaults don't apply without a profile option")
+}
+
+func (suite *ProfileSuite) TestOnlyOneProfileOption() {
+	builder := NewMockRegistryBuilder(nil)
+	builder.AddOption(NewProfileOption("APP_ENV"))
+
+	suite.Assert().Panics(func() {
+		builder.AddOption(NewProfileOption("ENVIRONMENT"))
+	})
+}
+
+func (suite *ProfileSuite) TestProfileWithoutOptionPanics() {
+	registry, buildErr := suite.build(nil)
+	suite.Require().NoError(buildErr)
+
+	suite.Assert().Panics(func() {
+		registry.Profile()
+	})
+}
+
+func (suite *ProfileSuite) TestProfileDefaultsAppearInSchema() {
+	builder := NewMockRegistryBuilder(nil)
+	builder.AddOption(NewOption("LOG_LEVEL", false).WithDefault("info").WithProfileDefault(ProfileLocal, "debug"))
+
+	suite.Assert().Equal(map[Profile]string{ProfileLocal: "debug"}, builder.Schema()[0].ProfileDefaults)
+	suite.Assert().Contains(GenerateMarkdown(builder.Schema()), "Defaults to `debug` in the local profile.")
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 )
 
 // RegistryBuilder is a builder for a Registry. It accepts the full list of configuration options available to
@@ -10,15 +12,23 @@ import (
 type RegistryBuilder struct {
 	registeredOptions map[string]struct{}
 	allOptions        []Option
-	dataSource        registrySource
+	rules             []Rule
+	sources           []Source
 }
 
-// NewRegistryBuilder constructs a RegistryBuilder
+// NewRegistryBuilder constructs a RegistryBuilder which reads configuration options from the process environment
 func NewRegistryBuilder() RegistryBuilder {
+	return NewLayeredRegistryBuilder(EnvironmentSource())
+}
+
+// NewLayeredRegistryBuilder constructs a RegistryBuilder which reads configuration options from the passed sources.
+// Sources are listed in order of increasing precedence, so a value in a later source overrides the same value in an
+// earlier one. See StandardSources for the standard layering of files, the environment, and command-line flags.
+func NewLayeredRegistryBuilder(sources ...Source) RegistryBuilder {
 	return RegistryBuilder{
 		registeredOptions: make(map[string]struct{}),
 		allOptions:        nil,
-		dataSource:        environmentRegistrySource{},
+		sources:           sources,
 	}
 }
 
@@ -28,25 +38,24 @@ func NewMockRegistryBuilder(environmentVariables map[string]string) RegistryBuil
 	if environmentVariables == nil {
 		environmentVariables = make(map[string]string)
 	}
-	return RegistryBuilder{
-		registeredOptions: make(map[string]struct{}),
-		allOptions:        nil,
-		dataSource:        mockRegistrySource(environmentVariables),
-	}
+	return NewLayeredRegistryBuilder(mockRegistrySource(environmentVariables))
 }
 
-// AddOption registers a new Option with the builder
-func (rb *RegistryBuilder) AddOption(option Option) {
+// AddOption registers a new Option or TypedOption with the builder
+func (rb *RegistryBuilder) AddOption(anyOption AnyOption) {
+	option := anyOption.baseOption()
 	if _, optionExists := rb.registeredOptions[option.envName]; optionExists {
 		panic(fmt.Sprintf("Registering option failed, nother option with the same name exists: %v", option.envName))
 	}
 
+	rb.mustBeOnlyProfileOption(option)
+
 	rb.registeredOptions[option.envName] = struct{}{}
 	rb.allOptions = append(rb.allOptions, option)
 }
 
-// AddOptions registers a list of Options with the builder
-func (rb *RegistryBuilder) AddOptions(options []Option) {
+// AddOptions registers a list of Options or TypedOptions with the builder
+func (rb *RegistryBuilder) AddOptions(options []AnyOption) {
 	for _, option := range options {
 		rb.AddOption(option)
 	}
@@ -59,15 +68,16 @@ type InvalidVariable struct {
 }
 
 // ErrIncorrectConfiguration is an error which describes issues with environment configuration, either for
-// required variables or variables that failed validation
+// required variables, variables that failed validation, or rules relating several variables that were broken
 type ErrIncorrectConfiguration struct {
 	MissingRequiredVariables []string
 	InvalidVariables         []InvalidVariable
+	FailedRules              []FailedRule
 }
 
-// errorsPresent returns true if any required variables are missing or variables failed validation
+// errorsPresent returns true if any required variables are missing, variables failed validation, or rules failed
 func (err ErrIncorrectConfiguration) errorsPresent() bool {
-	return len(err.MissingRequiredVariables) > 0 || len(err.InvalidVariables) > 0
+	return len(err.MissingRequiredVariables) > 0 || len(err.InvalidVariables) > 0 || len(err.FailedRules) > 0
 }
 
 // Error implements the error interface for ErrIncorrectConfiguration
@@ -92,6 +102,20 @@ func (err ErrIncorrectConfiguration) Error() string {
 		}
 	}
 
+	if len(err.FailedRules) > 0 {
+		var ruleErrorsList []string
+		for _, failedRule := range err.FailedRules {
+			ruleErrorsList = append(ruleErrorsList, fmt.Sprintf("rule \"%v\" failed for this reason: %v", failedRule.Description, failedRule.RuleError.Error()))
+		}
+		ruleErrors := strings.Join(ruleErrorsList, ", ")
+
+		if len(errorText) == 0 {
+			errorText = ruleErrors
+		} else {
+			errorText += ", " + ruleErrors
+		}
+	}
+
 	return errorText
 }
 
@@ -108,68 +132,222 @@ func (err ErrIncorrectConfiguration) Is(testErr error) bool {
 	return false
 }
 
-// VerifyAndBuild checks the registered Options against the environment, producing a constructed registry if
-// all required environment variables are present and all environment variables with validation pass validation.
-// Produces an ErrIncorrectConfiguration describing problems with the current configuration if those checks fail.
+// VerifyAndBuild checks the registered Options against the layered configuration sources, producing a constructed
+// registry if all required environment variables are present, all environment variables with validation pass
+// validation, and all registered rules pass. Produces an ErrIncorrectConfiguration describing problems with the
+// current configuration if those checks fail, or a different error if one of the sources couldn't be loaded.
 func (rb *RegistryBuilder) VerifyAndBuild() (Registry, error) {
+	layeredValues, loadErr := layerSources(rb.sources)
+	if loadErr != nil {
+		return Registry{}, loadErr
+	}
+
 	var variableIssues ErrIncorrectConfiguration
-	for _, option := range rb.allOptions {
-		optionValue, optionPresent := rb.dataSource.getValue(option.envName)
+	values := make(map[string]resolvedValue)
+	skippedNames := make(map[string]bool)
+	var warnings []string
+	var activeProfile Profile
+	for _, option := range profileOptionFirst(rb.allOptions) {
+		resolved, optionPresent, lookupWarning, lookupIssue := lookupValue(option, layeredValues, activeProfile)
+		if lookupWarning != "" {
+			warnings = append(warnings, lookupWarning)
+		}
+		if lookupIssue != nil {
+			variableIssues.InvalidVariables = append(variableIssues.InvalidVariables, *lookupIssue)
+			skippedNames[option.envName] = true
+			continue
+		}
+
 		// Verify required option is present
-		if option.required {
-			if !optionPresent {
+		if !optionPresent {
+			if option.required {
 				variableIssues.MissingRequiredVariables = append(variableIssues.MissingRequiredVariables, option.envName)
+				skippedNames[option.envName] = true
 			}
+			continue
 		}
 
-		// If the option is present at all, and it has a validation function, validate the option
-		if optionPresent && option.validationFn != nil {
-			validationErr := option.validationFn(optionValue)
-			if validationErr != nil {
-				variableIssues.InvalidVariables = append(variableIssues.InvalidVariables, InvalidVariable{
-					Name:            option.envName,
-					ValidationError: validationErr,
-				})
+		// The option is present, so validate it if it has a validation function and parse it if it's typed
+		if verifyErr := verifyValue(option, &resolved); verifyErr != nil {
+			if resolved.sourceName == DefaultSourceName {
+				verifyErr = fmt.Errorf("the default value is invalid: %w", verifyErr)
 			}
+			if option.secret {
+				verifyErr = redactError(verifyErr, resolved.value)
+			}
+			variableIssues.InvalidVariables = append(variableIssues.InvalidVariables, InvalidVariable{
+				Name:            option.envName,
+				ValidationError: verifyErr,
+			})
+			skippedNames[option.envName] = true
+			continue
+		}
+		values[option.envName] = resolved
+		if option.isProfile {
+			activeProfile = resolved.parsed.(Profile)
 		}
 	}
 
+	verified := Registry{
+		registeredOptions: rb.registeredOptions,
+		options:           rb.allOptions,
+		values:            values,
+		warnings:          warnings,
+	}
+	variableIssues.FailedRules = rb.checkRules(verified, skippedNames)
+
 	if variableIssues.errorsPresent() {
 		return Registry{}, variableIssues
 	}
 
-	return Registry{
-		registeredOptions: rb.registeredOptions,
-		dataSource:        rb.dataSource,
-	}, nil
+	return verified, nil
+}
+
+// lookupValue finds the value of an option in the layered configuration sources, honoring deprecated aliases, reading
+// secret options from mounted files, and falling back to the option's default for the active profile, then its plain
+// default, if no source supplied a value. A warning is returned if a deprecated alias was used. The returned
+// InvalidVariable is non-nil if the option's value couldn't be looked up.
+func lookupValue(option Option, layeredValues map[string]resolvedValue, activeProfile Profile) (resolvedValue, bool, string, *InvalidVariable) {
+	aliasResolved, aliasPresent, aliasWarning, aliasIssue := lookupDeprecatedAlias(option, layeredValues, time.Now())
+	if aliasIssue != nil {
+		return resolvedValue{}, false, "", aliasIssue
+	}
+
+	resolved, optionPresent := layeredValues[option.envName]
+	if aliasPresent {
+		resolved, optionPresent = aliasResolved, true
+	}
+	if option.secret {
+		if _, fileVariablePresent := layeredValues[secretFileVariableName(option)]; fileVariablePresent && optionPresent {
+			return resolvedValue{}, false, "", &InvalidVariable{
+				Name:            option.envName,
+				ValidationError: fmt.Errorf("both %v and %v are set, only one may be used", option.envName, secretFileVariableName(option)),
+			}
+		}
+
+		fileResolved, filePresent, fileIssue := lookupSecretFile(option, layeredValues)
+		if fileIssue != nil {
+			return resolvedValue{}, false, "", fileIssue
+		}
+		if filePresent {
+			resolved, optionPresent = fileResolved, true
+		}
+	}
+
+	if profileDefault, hasProfileDefault := option.profileDefaults[activeProfile]; !optionPresent && hasProfileDefault {
+		resolved = resolvedValue{value: profileDefault, sourceName: DefaultSourceName}
+		optionPresent = true
+	}
+	if !optionPresent && option.defaultValue != nil {
+		resolved = resolvedValue{value: *option.defaultValue, sourceName: DefaultSourceName}
+		optionPresent = true
+	}
+
+	return resolved, optionPresent, aliasWarning, nil
+}
+
+// verifyValue runs the option's validation function against a resolved value, then parses the value if the option is a
+// TypedOption. The parsed value is stored on the resolved value.
+func verifyValue(option Option, resolved *resolvedValue) error {
+	if option.validationFn != nil {
+		if validationErr := option.validationFn(resolved.value); validationErr != nil {
+			return validationErr
+		}
+	}
+
+	if option.parseFn != nil {
+		parsedValue, parseErr := option.parseFn(resolved.value)
+		if parseErr != nil {
+			return parseErr
+		}
+		resolved.parsed = parsedValue
+	}
+
+	return nil
 }
 
 // Registry is a validated registry of Option values. This type should be constructed via a RegistryBuilder which will
 // verify the integrity of environment variables during the construction of this type
 type Registry struct {
 	registeredOptions map[string]struct{}
-	dataSource        registrySource
+	options           []Option
+	values            map[string]resolvedValue
+	warnings          []string
+}
+
+// alwaysPresent reports whether the option is guaranteed to have a value in the registry. Besides required options and
+// options with a default, that's options with a profile default for every profile, as long as a profile option is
+// registered to select one of them.
+func (reg Registry) alwaysPresent(option Option) bool {
+	if option.alwaysPresent() {
+		return true
+	}
+	if !slices.ContainsFunc(reg.options, func(registered Option) bool { return registered.isProfile }) {
+		return false
+	}
+	for _, profile := range AllProfiles {
+		if _, hasProfileDefault := option.profileDefaults[profile]; !hasProfileDefault {
+			return false
+		}
+	}
+	return true
 }
 
-// Get retrieves the specified Option from the environment. The first return value contains the option's value if it's
-// present, and the second value is true if the value was actually present or false otherwise. This function will panic
-// if an unregistered option is passed.
-func (reg Registry) Get(option Option) (string, bool) {
+// mustBeRegistered panics if the passed option was not registered when building the registry
+func (reg Registry) mustBeRegistered(anyOption AnyOption) Option {
+	option := anyOption.baseOption()
 	if _, registeredOption := reg.registeredOptions[option.envName]; !registeredOption {
 		panic(fmt.Sprintf("Option %v is not registered! Make sure to register it when building the option registry.", option.envName))
 	}
 
-	return reg.dataSource.getValue(option.envName)
+	return option
+}
+
+// Get retrieves the raw value of the specified Option from the environment. The first return value contains the
+// option's value if it's present, and the second value is true if the value was actually present or false otherwise.
+// If no source supplied the option but it has a default, the default is returned as a present value. This function
+// will panic if an unregistered option is passed.
+func (reg Registry) Get(anyOption AnyOption) (string, bool) {
+	option := reg.mustBeRegistered(anyOption)
+
+	resolved, isPresent := reg.values[option.envName]
+	return resolved.value, isPresent
+}
+
+// SourceOf reports the name of the Source which supplied the value of the specified Option, such as "environment" or
+// "flags". The second return value is false if the option isn't present in any source. This function will panic if
+// an unregistered option is passed.
+func (reg Registry) SourceOf(anyOption AnyOption) (string, bool) {
+	option := reg.mustBeRegistered(anyOption)
+
+	resolved, isPresent := reg.values[option.envName]
+	return resolved.sourceName, isPresent
 }
 
-// GetRequired retrieves the specified required Option from the environment. Since the passed option is required,
-// the "presence" boolean is not returned like it is in Get. This function will panic if it is passed an optional
-// option or an unregistered option.
-func (reg Registry) GetRequired(option Option) string {
-	if !option.required {
-		panic(fmt.Sprintf("Non-required option %v was passed to Registry.GetRequired", option.envName))
+// IsDefault reports whether the specified Option took its declared default value because no configuration source
+// supplied it. It returns false for explicitly configured options and for absent options without a default. This
+// function will panic if an unregistered option is passed.
+func (reg Registry) IsDefault(anyOption AnyOption) bool {
+	sourceName, isPresent := reg.SourceOf(anyOption)
+	return isPresent && sourceName == DefaultSourceName
+}
+
+// GetRequired retrieves the specified Option from the environment, which must either be required, have a default value,
+// or have a profile default for every profile while a profile option is registered. Since the option is guaranteed to
+// have a value, the "presence" boolean is not returned like it is in Get. This function will panic if it is passed an
+// optional option without a default or an unregistered option.
+func (reg Registry) GetRequired(anyOption AnyOption) string {
+	option := anyOption.baseOption()
+	if !reg.alwaysPresent(option) {
+		panic(fmt.Sprintf("Non-required option %v without a default was passed to Registry.GetRequired", option.envName))
 	}
 	// We don't need to check presence since the construction of the registry verified the option is present
 	value, _ := reg.Get(option)
 	return value
 }
+
+// Warnings lists problems with the configuration which didn't prevent the registry from being built, such as the use of
+// deprecated aliases. They should be logged once the logger is available, which logger.InitLoggerFromConfig does.
+func (reg Registry) Warnings() []string {
+	return reg.warnings
+}
@@ -1,23 +1,122 @@
 package config
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"strings"
+)
 
-// registrySource is a source of information for retrieving configuration options
-type registrySource interface {
-	getValue(variableName string) (string, bool)
+// Source is a source of information for retrieving configuration options, such as the process environment, a
+// configuration file, or command-line flags. Sources are layered by a RegistryBuilder, with sources later in the list
+// overriding values supplied by earlier ones.
+type Source interface {
+	// Name describes the source in diagnostics, such as when reporting which source supplied an option's value.
+	Name() string
+	// Load reads every value the source provides, keyed by the environment variable name of the option it configures.
+	Load() (map[string]string, error)
 }
 
-// environmentRegistrySource is a registrySource that pulls configuration options from environment variables
+// StandardSources returns the standard layering of configuration sources in order of increasing precedence: an
+// optional YAML/JSON configuration file, an optional .env file, the process environment, and finally command-line
+// flags. Either file path may be left empty to skip that source entirely.
+func StandardSources(configFilePath string, dotEnvFilePath string, args []string) []Source {
+	return StandardSourcesWithRemote(configFilePath, dotEnvFilePath, nil, args)
+}
+
+// StandardSourcesWithRemote is like StandardSources, except a remote source, such as a KVSource, is layered between the
+// files and the process environment. Shared settings from the remote source override local files, while the
+// environment and flags of a single deployment still override the shared settings. A nil remote source is skipped.
+func StandardSourcesWithRemote(configFilePath string, dotEnvFilePath string, remote Source, args []string) []Source {
+	var sources []Source
+	if configFilePath != "" {
+		sources = append(sources, NewFileSource(configFilePath, false))
+	}
+	if dotEnvFilePath != "" {
+		sources = append(sources, NewDotEnvSource(dotEnvFilePath, false))
+	}
+	if remote != nil {
+		sources = append(sources, remote)
+	}
+
+	return append(sources, EnvironmentSource(), NewFlagSource(args))
+}
+
+// environmentSourceName is the name reported for values supplied by the process environment
+const environmentSourceName = "environment"
+
+// environmentRegistrySource is a Source that pulls configuration options from environment variables
 type environmentRegistrySource struct{}
 
-func (src environmentRegistrySource) getValue(variableName string) (string, bool) {
-	return os.LookupEnv(variableName)
+// EnvironmentSource returns a Source which reads configuration options from the process environment
+func EnvironmentSource() Source {
+	return environmentRegistrySource{}
+}
+
+// Name implements Source for environmentRegistrySource
+func (src environmentRegistrySource) Name() string {
+	return environmentSourceName
+}
+
+// Load implements Source for environmentRegistrySource
+func (src environmentRegistrySource) Load() (map[string]string, error) {
+	values := make(map[string]string)
+	for _, variable := range os.Environ() {
+		if name, value, hasSeparator := strings.Cut(variable, "="); hasSeparator {
+			values[name] = value
+		}
+	}
+
+	return values, nil
 }
 
-// mockRegistrySource is an in-memory map-based registrySource implementation appropriate for testing
+// mockSourceName is the name reported for values supplied by a mockRegistrySource
+const mockSourceName = "mock"
+
+// mockRegistrySource is an in-memory map-based Source implementation appropriate for testing
 type mockRegistrySource map[string]string
 
-func (src mockRegistrySource) getValue(variableName string) (string, bool) {
-	value, isPresent := src[variableName]
-	return value, isPresent
+// Name implements Source for mockRegistrySource
+func (src mockRegistrySource) Name() string {
+	return mockSourceName
+}
+
+// Load implements Source for mockRegistrySource
+func (src mockRegistrySource) Load() (map[string]string, error) {
+	values := make(map[string]string, len(src))
+	for name, value := range src {
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+// DefaultSourceName is the source name reported by Registry.SourceOf for options which took their declared default
+const DefaultSourceName = "default"
+
+// resolvedValue is the value of an option after all sources have been layered, along with the source that supplied it
+type resolvedValue struct {
+	value      string
+	sourceName string
+	// parsed is the typed value of a TypedOption, produced once while the registry is verified
+	parsed any
+}
+
+// layerSources loads every passed source in order, with values from later sources overriding earlier ones.
+func layerSources(sources []Source) (map[string]resolvedValue, error) {
+	resolvedValues := make(map[string]resolvedValue)
+	for _, source := range sources {
+		sourceValues, loadErr := source.Load()
+		if loadErr != nil {
+			return nil, fmt.Errorf("could not load configuration from %v: %w", source.Name(), loadErr)
+		}
+
+		for name, value := range sourceValues {
+			resolvedValues[name] = resolvedValue{
+				value:      value,
+				sourceName: source.Name(),
+			}
+		}
+	}
+
+	return resolvedValues, nil
 }
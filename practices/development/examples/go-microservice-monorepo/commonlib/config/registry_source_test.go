@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RegistrySourceSuite struct {
+	suite.Suite
+}
+
+func TestRegistrySourceSuite(t *testing.T) {
+	suite.Run(t, new(RegistrySourceSuite))
+}
+
+// writeFile writes a file into a temporary directory for the current test, returning its path
+func (suite *RegistrySourceSuite) writeFile(name string, contents string) string {
+	path := filepath.Join(suite.T().TempDir(), name)
+	suite.Require().NoError(os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+func (suite *RegistrySourceSuite) TestLaterSourcesOverrideEarlierOnes() {
+	fileOnly := NewOption("FILE_ONLY", true)
+	overridden := NewOption("OVERRIDDEN", true)
+	flagged := NewOption("FLAGGED", true)
+
+	builder := NewLayeredRegistryBuilder(
+		mockRegistrySource{fileOnly.VariableName(): "from file", overridden.VariableName(): "from file"},
+		mockRegistrySource{overridden.VariableName(): "from env", flagged.VariableName(): "from env"},
+		NewFlagSource([]string{"--flagged=from flags"}),
+	)
+	builder.AddOptions([]AnyOption{fileOnly, overridden, flagged})
+	registry, buildErr := builder.VerifyAndBuild()
+	suite.Require().NoError(buildErr)
+
+	suite.Assert().Equal("from file", registry.GetRequired(fileOnly))
+	suite.Assert().Equal("from env", registry.GetRequired(overridden))
+	suite.Assert().Equal("from flags", registry.GetRequired(flagged))
+
+	flagSource, _ := registry.SourceOf(flagged)
+	suite.Assert().Equal("flags", flagSource)
+}
+
+func (suite *RegistrySourceSuite) TestSourceOfReportsAbsentOptions() {
+	option := NewOption("NOT_HERE", false)
+	builder := NewMockRegistryBuilder(nil)
+	builder.AddOption(option)
+	registry, buildErr := builder.VerifyAndBuild()
+	suite.Require().NoError(buildErr)
+
+	_, isPresent := registry.SourceOf(option)
+	suite.Assert().False(isPresent)
+}
+
+func (suite *RegistrySourceSuite) TestYamlFileIsFlattened() {
+	path := suite.writeFile("config.yaml", `
+db:
+  host: localhost
+  port: 3306
+allowed_cors_origins:
+  - http://localhost
+  - http://127.0.0.1
+`)
+
+	values, loadErr := NewFileSource(path, true).Load()
+	suite.Require().NoError(loadErr)
+	suite.Assert().Equal(map[string]string{
+		"DB_HOST":              "localhost",
+		"DB_PORT":              "3306",
+		"ALLOWED_CORS_ORIGINS": "http://localhost,http://127.0.0.1",
+	}, values)
+}
+
+func (suite *RegistrySourceSuite) TestJsonFileIsRead() {
+	path := suite.writeFile("config.json", `{"LOG_LEVEL": "debug", "DB": {"SCHEMA": "test"}}`)
+
+	values, loadErr := NewFileSource(path, true).Load()
+	suite.Require().NoError(loadErr)
+	suite.Assert().Equal(map[string]string{"LOG_LEVEL": "debug", "DB_SCHEMA": "test"}, values)
+}
+
+func (suite *RegistrySourceSuite) TestDotEnvFileDoesNotTouchEnvironment() {
+	path := suite.writeFile(".env", "DOTENV_SOURCE_TEST_VALUE=hello\n")
+
+	values, loadErr := NewDotEnvSource(path, true).Load()
+	suite.Require().NoError(loadErr)
+	suite.Assert().Equal("hello", values["DOTENV_SOURCE_TEST_VALUE"])
+
+	_, inEnvironment := os.LookupEnv("DOTENV_SOURCE_TEST_VALUE")
+	suite.Assert().False(inEnvironment)
+}
+
+func (suite *RegistrySourceSuite) TestMissingFiles() {
+	missingPath := filepath.Join(suite.T().TempDir(), "missing.yaml")
+
+	_, optionalErr := NewFileSource(missingPath, false).Load()
+	suite.Assert().NoError(optionalErr)
+
+	_, requiredErr := NewFileSource(missingPath, true).Load()
+	suite.Assert().ErrorIs(requiredErr, os.ErrNotExist)
+
+	builder := NewLayeredRegistryBuilder(NewDotEnvSource(missingPath, true))
+	_, buildErr := builder.VerifyAndBuild()
+	suite.Assert().ErrorIs(buildErr, os.ErrNotExist)
+}
+
+func (suite *RegistrySourceSuite) TestFlagParsing() {
+	values, loadErr := NewFlagSource([]string{"--db-host", "localhost", "--listen-port=80", "--verbose"}).Load()
+	suite.Require().NoError(loadErr)
+	suite.Assert().Equal(map[string]string{
+		"DB_HOST":     "localhost",
+		"LISTEN_PORT": "80",
+		"VERBOSE":     "true",
+	}, values)
+
+	_, badArgErr := NewFlagSource([]string{"positional"}).Load()
+	suite.Assert().Error(badArgErr)
+}
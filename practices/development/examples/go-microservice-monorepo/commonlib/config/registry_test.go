@@ -30,7 +30,7 @@ func (suite *RegistrySuite) TestPresenceVerification() {
 	requiredOption := NewOption("MY_OPTION", true)
 	notRequiredOption := NewOption("OTHER_OPTION", false)
 	builder := NewMockRegistryBuilder(nil)
-	builder.AddOptions([]Option{
+	builder.AddOptions([]AnyOption{
 		requiredOption,
 		notRequiredOption,
 	})
@@ -63,7 +63,7 @@ func (suite *RegistrySuite) TestValidationAppliesForPresentVariables() {
 		validatedRequiredOption.VariableName(): "vanilla",
 		validatedOptionalOption.VariableName(): "mayonnaise",
 	})
-	builder.AddOptions([]Option{
+	builder.AddOptions([]AnyOption{
 		validatedRequiredOption,
 		validatedOptionalOption,
 		validatedNotPresentRequiredOption,
@@ -94,7 +94,7 @@ func (suite *RegistrySuite) TestOptionPresence() {
 	builder := NewMockRegistryBuilder(map[string]string{
 		presentVariable.VariableName(): "hello",
 	})
-	builder.AddOptions([]Option{
+	builder.AddOptions([]AnyOption{
 		presentVariable,
 		notPresentVariable,
 	})
@@ -141,7 +141,7 @@ func (suite *RegistrySuite) TestOptionsRetrieveSuccessfully() {
 		optionalOption.VariableName(): "abcde",
 		requiredOption.VariableName(): "wxyz",
 	})
-	builder.AddOptions([]Option{
+	builder.AddOptions([]AnyOption{
 		requiredOption,
 		optionalOption,
 	})
@@ -156,3 +156,34 @@ func (suite *RegistrySuite) TestOptionsRetrieveSuccessfully() {
 	suite.Assert().Equal("abcde", optionalValue)
 	suite.Assert().Equal("wxyz", requiredValue)
 }
+
+func (suite *RegistrySuite) TestDefaultsApplyWhenAbsent() {
+	defaultedOption := NewOption("DEFAULTED", false).WithDefault("fallback")
+	explicitOption := NewOption("EXPLICIT", false).WithDefault("fallback")
+	builder := NewMockRegistryBuilder(map[string]string{
+		explicitOption.VariableName(): "configured",
+	})
+	builder.AddOptions([]AnyOption{defaultedOption, explicitOption})
+	registry, buildErr := builder.VerifyAndBuild()
+	suite.Require().NoError(buildErr)
+
+	defaultedValue, defaultedPresent := registry.Get(defaultedOption)
+	suite.Assert().True(defaultedPresent)
+	suite.Assert().Equal("fallback", defaultedValue)
+	suite.Assert().True(registry.IsDefault(defaultedOption))
+
+	suite.Assert().Equal("configured", registry.GetRequired(explicitOption))
+	suite.Assert().False(registry.IsDefault(explicitOption))
+}
+
+func (suite *RegistrySuite) TestInvalidDefaultsAreReported() {
+	option := NewIntOption("BAD_DEFAULT", false).WithDefault("twelve")
+	builder := NewMockRegistryBuilder(nil)
+	builder.AddOption(option)
+	_, buildErr := builder.VerifyAndBuild()
+
+	var configErr ErrIncorrectConfiguration
+	suite.Require().ErrorAs(buildErr, &configErr)
+	suite.Require().Len(configErr.InvalidVariables, 1)
+	suite.Assert().Equal(option.VariableName(), configErr.InvalidVariables[0].Name)
+}
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Provider supplies the current configuration Registry. A Registry provides itself, while a ReloadableRegistry provides
+// its most recently loaded snapshot, so components accepting a Provider work with either one.
+type Provider interface {
+	// Current returns the current snapshot of the configuration
+	Current() Registry
+}
+
+// Current implements Provider for Registry, which never changes once built
+func (reg Registry) Current() Registry {
+	return reg
+}
+
+// ReloadableRegistry is an opt-in alternative to a plain Registry which can re-read its configuration sources while the
+// application runs. Each reload is verified exactly like the initial build, and the new snapshot only replaces the old
+// one if verification succeeds, so a bad edit to a configuration file never leaves the application half-configured.
+// Components can subscribe to changes of specific options to apply new values as they arrive.
+type ReloadableRegistry struct {
+	builder RegistryBuilder
+	current atomic.Pointer[Registry]
+
+	// reloadLock makes sure reloads, and the notifications they trigger, happen one at a time
+	reloadLock sync.Mutex
+
+	subscriptionLock   sync.Mutex
+	subscriptions      map[int]subscription
+	nextSubscriptionID int
+}
+
+// subscription is a callback interested in changes to a set of options
+type subscription struct {
+	optionNames []string
+	callback    func(Registry)
+}
+
+// NewReloadableRegistry verifies and builds the initial snapshot of a ReloadableRegistry from the passed builder. Later
+// reloads read the builder's sources again. An error is returned if the initial configuration is invalid.
+func NewReloadableRegistry(builder RegistryBuilder) (*ReloadableRegistry, error) {
+	initialRegistry, buildErr := builder.VerifyAndBuild()
+	if buildErr != nil {
+		return nil, buildErr
+	}
+
+	reloadable := &ReloadableRegistry{
+		builder:       builder,
+		subscriptions: make(map[int]subscription),
+	}
+	reloadable.current.Store(&initialRegistry)
+	return reloadable, nil
+}
+
+// Current implements Provider for ReloadableRegistry, returning the most recently loaded configuration snapshot
+func (reg *ReloadableRegistry) Current() Registry {
+	return *reg.current.Load()
+}
+
+// Subscribe registers a callback which is invoked with the new snapshot whenever a reload changes the value of any of
+// the passed options. Callbacks run on the goroutine performing the reload. The returned function cancels the
+// subscription.
+func (reg *ReloadableRegistry) Subscribe(callback func(Registry), options ...AnyOption) (unsubscribe func()) {
+	current := reg.Current()
+	optionNames := make([]string, 0, len(options))
+	for _, option := range options {
+		optionNames = append(optionNames, current.mustBeRegistered(option).envName)
+	}
+
+	reg.subscriptionLock.Lock()
+	defer reg.subscriptionLock.Unlock()
+	subscriptionID := reg.nextSubscriptionID
+	reg.nextSubscriptionID++
+	reg.subscriptions[subscriptionID] = subscription{
+		optionNames: optionNames,
+		callback:    callback,
+	}
+
+	return func() {
+		reg.subscriptionLock.Lock()
+		defer reg.subscriptionLock.Unlock()
+		delete(reg.subscriptions, subscriptionID)
+	}
+}
+
+// Reload re-reads every configuration source and verifies the result. If verification succeeds, the new snapshot
+// replaces the current one and subscribers to changed options are notified. Otherwise, the current snapshot is kept
+// and the verification error is returned.
+func (reg *ReloadableRegistry) Reload() error {
+	reg.reloadLock.Lock()
+	defer reg.reloadLock.Unlock()
+
+	newRegistry, buildErr := reg.builder.VerifyAndBuild()
+	if buildErr != nil {
+		return buildErr
+	}
+
+	oldRegistry := reg.current.Swap(&newRegistry)
+	for _, sub := range reg.subscriptionsSnapshot() {
+		if valuesChanged(*oldRegistry, newRegistry, sub.optionNames) {
+			sub.callback(newRegistry)
+		}
+	}
+
+	return nil
+}
+
+// subscriptionsSnapshot copies the current subscriptions so callbacks can run without holding the subscription lock
+func (reg *ReloadableRegistry) subscriptionsSnapshot() []subscription {
+	reg.subscriptionLock.Lock()
+	defer reg.subscriptionLock.Unlock()
+
+	subscriptions := make([]subscription, 0, len(reg.subscriptions))
+	for _, sub := range reg.subscriptions {
+		subscriptions = append(subscriptions, sub)
+	}
+	return subscriptions
+}
+
+// valuesChanged reports whether any of the named options has a different value, or presence, between two snapshots
+func valuesChanged(oldRegistry Registry, newRegistry Registry, optionNames []string) bool {
+	for _, optionName := range optionNames {
+		oldValue, oldPresent := oldRegistry.values[optionName]
+		newValue, newPresent := newRegistry.values[optionName]
+		if oldPresent != newPresent || oldValue.value != newValue.value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WatchSignals reloads the registry every time the process receives SIGHUP, until the passed context is cancelled.
+// The result of each reload attempt is passed to onReload, which is a good place to log failures. This function
+// blocks, so it's usually run in its own goroutine.
+func (reg *ReloadableRegistry) WatchSignals(ctx context.Context, onReload func(error)) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+			onReload(reg.Reload())
+		}
+	}
+}
+
+// WatchFiles checks the files backing the registry's file and .env sources once per poll interval, reloading the
+// registry whenever one of them changes, until the passed context is cancelled. The result of each reload attempt is
+// passed to onReload. This function blocks, so it's usually run in its own goroutine.
+func (reg *ReloadableRegistry) WatchFiles(ctx context.Context, pollInterval time.Duration, onReload func(error)) {
+	lastStates := reg.fileStates()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			currentStates := reg.fileStates()
+			if currentStates != lastStates {
+				lastStates = currentStates
+				onReload(reg.Reload())
+			}
+		}
+	}
+}
+
+// ReloadPeriodically reloads the registry once per interval until the passed context is cancelled, which picks up
+// changes in sources that can't be watched, such as a KVSource. The result of each reload attempt is passed to
+// onReload. This function blocks, so it's usually run in its own goroutine.
+func (reg *ReloadableRegistry) ReloadPeriodically(ctx context.Context, interval time.Duration, onReload func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			onReload(reg.Reload())
+		}
+	}
+}
+
+// fileBackedSource is implemented by sources which read their values from a file on disk
+type fileBackedSource interface {
+	filePath() string
+}
+
+// filePath implements fileBackedSource for fileSource
+func (src fileSource) filePath() string {
+	return src.path
+}
+
+// filePath implements fileBackedSource for dotEnvSource
+func (src dotEnvSource) filePath() string {
+	return src.path
+}
+
+// fileStates summarizes the modification time and size of every file backing the registry's sources, so a change to
+// any of them produces a different summary. Missing files are included in the summary as missing.
+func (reg *ReloadableRegistry) fileStates() string {
+	var states string
+	for _, source := range reg.builder.sources {
+		if backedSource, isFileBacked := source.(fileBackedSource); isFileBacked {
+			states += backedSource.filePath() + ":"
+			if fileInfo, statErr := os.Stat(backedSource.filePath()); statErr == nil {
+				states += fileInfo.ModTime().String() + ":" + strconv.FormatInt(fileInfo.Size(), 10)
+			}
+			states += ";"
+		}
+	}
+
+	return states
+}
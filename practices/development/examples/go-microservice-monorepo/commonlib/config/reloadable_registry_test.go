@@ -0,0 +1,84 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ReloadableRegistrySuite struct {
+	suite.Suite
+	configPath  string
+	levelOption TypedOption[int]
+	otherOption Option
+	registry    *ReloadableRegistry
+}
+
+func TestReloadableRegistrySuite(t *testing.T) {
+	suite.Run(t, new(ReloadableRegistrySuite))
+}
+
+func (suite *ReloadableRegistrySuite) SetupTest() {
+	suite.configPath = filepath.Join(suite.T().TempDir(), "config.yaml")
+	suite.writeConfig("LEVEL: 1\nOTHER: a\n")
+
+	suite.levelOption = NewIntOption("LEVEL", true)
+	suite.otherOption = NewOption("OTHER", false)
+	builder := NewLayeredRegistryBuilder(NewFileSource(suite.configPath, true))
+	builder.AddOptions([]AnyOption{suite.levelOption, suite.otherOption})
+
+	var buildErr error
+	suite.registry, buildErr = NewReloadableRegistry(builder)
+	suite.Require().NoError(buildErr)
+}
+
+func (suite *ReloadableRegistrySuite) writeConfig(contents string) {
+	suite.Require().NoError(os.WriteFile(suite.configPath, []byte(contents), 0o600))
+}
+
+func (suite *ReloadableRegistrySuite) TestReloadReplacesSnapshotAndNotifiesSubscribers() {
+	var notifiedLevels []int
+	suite.registry.Subscribe(func(current Registry) {
+		notifiedLevels = append(notifiedLevels, GetRequiredTyped(current, suite.levelOption))
+	}, suite.levelOption)
+
+	suite.writeConfig("LEVEL: 2\nOTHER: a\n")
+	suite.Require().NoError(suite.registry.Reload())
+	suite.Assert().Equal(2, GetRequiredTyped(suite.registry.Current(), suite.levelOption))
+
+	// Changing an option nobody subscribed to doesn't notify anyone
+	suite.writeConfig("LEVEL: 2\nOTHER: b\n")
+	suite.Require().NoError(suite.registry.Reload())
+
+	suite.Assert().Equal([]int{2}, notifiedLevels)
+}
+
+func (suite *ReloadableRegistrySuite) TestFailedReloadKeepsPreviousSnapshot() {
+	var notified bool
+	suite.registry.Subscribe(func(Registry) {
+		notified = true
+	}, suite.levelOption)
+
+	suite.writeConfig("LEVEL: not a number\n")
+	reloadErr := suite.registry.Reload()
+
+	suite.Require().ErrorIs(reloadErr, ErrIncorrectConfiguration{})
+	suite.Assert().Equal(1, GetRequiredTyped(suite.registry.Current(), suite.levelOption))
+	otherValue, _ := suite.registry.Current().Get(suite.otherOption)
+	suite.Assert().Equal("a", otherValue)
+	suite.Assert().False(notified)
+}
+
+func (suite *ReloadableRegistrySuite) TestUnsubscribe() {
+	var notified bool
+	unsubscribe := suite.registry.Subscribe(func(Registry) {
+		notified = true
+	}, suite.levelOption)
+	unsubscribe()
+
+	suite.writeConfig("LEVEL: 3\n")
+	suite.Require().NoError(suite.registry.Reload())
+	suite.Assert().False(notified)
+}
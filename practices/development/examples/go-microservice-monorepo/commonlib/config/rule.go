@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rule is a registry-level validator which checks the relationship between several options, such as one limit being
+// lower than another, which can't be expressed by validating each option on its own. Rules are checked by
+// RegistryBuilder.VerifyAndBuild after every option has been verified individually.
+type Rule struct {
+	// Description is a short summary of what the rule enforces, used when reporting failures
+	Description string
+	// Options lists the options the rule checks. The rule is skipped if any of them is missing or invalid, since
+	// those problems are already reported, so the check can safely retrieve their values.
+	Options []AnyOption
+	// Check inspects the verified option values and returns an error if they break the rule. Errors shouldn't contain
+	// the values of secret options.
+	Check func(registry Registry) error
+}
+
+// NewRule constructs a Rule with the passed description, checking function, and the options it checks
+func NewRule(description string, check func(registry Registry) error, options ...AnyOption) Rule {
+	return Rule{
+		Description: description,
+		Options:     options,
+		Check:       check,
+	}
+}
+
+// AllOrNone constructs a Rule requiring that either every one of the passed options is present or none of them are,
+// such as a TLS certificate and its private key
+func AllOrNone(options ...AnyOption) Rule {
+	optionNames := make([]string, 0, len(options))
+	for _, option := range options {
+		optionNames = append(optionNames, option.baseOption().envName)
+	}
+
+	return NewRule(fmt.Sprintf("%v must be set together", strings.Join(optionNames, ", ")), func(registry Registry) error {
+		var presentNames, absentNames []string
+		for _, option := range options {
+			if _, isPresent := registry.Get(option); isPresent {
+				presentNames = append(presentNames, option.baseOption().envName)
+			} else {
+				absentNames = append(absentNames, option.baseOption().envName)
+			}
+		}
+
+		if len(presentNames) > 0 && len(absentNames) > 0 {
+			return fmt.Errorf("%v set but %v missing", strings.Join(presentNames, ", "), strings.Join(absentNames, ", "))
+		}
+		return nil
+	}, options...)
+}
+
+// FailedRule describes a Rule which the configuration broke
+type FailedRule struct {
+	// Description is the description of the rule
+	Description string
+	// Variables lists the names of the variables the rule checks
+	Variables []string
+	// RuleError is the error returned by the rule's check
+	RuleError error
+}
+
+// AddRule registers a Rule with the builder. Every option the rule checks must also be registered with the builder
+// before the registry is built.
+func (rb *RegistryBuilder) AddRule(rule Rule) {
+	rb.rules = append(rb.rules, rule)
+}
+
+// AddRules registers a list of Rules with the builder
+func (rb *RegistryBuilder) AddRules(rules []Rule) {
+	for _, rule := range rules {
+		rb.AddRule(rule)
+	}
+}
+
+// checkRules runs every registered rule whose options were all verified successfully against a registry of the
+// verified values, returning the rules which failed. The names of options which were missing or invalid are passed in
+// skippedNames.
+func (rb *RegistryBuilder) checkRules(verified Registry, skippedNames map[string]bool) []FailedRule {
+	var failedRules []FailedRule
+	for _, rule := range rb.rules {
+		variableNames := make([]string, 0, len(rule.Options))
+		canCheck := true
+		for _, option := range rule.Options {
+			optionName := verified.mustBeRegistered(option).envName
+			variableNames = append(variableNames, optionName)
+			if skippedNames[optionName] {
+				canCheck = false
+			}
+		}
+		if !canCheck {
+			continue
+		}
+
+		if ruleErr := rule.Check(verified); ruleErr != nil {
+			failedRules = append(failedRules, FailedRule{
+				Description: rule.Description,
+				Variables:   variableNames,
+				RuleError:   ruleErr,
+			})
+		}
+	}
+
+	return failedRules
+}
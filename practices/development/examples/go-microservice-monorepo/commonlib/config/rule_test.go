@@ -0,0 +1,96 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RuleSuite struct {
+	suite.Suite
+	lowOption  TypedOption[int]
+	highOption TypedOption[int]
+	lowerRule  Rule
+}
+
+func TestRuleSuite(t *testing.T) {
+	suite.Run(t, new(RuleSuite))
+}
+
+func (suite *RuleSuite) SetupTest() {
+	suite.lowOption = NewIntOption("LOW", true)
+	suite.highOption = NewIntOption("HIGH", true)
+	suite.lowerRule = NewRule("low must be lower than high", func(registry Registry) error {
+		if GetRequiredTyped(registry, suite.lowOption) >= GetRequiredTyped(registry, suite.highOption) {
+			return errors.New("low is too high")
+		}
+		return nil
+	}, suite.lowOption, suite.highOption)
+}
+
+func (suite *RuleSuite) build(environment map[string]string) (Registry, error) {
+	builder := NewMockRegistryBuilder(environment)
+	builder.AddOptions([]AnyOption{suite.lowOption, suite.highOption})
+	builder.AddRule(suite.lowerRule)
+	return builder.VerifyAndBuild()
+}
+
+func (suite *RuleSuite) TestPassingRule() {
+	_, buildErr := suite.build(map[string]string{"LOW": "1", "HIGH": "2"})
+	suite.Require().NoError(buildErr)
+}
+
+func (suite *RuleSuite) TestFailingRuleIsReported() {
+	_, buildErr := suite.build(map[string]string{"LOW": "3", "HIGH": "2"})
+
+	var configErr ErrIncorrectConfiguration
+	suite.Require().ErrorAs(buildErr, &configErr)
+	suite.Require().Len(configErr.FailedRules, 1)
+	suite.Assert().Equal("low must be lower than high", configErr.FailedRules[0].Description)
+	suite.Assert().Equal([]string{"LOW", "HIGH"}, configErr.FailedRules[0].Variables)
+	suite.Assert().Contains(buildErr.Error(), "low is too high")
+}
+
+func (suite *RuleSuite) TestRuleIsSkippedWhenItsOptionsAreBroken() {
+	_, buildErr := suite.build(map[string]string{"LOW": "not a number"})
+
+	var configErr ErrIncorrectConfiguration
+	suite.Require().ErrorAs(buildErr, &configErr)
+	suite.Assert().Equal([]string{"HIGH"}, configErr.MissingRequiredVariables)
+	suite.Assert().Len(configErr.InvalidVariables, 1)
+	suite.Assert().Empty(configErr.FailedRules)
+}
+
+func (suite *RuleSuite) TestAllOrNone() {
+	certOption := NewOption("TLS_CERT", false)
+	keyOption := NewOption("TLS_KEY", false)
+
+	subtests := []struct {
+		testName    string
+		environment map[string]string
+		shouldPass  bool
+	}{
+		{testName: "Neither set", environment: nil, shouldPass: true},
+		{testName: "Both set", environment: map[string]string{"TLS_CERT": "cert", "TLS_KEY": "key"}, shouldPass: true},
+		{testName: "Only one set", environment: map[string]string{"TLS_CERT": "cert"}, shouldPass: false},
+	}
+
+	for _, subtest := range subtests {
+		suite.Run(subtest.testName, func() {
+			builder := NewMockRegistryBuilder(subtest.environment)
+			builder.AddOptions([]AnyOption{certOption, keyOption})
+			builder.AddRule(AllOrNone(certOption, keyOption))
+			_, buildErr := builder.VerifyAndBuild()
+
+			if subtest.shouldPass {
+				suite.Require().NoError(buildErr)
+			} else {
+				var configErr ErrIncorrectConfiguration
+				suite.Require().ErrorAs(buildErr, &configErr)
+				suite.Require().Len(configErr.FailedRules, 1)
+				suite.Assert().Equal("TLS_CERT set but TLS_KEY missing", configErr.FailedRules[0].RuleError.Error())
+			}
+		})
+	}
+}
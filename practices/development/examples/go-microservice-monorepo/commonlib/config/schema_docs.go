@@ -0,0 +1,138 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GenerateMarkdown renders a markdown reference of every option in the passed schema, suitable for checking in next to
+// a service's documentation. Pass the result of RegistryBuilder.Schema or Registry.Schema.
+func GenerateMarkdown(schema []OptionSchema) string {
+	var builder strings.Builder
+	builder.WriteString("| Variable | Type | Required | Default | Description |\n")
+	builder.WriteString("|----------|------|----------|---------|-------------|\n")
+
+	for _, option := range schema {
+		required := "No"
+		if option.Required {
+			required = "Yes"
+		}
+
+		defaultValue := ""
+		if option.HasDefault {
+			defaultValue = fmt.Sprintf("`%v`", option.Default)
+		}
+
+		description := option.Description
+		if len(option.AllowedValues) > 0 {
+			description = appendSentence(description, fmt.Sprintf("One of: `%v`.", strings.Join(option.AllowedValues, "`, `")))
+		}
+		for _, profile := range AllProfiles {
+			if profileDefault, hasProfileDefault := option.ProfileDefaults[profile]; hasProfileDefault {
+				description = appendSentence(description, fmt.Sprintf("Defaults to `%v` in the %v profile.", profileDefault, profile))
+			}
+		}
+		if option.Secret {
+			description = appendSentence(description, fmt.Sprintf("Secret, may instead be read from the file named by `%v`.", option.SecretFileName))
+		}
+		if len(option.DeprecatedAliases) > 0 {
+			description = appendSentence(description, fmt.Sprintf("Formerly `%v`, which is deprecated.", strings.Join(option.DeprecatedAliases, "`, `")))
+		}
+
+		builder.WriteString(fmt.Sprintf("| `%v` | %v | %v | %v | %v |\n",
+			option.Name, option.Type, required, defaultValue, escapeTableCell(description)))
+	}
+
+	return builder.String()
+}
+
+// appendSentence appends a sentence to a possibly empty piece of text, ending the text with a period first if needed
+func appendSentence(text string, sentence string) string {
+	if text == "" {
+		return sentence
+	}
+	if !strings.HasSuffix(text, ".") {
+		text += "."
+	}
+
+	return text + " " + sentence
+}
+
+// escapeTableCell makes text safe to place inside a markdown table cell
+func escapeTableCell(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "|", "\\|"), "\n", " ")
+}
+
+// jsonSchemaProperty describes a single variable in a generated JSON Schema document
+type jsonSchemaProperty struct {
+	Type        string   `json:"type"`
+	Description string   `json:"description,omitempty"`
+	Default     *string  `json:"default,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	WriteOnly   bool     `json:"writeOnly,omitempty"`
+	Deprecated  bool     `json:"deprecated,omitempty"`
+}
+
+// jsonSchemaDocument is the top level of a generated JSON Schema document
+type jsonSchemaDocument struct {
+	Schema     string                        `json:"$schema"`
+	Type       string                        `json:"type"`
+	Properties map[string]jsonSchemaProperty `json:"properties"`
+	Required   []string                      `json:"required"`
+}
+
+// jsonSchemaPatterns constrains the string form of option types which have one
+var jsonSchemaPatterns = map[string]string{
+	"integer": "^[+-]?[0-9]+$",
+	"boolean": "^(1|0|t|f|T|F|true|false|TRUE|FALSE|True|False)$",
+}
+
+// GenerateJSONSchema renders a JSON Schema document describing a map of variable names to values, such as a parsed .env
+// file, which satisfies the passed schema. Every value is a string, since that's how every configuration source
+// supplies values. Secret options are marked writeOnly and their defaults are redacted.
+func GenerateJSONSchema(schema []OptionSchema) ([]byte, error) {
+	document := jsonSchemaDocument{
+		Schema:     "https://json-schema.org/draft/2020-12/schema",
+		Type:       "object",
+		Properties: make(map[string]jsonSchemaProperty),
+		Required:   []string{},
+	}
+
+	for _, option := range schema {
+		property := jsonSchemaProperty{
+			Type:        "string",
+			Description: option.Description,
+			Enum:        option.AllowedValues,
+			Pattern:     jsonSchemaPatterns[option.Type],
+			WriteOnly:   option.Secret,
+		}
+		if option.HasDefault {
+			defaultValue := option.Default
+			property.Default = &defaultValue
+		}
+		document.Properties[option.Name] = property
+
+		if option.Secret {
+			document.Properties[option.SecretFileName] = jsonSchemaProperty{
+				Type:        "string",
+				Description: fmt.Sprintf("Path to a file containing the value of %v", option.Name),
+			}
+		}
+		for _, aliasName := range option.DeprecatedAliases {
+			document.Properties[aliasName] = jsonSchemaProperty{
+				Type:        "string",
+				Description: fmt.Sprintf("Deprecated name of %v", option.Name),
+				Deprecated:  true,
+			}
+		}
+		// A secret can be read from its file instead, and an option with aliases can be set by its former names, so
+		// neither can be listed as required on its own
+		if option.Required && !option.HasDefault && !option.Secret && len(option.DeprecatedAliases) == 0 {
+			document.Required = append(document.Required, option.Name)
+		}
+	}
+
+	return json.MarshalIndent(document, "", "  ")
+}
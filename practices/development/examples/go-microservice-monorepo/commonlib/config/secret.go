@@ -0,0 +1,94 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// RedactedText replaces secret values wherever they would otherwise be printed
+const RedactedText = "[REDACTED]"
+
+// Secret is a string holding a sensitive value such as a password. It is redacted whenever it is formatted, logged, or
+// serialized to JSON, so the real value must be explicitly retrieved with Reveal.
+type Secret string
+
+// Reveal returns the real value of the secret. Only call this where the value is actually needed, such as when
+// authenticating with another system.
+func (secret Secret) Reveal() string {
+	return string(secret)
+}
+
+// String implements fmt.Stringer for Secret, returning redacted text rather than the secret value
+func (secret Secret) String() string {
+	return RedactedText
+}
+
+// GoString implements fmt.GoStringer for Secret so the value is redacted when formatted with %#v
+func (secret Secret) GoString() string {
+	return RedactedText
+}
+
+// Format implements fmt.Formatter for Secret so the value is redacted regardless of the formatting verb
+func (secret Secret) Format(state fmt.State, _ rune) {
+	_, _ = state.Write([]byte(RedactedText))
+}
+
+// MarshalJSON implements json.Marshaler for Secret, serializing redacted text rather than the secret value
+func (secret Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + RedactedText + `"`), nil
+}
+
+// NewSecretOption constructs a TypedOption holding a Secret. Besides being redacted wherever it's printed, including in
+// ErrIncorrectConfiguration, a secret option supports the *_FILE convention used for Docker and Kubernetes secrets: if
+// the option itself isn't set but a variable with the same name and a _FILE suffix is, the value is read from the
+// file at that path. Setting both is a configuration error.
+func NewSecretOption(envName string, required bool) TypedOption[Secret] {
+	option := NewTypedOption(envName, required, func(value string) (Secret, error) {
+		return Secret(value), nil
+	}).withTypeName("secret")
+	option.secret = true
+	return option
+}
+
+// secretFileVariableName returns the name of the variable pointing to a file containing the value of a secret option
+func secretFileVariableName(option Option) string {
+	return option.envName + "_FILE"
+}
+
+// lookupSecretFile reads the value of a secret option from the file named by its *_FILE variable, if that variable is
+// present in the layered configuration sources. The returned InvalidVariable is non-nil if the file couldn't be read.
+func lookupSecretFile(option Option, layeredValues map[string]resolvedValue) (resolvedValue, bool, *InvalidVariable) {
+	fileVariableName := secretFileVariableName(option)
+	filePath, filePresent := layeredValues[fileVariableName]
+	if !filePresent {
+		return resolvedValue{}, false, nil
+	}
+
+	contents, readErr := os.ReadFile(filePath.value)
+	if readErr != nil {
+		return resolvedValue{}, false, &InvalidVariable{
+			Name:            fileVariableName,
+			ValidationError: fmt.Errorf("could not read secret file: %w", readErr),
+		}
+	}
+
+	return resolvedValue{
+		// Mounted secrets and files written by editors commonly end with a newline that isn't part of the value
+		value:      strings.TrimRight(string(contents), "\r\n"),
+		sourceName: fmt.Sprintf("%v via %v", filePath.sourceName, fileVariableName),
+	}, true, nil
+}
+
+// redactError produces an error with the same message as the passed error, except every occurrence of the secret value
+// is replaced with RedactedText. The original error is intentionally not wrapped, since unwrapping it would expose the
+// unredacted message.
+func redactError(err error, secretValue string) error {
+	message := err.Error()
+	if secretValue != "" {
+		message = strings.ReplaceAll(message, secretValue, RedactedText)
+	}
+
+	return errors.New(message)
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretSuite struct {
+	suite.Suite
+}
+
+func TestSecretSuite(t *testing.T) {
+	suite.Run(t, new(SecretSuite))
+}
+
+func (suite *SecretSuite) TestSecretIsRedactedWhenPrinted() {
+	secret := Secret("hunter2")
+	type connectionSettings struct {
+		Password Secret
+	}
+	settings := connectionSettings{Password: secret}
+
+	suite.Assert().Equal(RedactedText, secret.String())
+	suite.Assert().NotContains(fmt.Sprintf("%v %s %q %+v %#v", secret, secret, secret, settings, settings), "hunter2")
+
+	serialized, marshalErr := json.Marshal(settings)
+	suite.Require().NoError(marshalErr)
+	suite.Assert().NotContains(string(serialized), "hunter2")
+
+	suite.Assert().Equal("hunter2", secret.Reveal())
+}
+
+func (suite *SecretSuite) TestValidationErrorsAreRedacted() {
+	option := NewSecretOption("API_KEY", true).WithValidation(func(value string) error {
+		return fmt.Errorf("%v is too short", value)
+	})
+	builder := NewMockRegistryBuilder(map[string]string{option.VariableName(): "hunter2"})
+	builder.AddOption(option)
+	_, buildErr := builder.VerifyAndBuild()
+
+	var configErr ErrIncorrectConfiguration
+	suite.Require().ErrorAs(buildErr, &configErr)
+	suite.Assert().NotContains(buildErr.Error(), "hunter2")
+	suite.Assert().Contains(buildErr.Error(), RedactedText)
+	suite.Assert().Nil(errors.Unwrap(configErr.InvalidVariables[0].ValidationError))
+}
+
+func (suite *SecretSuite) TestSecretIsReadFromFile() {
+	secretPath := filepath.Join(suite.T().TempDir(), "db_password")
+	suite.Require().NoError(os.WriteFile(secretPath, []byte("hunter2\n"), 0o600))
+
+	option := NewSecretOption("DB_PASSWORD", true)
+	builder := NewMockRegistryBuilder(map[string]string{"DB_PASSWORD_FILE": secretPath})
+	builder.AddOption(option)
+	registry, buildErr := builder.VerifyAndBuild()
+	suite.Require().NoError(buildErr)
+
+	suite.Assert().Equal("hunter2", GetRequiredTyped(registry, option).Reveal())
+	sourceName, _ := registry.SourceOf(option)
+	suite.Assert().Equal("mock via DB_PASSWORD_FILE", sourceName)
+}
+
+func (suite *SecretSuite) TestSecretFileProblemsAreInvalidVariables() {
+	option := NewSecretOption("DB_PASSWORD", true)
+
+	suite.Run("Missing file", func() {
+		builder := NewMockRegistryBuilder(map[string]string{
+			"DB_PASSWORD_FILE": filepath.Join(suite.T().TempDir(), "missing"),
+		})
+		builder.AddOption(option)
+		_, buildErr := builder.VerifyAndBuild()
+
+		var configErr ErrIncorrectConfiguration
+		suite.Require().ErrorAs(buildErr, &configErr)
+		suite.Require().Len(configErr.InvalidVariables, 1)
+		suite.Assert().Equal("DB_PASSWORD_FILE", configErr.InvalidVariables[0].Name)
+	})
+
+	suite.Run("Both variables set", func() {
+		builder := NewMockRegistryBuilder(map[string]string{
+			"DB_PASSWORD":      "hunter2",
+			"DB_PASSWORD_FILE": "/run/secrets/db_password",
+		})
+		builder.AddOption(option)
+		_, buildErr := builder.VerifyAndBuild()
+
+		var configErr ErrIncorrectConfiguration
+		suite.Require().ErrorAs(buildErr, &configErr)
+		suite.Require().Len(configErr.InvalidVariables, 1)
+		suite.Assert().Equal("DB_PASSWORD", configErr.InvalidVariables[0].Name)
+		suite.Assert().NotContains(buildErr.Error(), "hunter2")
+	})
+}
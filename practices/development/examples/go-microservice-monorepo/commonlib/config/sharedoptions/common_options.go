@@ -1,64 +1,68 @@
 package sharedoptions
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	"example.com/sample/commonlib/config"
 	"github.com/jellydator/validation"
 	"github.com/jellydator/validation/is"
+	"go.uber.org/zap/zapcore"
 )
 
-// IsInProduction is either "true" or "false" and determines whether or not the app is executing in production mode
-var IsInProduction = config.NewValidatedOption("IS_PRODUCTION", true, func(value string) error {
-	return validation.Validate(
-		value,
-		validation.In("true", "false").Error("value must be 'true' or 'false'"),
-	)
-})
+// AppEnv selects the environment profile the application runs in, one of local, test, staging, or production. Query it
+// with Registry.Profile. It replaces IS_PRODUCTION, which is still honored as a deprecated alias, with "true" meaning
+// production and "false" meaning local.
+var AppEnv = config.NewProfileOption("APP_ENV").WithDeprecatedAlias(config.DeprecatedAlias{
+	Name: "IS_PRODUCTION",
+	Translate: func(value string) (string, error) {
+		switch value {
+		case "true":
+			return string(config.ProfileProduction), nil
+		case "false":
+			return string(config.ProfileLocal), nil
+		default:
+			return "", fmt.Errorf("value must be 'true' or 'false'")
+		}
+	},
+}).WithDescription("The environment the application runs in")
 
-// LogLevel determines the log level when starting the application. The value must be a valid Zap log level.
-var LogLevel = config.NewValidatedOption("LOG_LEVEL", false, func(value string) error {
+// LogLevel determines the log level when starting the application. The value must be a valid Zap log level, and
+// defaults to debug in the local profile and info otherwise.
+var LogLevel = config.NewTypedOption("LOG_LEVEL", false, zapcore.ParseLevel).WithDefault("info").
+	WithProfileDefault(config.ProfileLocal, "debug").WithValidation(func(value string) error {
 	return validation.Validate(
 		value,
 		validation.In("debug", "info", "warn", "error", "panic", "fatal").
 			Error("value must be one of debug, info, warn, error, panic, or fatal"),
 	)
-})
+}).WithDescription("The log level used when the application starts")
 
-// ListenPort determines the port the application listens on
-var ListenPort = config.NewValidatedOption("LISTEN_PORT", false, func(value string) error {
+// ListenPort determines the port the application listens on, and defaults to 8080
+var ListenPort = config.NewIntOption("LISTEN_PORT", false).WithDefault("8080").WithValidation(func(value string) error {
 	return validation.Validate(value, is.Port)
-})
-
-// AllowedOrigins contains a comma-separated list of allowed CORS origins
-var AllowedOrigins = config.NewOption("ALLOWED_CORS_ORIGINS", false)
-
-// DBUser is the username used to authenticate with the database
-var DBUser = config.NewOption("DB_USER", true)
-
-// DBPassword is the password used to authenticate with the database
-var DBPassword = config.NewOption("DB_PASSWORD", true)
-
-// DBHostname is the hostname of the database to connect to
-var DBHostname = config.NewValidatedOption("DB_HOST", true, func(value string) error {
-	return validation.Validate(value, is.Host)
-})
-
-// DBPort is the database port the application should connect to
-var DBPort = config.NewValidatedOption("DB_PORT", false, func(value string) error {
-	return validation.Validate(value, is.Int)
-})
-
-// DBSchema is the schema to use by default once connected to the database
-var DBSchema = config.NewOption("DB_SCHEMA", true)
+}).WithDescription("The port the application listens on")
 
-// DBMaxConnections is the number of total SQL connections the database pool cannot exceed
-var DBMaxConnections = config.NewValidatedOption("DB_MAX_CONNECTIONS", false, func(value string) error {
-	return validation.Validate(value, is.Int)
-})
+// AllowedOrigins contains a comma-separated list of allowed CORS origins, and defaults to http://localhost:8080
+var AllowedOrigins = config.NewStringListOption("ALLOWED_CORS_ORIGINS", false).WithDefault("http://localhost:8080").
+	WithDescription("Comma-separated list of origins allowed to make cross-origin requests")
 
-// DBMaxIdleConnections is the number of total idle SQL connections the database pool cannot exceed. This number should be less than DBMaxConnections.
-var DBMaxIdleConnections = config.NewValidatedOption("DB_MAX_IDLE_CONNECTIONS", false, func(value string) error {
-	return validation.Validate(value, is.Int.Error("should be a valid port number"))
-})
+// ProductionCorsRule holds CORS origins to a stricter standard while the application is in the production profile:
+// every origin must be listed explicitly rather than allowed with "*", and must use https
+var ProductionCorsRule = config.NewRule("production must only allow specific https CORS origins", func(registry config.Registry) error {
+	if !registry.Profile().IsProduction() {
+		return nil
+	}
 
-// DBOptions is a bundle of all available database configuration options
-var DBOptions = []config.Option{DBUser, DBPassword, DBHostname, DBPort, DBSchema, DBMaxConnections, DBMaxIdleConnections}
+	allowedOrigins := config.GetRequiredTyped(registry, AllowedOrigins)
+	if slices.Contains(allowedOrigins, "*") {
+		return fmt.Errorf("%v must list specific origins rather than \"*\" in production", AllowedOrigins.VariableName())
+	}
+	for _, origin := range allowedOrigins {
+		if !strings.HasPrefix(origin, "https://") {
+			return fmt.Errorf("%v must only list https origins in production, but %v isn't", AllowedOrigins.VariableName(), origin)
+		}
+	}
+	return nil
+}, AppEnv, AllowedOrigins)
@@ -4,11 +4,14 @@ import (
 	"testing"
 
 	"example.com/sample/commonlib/config"
+	"example.com/sample/commonlib/config/testhelper"
 	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap/zapcore"
 )
 
 type CommonOptionsSuite struct {
 	suite.Suite
+	fixture *testhelper.RegistryFixture
 }
 
 type validationSubtestParams struct {
@@ -21,44 +24,55 @@ func TestCommonOptionsSuite(t *testing.T) {
 	suite.Run(t, new(CommonOptionsSuite))
 }
 
-func (suite *CommonOptionsSuite) TestIsInProductionIsRequired() {
-	builder := config.NewMockRegistryBuilder(nil)
-	builder.AddOption(IsInProduction)
-	_, rawBuildErr := builder.VerifyAndBuild()
+func (suite *CommonOptionsSuite) SetupTest() {
+	commonOptions := []config.AnyOption{AppEnv, LogLevel, ListenPort, AllowedOrigins}
+	suite.fixture = testhelper.NewRegistryFixture(suite.T(), testhelper.BuilderOf(commonOptions, ProductionCorsRule),
+		map[string]string{
+			AppEnv.VariableName():         string(config.ProfileTest),
+			AllowedOrigins.VariableName(): "https://example.com",
+		})
+}
+
+// build attempts to build a registry from the fixture's current values
+func (suite *CommonOptionsSuite) build() (config.Registry, error) {
+	builder := suite.fixture.Builder()
+	return builder.VerifyAndBuild()
+}
+
+func (suite *CommonOptionsSuite) TestAppEnvIsRequired() {
+	suite.fixture.Unset(suite.T(), AppEnv.VariableName())
+	_, rawBuildErr := suite.build()
 	suite.Require().Error(rawBuildErr)
 
 	var buildError config.ErrIncorrectConfiguration
 	suite.Require().ErrorAs(rawBuildErr, &buildError)
-	suite.Require().Contains(buildError.MissingRequiredVariables, IsInProduction.VariableName())
+	suite.Require().Contains(buildError.MissingRequiredVariables, AppEnv.VariableName())
 }
 
-func (suite *CommonOptionsSuite) TestIsInProductionValidation() {
+func (suite *CommonOptionsSuite) TestAppEnvValidation() {
 
 	subtests := []validationSubtestParams{
 		{
-			testName:             "Fails on not true or false",
-			registryValue:        "not true or false",
+			testName:             "Fails on unknown profile",
+			registryValue:        "prod",
 			shouldPassValidation: false,
 		},
 		{
-			testName:             "Succeeds on true",
-			registryValue:        "true",
+			testName:             "Succeeds on local",
+			registryValue:        "local",
 			shouldPassValidation: true,
 		},
 		{
-			testName:             "Succeeds on false",
-			registryValue:        "false",
+			testName:             "Succeeds on production",
+			registryValue:        "production",
 			shouldPassValidation: true,
 		},
 	}
 
 	for _, subtest := range subtests {
 		suite.Run(subtest.testName, func() {
-			builder := config.NewMockRegistryBuilder(map[string]string{
-				IsInProduction.VariableName(): subtest.registryValue,
-			})
-			builder.AddOption(IsInProduction)
-			_, rawBuildErr := builder.VerifyAndBuild()
+			suite.fixture.Override(suite.T(), map[string]string{AppEnv.VariableName(): subtest.registryValue})
+			_, rawBuildErr := suite.build()
 
 			if subtest.shouldPassValidation {
 				suite.Require().NoError(rawBuildErr)
@@ -68,16 +82,63 @@ func (suite *CommonOptionsSuite) TestIsInProductionValidation() {
 				var buildError config.ErrIncorrectConfiguration
 				suite.Require().ErrorAs(rawBuildErr, &buildError)
 				suite.Require().Len(buildError.InvalidVariables, 1)
-				suite.Require().Equal(IsInProduction.VariableName(), buildError.InvalidVariables[0].Name)
+				suite.Require().Equal(AppEnv.VariableName(), buildError.InvalidVariables[0].Name)
 			}
 		})
 	}
 }
 
+func (suite *CommonOptionsSuite) TestIsProductionAlias() {
+	subtests := []struct {
+		testName        string
+		isInProduction  string
+		expectedProfile config.Profile
+	}{
+		{
+			testName:        "True means production",
+			isInProduction:  "true",
+			expectedProfile: config.ProfileProduction,
+		},
+		{
+			testName:        "False means local",
+			isInProduction:  "false",
+			expectedProfile: config.ProfileLocal,
+		},
+	}
+
+	for _, subtest := range subtests {
+		suite.Run(subtest.testName, func() {
+			suite.fixture.Unset(suite.T(), AppEnv.VariableName())
+			suite.fixture.Override(suite.T(), map[string]string{"IS_PRODUCTION": subtest.isInProduction})
+			registry := suite.fixture.Registry(suite.T())
+
+			suite.Assert().Equal(subtest.expectedProfile, registry.Profile())
+			suite.Assert().Len(registry.Warnings(), 1)
+		})
+	}
+}
+
+func (suite *CommonOptionsSuite) TestLogLevelProfileDefault() {
+	subtests := []struct {
+		profile       config.Profile
+		expectedLevel zapcore.Level
+	}{
+		{profile: config.ProfileLocal, expectedLevel: zapcore.DebugLevel},
+		{profile: config.ProfileStaging, expectedLevel: zapcore.InfoLevel},
+	}
+
+	for _, subtest := range subtests {
+		suite.Run(string(subtest.profile), func() {
+			suite.fixture.Override(suite.T(), map[string]string{AppEnv.VariableName(): string(subtest.profile)})
+			registry := suite.fixture.Registry(suite.T())
+
+			suite.Assert().Equal(subtest.expectedLevel, config.GetRequiredTyped(registry, LogLevel))
+		})
+	}
+}
+
 func (suite *CommonOptionsSuite) TestLogLevelIsNotRequired() {
-	builder := config.NewMockRegistryBuilder(nil)
-	builder.AddOption(LogLevel)
-	_, buildErr := builder.VerifyAndBuild()
+	_, buildErr := suite.build()
 
 	suite.Require().NoError(buildErr)
 }
@@ -123,11 +184,8 @@ func (suite *CommonOptionsSuite) TestLogLevelValidation() {
 
 	for _, subtest := range subtests {
 		suite.Run(subtest.testName, func() {
-			registryBuilder := config.NewMockRegistryBuilder(map[string]string{
-				LogLevel.VariableName(): subtest.registryValue,
-			})
-			registryBuilder.AddOption(LogLevel)
-			_, buildErr := registryBuilder.VerifyAndBuild()
+			suite.fixture.Override(suite.T(), map[string]string{LogLevel.VariableName(): subtest.registryValue})
+			_, buildErr := suite.build()
 
 			if subtest.shouldPassValidation {
 				suite.Require().NoError(buildErr)
@@ -142,3 +200,55 @@ func (suite *CommonOptionsSuite) TestLogLevelValidation() {
 		})
 	}
 }
+
+func (suite *CommonOptionsSuite) TestProductionCorsRule() {
+	subtests := []struct {
+		testName             string
+		appEnv               string
+		allowedOrigins       string
+		shouldPassValidation bool
+	}{
+		{
+			testName:             "Allows every origin outside production",
+			appEnv:               "staging",
+			allowedOrigins:       "http://localhost,*",
+			shouldPassValidation: true,
+		},
+		{
+			testName:             "Rejects every origin in production",
+			appEnv:               "production",
+			allowedOrigins:       "https://example.com,*",
+			shouldPassValidation: false,
+		},
+		{
+			testName:             "Rejects http origins in production",
+			appEnv:               "production",
+			allowedOrigins:       "https://example.com,http://example.com",
+			shouldPassValidation: false,
+		},
+		{
+			testName:             "Allows https origins in production",
+			appEnv:               "production",
+			allowedOrigins:       "https://example.com",
+			shouldPassValidation: true,
+		},
+	}
+
+	for _, subtest := range subtests {
+		suite.Run(subtest.testName, func() {
+			suite.fixture.Override(suite.T(), map[string]string{
+				AppEnv.VariableName():         subtest.appEnv,
+				AllowedOrigins.VariableName(): subtest.allowedOrigins,
+			})
+			_, buildErr := suite.build()
+
+			if subtest.shouldPassValidation {
+				suite.Require().NoError(buildErr)
+			} else {
+				var validationError config.ErrIncorrectConfiguration
+				suite.Require().ErrorAs(buildErr, &validationError)
+				suite.Require().Len(validationError.FailedRules, 1)
+			}
+		})
+	}
+}
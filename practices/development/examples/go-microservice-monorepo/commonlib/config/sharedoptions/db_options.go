@@ -0,0 +1,305 @@
+package sharedoptions
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"example.com/sample/commonlib/config"
+	"github.com/jellydator/validation"
+	"github.com/jellydator/validation/is"
+)
+
+// DBOptionGroup is the bundle of configuration options for one database connection. Construct one with
+// NewDBOptionGroup to configure an additional database under a prefix, such as a reporting database configured by
+// REPORTING_DB_HOST, REPORTING_DB_USER, and so on.
+type DBOptionGroup struct {
+	// Driver is the kind of database to connect to, either mysql or postgres, and defaults to mysql
+	Driver config.TypedOption[string]
+	// User is the username used to authenticate with the database
+	User config.Option
+	// Password is the password used to authenticate with the database. It can also be read from a mounted secret file.
+	Password config.TypedOption[config.Secret]
+	// Hostname is the hostname of the database to connect to
+	Hostname config.Option
+	// Port is the database port the application should connect to
+	Port config.TypedOption[int]
+	// Schema is the schema to use by default once connected to the database
+	Schema config.Option
+	// ReplicaHosts lists the hostnames of read replicas of the database, each optionally followed by a port. Replicas
+	// use the same credentials and schema as the primary database.
+	ReplicaHosts config.TypedOption[[]string]
+	// ReadYourWrites sends a request's reads to the primary database once it has written, so it doesn't read stale data
+	// from a replica which hasn't caught up yet. It defaults to true.
+	ReadYourWrites config.TypedOption[bool]
+	// ConnectMaxWait is how long to keep trying to reach the database at startup before giving up, and defaults to 5
+	// minutes
+	ConnectMaxWait config.TypedOption[time.Duration]
+	// SlowQueryThreshold is how long a statement may take before it's logged as a slow query, and defaults to 500ms.
+	// Zero disables the slow query log.
+	SlowQueryThreshold config.TypedOption[time.Duration]
+	// StatementTimeout is how long a statement run for a request may take before it's cancelled, and defaults to 30
+	// seconds. Zero disables the timeout.
+	StatementTimeout config.TypedOption[time.Duration]
+	// TLS is the TLS mode of database connections, one of false, true, skip-verify or preferred, and defaults to false
+	TLS config.TypedOption[string]
+	// TLSCAFile is the path of a PEM bundle of certificate authorities to verify the database server's certificate with,
+	// instead of the system's. It requires TLS to be true.
+	TLSCAFile config.Option
+	// DialTimeout limits how long establishing a database connection may take
+	DialTimeout config.TypedOption[time.Duration]
+	// ReadTimeout limits how long reading a response from a MySQL connection may take
+	ReadTimeout config.TypedOption[time.Duration]
+	// WriteTimeout limits how long writing a request to a MySQL connection may take
+	WriteTimeout config.TypedOption[time.Duration]
+	// ParseTime scans MySQL DATE and DATETIME columns into time.Time, and defaults to false
+	ParseTime config.TypedOption[bool]
+	// Location is the time zone MySQL DATETIME values are interpreted in, and defaults to UTC
+	Location config.Option
+	// Charset is the character set of MySQL connections
+	Charset config.Option
+	// Collation is the collation of MySQL connections
+	Collation config.Option
+	// ConnMaxLifetime is how long a database connection may be reused before it's closed, and defaults to 3 minutes
+	ConnMaxLifetime config.TypedOption[time.Duration]
+	// ConnMaxIdleTime is how long a database connection may sit idle in the pool before it's closed. There's no limit by
+	// default.
+	ConnMaxIdleTime config.TypedOption[time.Duration]
+	// MaxConnections is the number of total SQL connections the database pool cannot exceed, and defaults to 20
+	MaxConnections config.TypedOption[int]
+	// MaxIdleConnections is the number of total idle SQL connections the database pool cannot exceed, and defaults to
+	// 5. This number must be less than MaxConnections, which the group's rules enforce.
+	MaxIdleConnections config.TypedOption[int]
+}
+
+// NewDBOptionGroup constructs the database options with the passed prefix added to each variable name. An empty prefix
+// produces the standard DB_* variables.
+func NewDBOptionGroup(prefix string) DBOptionGroup {
+	return DBOptionGroup{
+		Driver: config.NewEnumOption("DB_DRIVER", false, "mysql", "postgres").WithDefault("mysql").
+			WithDescription("The kind of database to connect to").
+			WithPrefix(prefix),
+		User: config.NewOption("DB_USER", true).
+			WithDescription("The username used to authenticate with the database").
+			WithPrefix(prefix),
+		Password: config.NewSecretOption("DB_PASSWORD", true).
+			WithDescription("The password used to authenticate with the database").
+			WithPrefix(prefix),
+		Hostname: config.NewValidatedOption("DB_HOST", true, func(value string) error {
+			return validation.Validate(value, is.Host)
+		}).WithDescription("The hostname of the database to connect to").WithPrefix(prefix),
+		Port: config.NewIntOption("DB_PORT", false).
+			WithDescription("The port of the database to connect to, if not the driver's default").
+			WithPrefix(prefix),
+		Schema: config.NewOption("DB_SCHEMA", true).
+			WithDescription("The schema used by default once connected to the database").
+			WithPrefix(prefix),
+		ReplicaHosts: config.NewStringListOption("DB_REPLICA_HOSTS", false).WithValidation(validateReplicaHosts).
+			WithDescription("Comma-separated list of read replica hostnames, each optionally followed by a port").
+			WithPrefix(prefix),
+		ReadYourWrites: config.NewBoolOption("DB_READ_YOUR_WRITES", false).WithDefault("true").
+			WithDescription("Whether a request reads from the primary database instead of replicas once it has written").
+			WithPrefix(prefix),
+		ConnectMaxWait: config.NewDurationOption("DB_CONNECT_MAX_WAIT", false).WithDefault("5m").
+			WithDescription("How long to keep trying to reach the database at startup before giving up").
+			WithPrefix(prefix),
+		SlowQueryThreshold: config.NewDurationOption("DB_SLOW_QUERY_THRESHOLD", false).WithDefault("500ms").
+			WithDescription("How long a statement may take before it's logged as a slow query, or 0 to disable the log").
+			WithPrefix(prefix),
+		StatementTimeout: config.NewDurationOption("DB_STATEMENT_TIMEOUT", false).WithDefault("30s").
+			WithDescription("How long a statement run for a request may take before it's cancelled, or 0 to disable the timeout").
+			WithPrefix(prefix),
+		TLS: config.NewEnumOption("DB_TLS", false, "false", "true", "skip-verify", "preferred").WithDefault("false").
+			WithDescription("The TLS mode of database connections").
+			WithPrefix(prefix),
+		TLSCAFile: config.NewOption("DB_TLS_CA_FILE", false).
+			WithDescription("The path of a PEM bundle of certificate authorities to verify the database server with").
+			WithPrefix(prefix),
+		DialTimeout: config.NewDurationOption("DB_DIAL_TIMEOUT", false).
+			WithDescription("How long establishing a database connection may take").
+			WithPrefix(prefix),
+		ReadTimeout: config.NewDurationOption("DB_READ_TIMEOUT", false).
+			WithDescription("How long reading a MySQL response may take").
+			WithPrefix(prefix),
+		WriteTimeout: config.NewDurationOption("DB_WRITE_TIMEOUT", false).
+			WithDescription("How long writing a MySQL request may take").
+			WithPrefix(prefix),
+		ParseTime: config.NewBoolOption("DB_PARSE_TIME", false).WithDefault("false").
+			WithDescription("Whether MySQL DATE and DATETIME columns are scanned into time.Time").
+			WithPrefix(prefix),
+		Location: config.NewValidatedOption("DB_LOCATION", false, func(value string) error {
+			_, locationErr := time.LoadLocation(value)
+			return locationErr
+		}).WithDescription("The time zone MySQL DATETIME values are interpreted in").WithPrefix(prefix),
+		Charset: config.NewOption("DB_CHARSET", false).
+			WithDescription("The character set of MySQL connections").
+			WithPrefix(prefix),
+		Collation: config.NewOption("DB_COLLATION", false).
+			WithDescription("The collation of MySQL connections").
+			WithPrefix(prefix),
+		ConnMaxLifetime: config.NewDurationOption("DB_CONN_MAX_LIFETIME", false).WithDefault("3m").
+			WithDescription("How long a database connection may be reused, or 0 for no limit").
+			WithPrefix(prefix),
+		ConnMaxIdleTime: config.NewDurationOption("DB_CONN_MAX_IDLE_TIME", false).
+			WithDescription("How long a database connection may be idle, or 0 for no limit").
+			WithPrefix(prefix),
+		MaxConnections: config.NewIntOption("DB_MAX_CONNECTIONS", false).WithDefault("20").
+			WithDescription("The maximum number of open database connections").
+			WithPrefix(prefix),
+		MaxIdleConnections: config.NewIntOption("DB_MAX_IDLE_CONNECTIONS", false).WithDefault("5").
+			WithDescription("The maximum number of idle database connections, which must be less than the maximum number of open connections").
+			WithPrefix(prefix),
+	}
+}
+
+// Options lists every option in the group, to be registered with a config.RegistryBuilder
+func (group DBOptionGroup) Options() []config.AnyOption {
+	return []config.AnyOption{group.Driver, group.User, group.Password, group.Hostname, group.Port, group.Schema, group.ReplicaHosts,
+		group.ReadYourWrites, group.ConnectMaxWait, group.SlowQueryThreshold, group.StatementTimeout, group.TLS, group.TLSCAFile, group.DialTimeout,
+		group.ReadTimeout, group.WriteTimeout, group.ParseTime, group.Location, group.Charset, group.Collation,
+		group.ConnMaxLifetime, group.ConnMaxIdleTime, group.MaxConnections, group.MaxIdleConnections}
+}
+
+// validateReplicaHosts requires every item of a comma-separated list to be a hostname, optionally followed by a port
+func validateReplicaHosts(value string) error {
+	for _, replicaHost := range strings.Split(value, ",") {
+		hostname := strings.TrimSpace(replicaHost)
+		if hostname == "" {
+			continue
+		}
+		if splitHost, port, splitErr := net.SplitHostPort(hostname); splitErr == nil {
+			if portErr := validation.Validate(port, is.Port); portErr != nil {
+				return fmt.Errorf("%v: %w", replicaHost, portErr)
+			}
+			hostname = splitHost
+		}
+		if hostErr := validation.Validate(hostname, is.Host); hostErr != nil {
+			return fmt.Errorf("%v: %w", replicaHost, hostErr)
+		}
+	}
+	return nil
+}
+
+// ConnectionLimitsRule requires the group's MaxIdleConnections to be less than its MaxConnections
+func (group DBOptionGroup) ConnectionLimitsRule() config.Rule {
+	return config.NewRule("idle connections must be fewer than maximum connections", func(registry config.Registry) error {
+		maxConnections := config.GetRequiredTyped(registry, group.MaxConnections)
+		maxIdleConnections := config.GetRequiredTyped(registry, group.MaxIdleConnections)
+		if maxIdleConnections >= maxConnections {
+			return fmt.Errorf("%v is %v but %v is only %v", group.MaxIdleConnections.VariableName(), maxIdleConnections,
+				group.MaxConnections.VariableName(), maxConnections)
+		}
+		return nil
+	}, group.MaxConnections, group.MaxIdleConnections)
+}
+
+// TLSCAFileRule requires the group's TLS mode to be true when TLSCAFile is set, since other modes don't verify the
+// server's certificate
+func (group DBOptionGroup) TLSCAFileRule() config.Rule {
+	return config.NewRule("a TLS CA file requires TLS to verify certificates", func(registry config.Registry) error {
+		if _, isPresent := registry.Get(group.TLSCAFile); !isPresent {
+			return nil
+		}
+		if tlsMode := config.GetRequiredTyped(registry, group.TLS); tlsMode != "true" {
+			return fmt.Errorf("%v is set but %v is %v instead of true", group.TLSCAFile.VariableName(), group.TLS.VariableName(), tlsMode)
+		}
+		return nil
+	}, group.TLS, group.TLSCAFile)
+}
+
+// Rules lists every rule relating the options in the group, to be registered alongside the options
+func (group DBOptionGroup) Rules() []config.Rule {
+	return []config.Rule{group.ConnectionLimitsRule(), group.TLSCAFileRule()}
+}
+
+// DefaultDB is the option group for the microservice's main database, configured by the unprefixed DB_* variables
+var DefaultDB = NewDBOptionGroup("")
+
+// DBDriver is the kind of database to connect to, either mysql or postgres, and defaults to mysql
+var DBDriver = DefaultDB.Driver
+
+// DBUser is the username used to authenticate with the database
+var DBUser = DefaultDB.User
+
+// DBPassword is the password used to authenticate with the database. It can also be read from a mounted secret file
+// named by DB_PASSWORD_FILE.
+var DBPassword = DefaultDB.Password
+
+// DBHostname is the hostname of the database to connect to
+var DBHostname = DefaultDB.Hostname
+
+// DBPort is the database port the application should connect to
+var DBPort = DefaultDB.Port
+
+// DBSchema is the schema to use by default once connected to the database
+var DBSchema = DefaultDB.Schema
+
+// DBReplicaHosts lists the hostnames of read replicas of the database, each optionally followed by a port
+var DBReplicaHosts = DefaultDB.ReplicaHosts
+
+// DBReadYourWrites sends a request's reads to the primary database once it has written, and defaults to true
+var DBReadYourWrites = DefaultDB.ReadYourWrites
+
+// DBConnectMaxWait is how long to keep trying to reach the database at startup before giving up, and defaults to 5
+// minutes
+var DBConnectMaxWait = DefaultDB.ConnectMaxWait
+
+// DBSlowQueryThreshold is how long a statement may take before it's logged as a slow query, and defaults to 500ms
+var DBSlowQueryThreshold = DefaultDB.SlowQueryThreshold
+
+// DBStatementTimeout is how long a statement run for a request may take before it's cancelled, and defaults to 30
+// seconds
+var DBStatementTimeout = DefaultDB.StatementTimeout
+
+// DBTLS is the TLS mode of database connections, one of false, true, skip-verify or preferred, and defaults to false
+var DBTLS = DefaultDB.TLS
+
+// DBTLSCAFile is the path of a PEM bundle of certificate authorities to verify the database server's certificate with
+var DBTLSCAFile = DefaultDB.TLSCAFile
+
+// DBDialTimeout limits how long establishing a database connection may take
+var DBDialTimeout = DefaultDB.DialTimeout
+
+// DBReadTimeout limits how long reading a response from a MySQL connection may take
+var DBReadTimeout = DefaultDB.ReadTimeout
+
+// DBWriteTimeout limits how long writing a request to a MySQL connection may take
+var DBWriteTimeout = DefaultDB.WriteTimeout
+
+// DBParseTime scans MySQL DATE and DATETIME columns into time.Time, and defaults to false
+var DBParseTime = DefaultDB.ParseTime
+
+// DBLocation is the time zone MySQL DATETIME values are interpreted in, and defaults to UTC
+var DBLocation = DefaultDB.Location
+
+// DBCharset is the character set of MySQL connections
+var DBCharset = DefaultDB.Charset
+
+// DBCollation is the collation of MySQL connections
+var DBCollation = DefaultDB.Collation
+
+// DBConnMaxLifetime is how long a database connection may be reused before it's closed, and defaults to 3 minutes
+var DBConnMaxLifetime = DefaultDB.ConnMaxLifetime
+
+// DBConnMaxIdleTime is how long a database connection may sit idle in the pool before it's closed
+var DBConnMaxIdleTime = DefaultDB.ConnMaxIdleTime
+
+// DBMaxConnections is the number of total SQL connections the database pool cannot exceed, and defaults to 20
+var DBMaxConnections = DefaultDB.MaxConnections
+
+// DBMaxIdleConnections is the number of total idle SQL connections the database pool cannot exceed, and defaults to 5.
+// This number must be less than DBMaxConnections, which DBConnectionLimitsRule enforces.
+var DBMaxIdleConnections = DefaultDB.MaxIdleConnections
+
+// DBOptions is a bundle of all available database configuration options
+var DBOptions = DefaultDB.Options()
+
+// DBConnectionLimitsRule requires DBMaxIdleConnections to be less than DBMaxConnections
+var DBConnectionLimitsRule = DefaultDB.ConnectionLimitsRule()
+
+// DBTLSCAFileRule requires DBTLS to be true when DBTLSCAFile is set
+var DBTLSCAFileRule = DefaultDB.TLSCAFileRule()
+
+// DBRules is a bundle of all rules relating database configuration options. Register it alongside DBOptions.
+var DBRules = DefaultDB.Rules()
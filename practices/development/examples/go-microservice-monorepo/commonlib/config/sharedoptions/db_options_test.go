@@ -0,0 +1,172 @@
+package sharedoptions
+
+import (
+	"testing"
+
+	"example.com/sample/commonlib/config"
+	"github.com/stretchr/testify/suite"
+)
+
+type DBOptionsSuite struct {
+	suite.Suite
+}
+
+func TestDBOptionsSuite(t *testing.T) {
+	suite.Run(t, new(DBOptionsSuite))
+}
+
+func (suite *DBOptionsSuite) TestPrefixedGroup() {
+	reportingDB := NewDBOptionGroup("REPORTING")
+	builder := config.NewMockRegistryBuilder(map[string]string{
+		"DB_USER":                           "root",
+		"DB_PASSWORD":                       "mypass",
+		"DB_HOST":                           "localhost",
+		"DB_SCHEMA":                         "main",
+		"REPORTING_DB_USER":                 "reporter",
+		"REPORTING_DB_PASSWORD":             "otherpass",
+		"REPORTING_DB_HOST":                 "reporting.example.com",
+		"REPORTING_DB_SCHEMA":               "reports",
+		"REPORTING_DB_MAX_IDLE_CONNECTIONS": "30",
+	})
+	builder.AddOptions(DBOptions)
+	builder.AddRules(DBRules)
+	builder.AddOptions(reportingDB.Options())
+	builder.AddRules(reportingDB.Rules())
+	_, buildErr := builder.VerifyAndBuild()
+
+	// Only the reporting database breaks the connection limits rule
+	var validationError config.ErrIncorrectConfiguration
+	suite.Require().ErrorAs(buildErr, &validationError)
+	suite.Require().Len(validationError.FailedRules, 1)
+	suite.Assert().Equal([]string{"REPORTING_DB_MAX_CONNECTIONS", "REPORTING_DB_MAX_IDLE_CONNECTIONS"},
+		validationError.FailedRules[0].Variables)
+	suite.Assert().Equal("REPORTING_DB_PASSWORD", reportingDB.Password.VariableName())
+}
+
+func (suite *DBOptionsSuite) TestDBConnectionLimitsRule() {
+	subtests := []validationSubtestParams{
+		{
+			testName:             "Accepts the defaults",
+			registryValue:        "",
+			shouldPassValidation: true,
+		},
+		{
+			testName:             "Accepts fewer idle connections",
+			registryValue:        "19",
+			shouldPassValidation: true,
+		},
+		{
+			testName:             "Rejects as many idle connections",
+			registryValue:        "20",
+			shouldPassValidation: false,
+		},
+	}
+
+	for _, subtest := range subtests {
+		suite.Run(subtest.testName, func() {
+			environment := map[string]string{}
+			if subtest.registryValue != "" {
+				environment[DBMaxIdleConnections.VariableName()] = subtest.registryValue
+			}
+			builder := config.NewMockRegistryBuilder(environment)
+			builder.AddOptions([]config.AnyOption{DBMaxConnections, DBMaxIdleConnections, DBTLS, DBTLSCAFile})
+			builder.AddRules(DBRules)
+			_, buildErr := builder.VerifyAndBuild()
+
+			if subtest.shouldPassValidation {
+				suite.Require().NoError(buildErr)
+			} else {
+				var validationError config.ErrIncorrectConfiguration
+				suite.Require().ErrorAs(buildErr, &validationError)
+				suite.Require().Len(validationError.FailedRules, 1)
+			}
+		})
+	}
+}
+
+func (suite *DBOptionsSuite) TestDBTLSCAFileRule() {
+	subtests := []struct {
+		testName             string
+		environment          map[string]string
+		shouldPassValidation bool
+	}{
+		{
+			testName:             "Accepts the defaults",
+			environment:          map[string]string{},
+			shouldPassValidation: true,
+		},
+		{
+			testName:             "Accepts a CA file when verifying certificates",
+			environment:          map[string]string{"DB_TLS": "true", "DB_TLS_CA_FILE": "/etc/ssl/rds-ca.pem"},
+			shouldPassValidation: true,
+		},
+		{
+			testName:             "Rejects a CA file without TLS",
+			environment:          map[string]string{"DB_TLS_CA_FILE": "/etc/ssl/rds-ca.pem"},
+			shouldPassValidation: false,
+		},
+		{
+			testName:             "Rejects a CA file when skipping verification",
+			environment:          map[string]string{"DB_TLS": "skip-verify", "DB_TLS_CA_FILE": "/etc/ssl/rds-ca.pem"},
+			shouldPassValidation: false,
+		},
+	}
+
+	for _, subtest := range subtests {
+		suite.Run(subtest.testName, func() {
+			builder := config.NewMockRegistryBuilder(subtest.environment)
+			builder.AddOptions([]config.AnyOption{DBTLS, DBTLSCAFile})
+			builder.AddRules([]config.Rule{DBTLSCAFileRule})
+			_, buildErr := builder.VerifyAndBuild()
+
+			if subtest.shouldPassValidation {
+				suite.Require().NoError(buildErr)
+			} else {
+				var validationError config.ErrIncorrectConfiguration
+				suite.Require().ErrorAs(buildErr, &validationError)
+				suite.Require().Len(validationError.FailedRules, 1)
+			}
+		})
+	}
+}
+
+func (suite *DBOptionsSuite) TestDBReplicaHostsValidation() {
+	subtests := []validationSubtestParams{
+		{
+			testName:             "Accepts hostnames",
+			registryValue:        "replica1.example.com, replica2.example.com",
+			shouldPassValidation: true,
+		},
+		{
+			testName:             "Accepts hostnames with ports",
+			registryValue:        "replica1.example.com:3307,10.0.0.2:3308",
+			shouldPassValidation: true,
+		},
+		{
+			testName:             "Rejects bad ports",
+			registryValue:        "replica1.example.com:99999",
+			shouldPassValidation: false,
+		},
+		{
+			testName:             "Rejects bad hostnames",
+			registryValue:        "replica1.example.com,not a host",
+			shouldPassValidation: false,
+		},
+	}
+
+	for _, subtest := range subtests {
+		suite.Run(subtest.testName, func() {
+			builder := config.NewMockRegistryBuilder(map[string]string{DBReplicaHosts.VariableName(): subtest.registryValue})
+			builder.AddOptions([]config.AnyOption{DBReplicaHosts})
+			_, buildErr := builder.VerifyAndBuild()
+
+			if subtest.shouldPassValidation {
+				suite.Require().NoError(buildErr)
+			} else {
+				var validationError config.ErrIncorrectConfiguration
+				suite.Require().ErrorAs(buildErr, &validationError)
+				suite.Require().Len(validationError.InvalidVariables, 1)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package sharedoptions
+
+import "example.com/sample/commonlib/config"
+
+// DBMigrateOnStartup determines whether the application applies pending database migrations when it starts, using the
+// migrate package. It defaults to true in the local profile, so a local run always has an up-to-date schema, and false
+// otherwise, where migrations are usually applied as a separate deployment step.
+var DBMigrateOnStartup = config.NewBoolOption("DB_MIGRATE_ON_STARTUP", false).WithDefault("false").
+	WithProfileDefault(config.ProfileLocal, "true").
+	WithDescription("Whether pending database migrations are applied when the application starts")
+
+// SchemaCheckMode determines what happens when the database schema is behind the migrations shipped with the
+// application
+type SchemaCheckMode string
+
+const (
+	// SchemaCheckOff skips the check
+	SchemaCheckOff SchemaCheckMode = "off"
+	// SchemaCheckWarn logs a warning and reports the mismatch from the readiness check without failing it
+	SchemaCheckWarn SchemaCheckMode = "warn"
+	// SchemaCheckEnforce refuses to start, and fails the readiness check if the schema falls behind later on
+	SchemaCheckEnforce SchemaCheckMode = "enforce"
+)
+
+// DBSchemaCheck determines whether the application checks at startup that every migration shipped with it has been
+// applied to the database, and what happens if one hasn't. It's one of off, warn, or enforce, and defaults to warn.
+var DBSchemaCheck = config.NewEnumOption("DB_SCHEMA_CHECK", false, SchemaCheckOff, SchemaCheckWarn, SchemaCheckEnforce).
+	WithDefault(string(SchemaCheckWarn)).
+	WithDescription("What happens when migrations shipped with the application haven't been applied to the database")
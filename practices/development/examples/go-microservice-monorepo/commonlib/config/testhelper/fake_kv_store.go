@@ -0,0 +1,113 @@
+package testhelper
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FakeKVStore is an in-process stand-in for a key/value store with a Consul-style API, for testing config.KVSource
+// without a real store. It serves GET /v1/kv/<prefix>?recurse from an in-memory map.
+type FakeKVStore struct {
+	server *httptest.Server
+
+	// lock guards every field below it
+	lock        sync.Mutex
+	values      map[string]string
+	unavailable bool
+	requests    int
+}
+
+// kvEntry is a single key in the fake store's response
+type kvEntry struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+// NewFakeKVStore starts a fake key/value store. Close it when the test is done.
+func NewFakeKVStore() *FakeKVStore {
+	store := &FakeKVStore{values: make(map[string]string)}
+	store.server = httptest.NewServer(http.HandlerFunc(store.serve))
+	return store
+}
+
+// URL returns the base URL of the fake store, to be used as the address of a config.KVSource
+func (store *FakeKVStore) URL() string {
+	return store.server.URL
+}
+
+// Close shuts down the fake store
+func (store *FakeKVStore) Close() {
+	store.server.Close()
+}
+
+// Set stores a value under a key, such as config/shared/ALLOWED_CORS_ORIGINS
+func (store *FakeKVStore) Set(key string, value string) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.values[key] = value
+}
+
+// Delete removes a key from the store
+func (store *FakeKVStore) Delete(key string) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	delete(store.values, key)
+}
+
+// SetUnavailable makes the store respond to every request with 503 Service Unavailable while true, simulating an outage
+func (store *FakeKVStore) SetUnavailable(unavailable bool) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.unavailable = unavailable
+}
+
+// Requests returns the number of requests the store has received
+func (store *FakeKVStore) Requests() int {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	return store.requests
+}
+
+// serve responds to a request for the keys below a prefix
+func (store *FakeKVStore) serve(writer http.ResponseWriter, request *http.Request) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.requests++
+	if store.unavailable {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	prefix, isKVRequest := strings.CutPrefix(request.URL.Path, "/v1/kv/")
+	if request.Method != http.MethodGet || !isKVRequest {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var entries []kvEntry
+	for key, value := range store.values {
+		if strings.HasPrefix(key, prefix) {
+			entries = append(entries, kvEntry{Key: key, Value: base64.StdEncoding.EncodeToString([]byte(value))})
+		}
+	}
+	if len(entries) == 0 {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	writer.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(writer).Encode(entries)
+}
@@ -0,0 +1,138 @@
+package testhelper
+
+import (
+	"maps"
+	"testing"
+
+	"example.com/sample/commonlib/config"
+)
+
+// BuilderFunc constructs a RegistryBuilder reading from the passed sources, with a set of options and rules registered.
+// A microservice's options.NewRegistryBuilder is a BuilderFunc, so tests can use the service's real option set.
+type BuilderFunc func(sources ...config.Source) config.RegistryBuilder
+
+// BuilderOf returns a BuilderFunc registering only the passed options and rules, for tests of shared options and
+// components which don't belong to a particular microservice
+func BuilderOf(options []config.AnyOption, rules ...config.Rule) BuilderFunc {
+	return func(sources ...config.Source) config.RegistryBuilder {
+		regBuilder := config.NewLayeredRegistryBuilder(sources...)
+		regBuilder.AddOptions(options)
+		regBuilder.AddRules(rules)
+		return regBuilder
+	}
+}
+
+// overrideSource is a Source supplying the values set on a RegistryFixture
+type overrideSource map[string]string
+
+// Name implements config.Source for overrideSource
+func (src overrideSource) Name() string {
+	return "test"
+}
+
+// Load implements config.Source for overrideSource
+func (src overrideSource) Load() (map[string]string, error) {
+	return maps.Clone(src), nil
+}
+
+// RegistryFixture builds configuration registries for tests from a fixed option set and a set of variable values, which
+// tests can change for their own duration. Unlike config.NewMockRegistryBuilder, the option set only has to be
+// described once, and setting a variable no registered option reads fails the test immediately rather than being
+// silently ignored.
+type RegistryFixture struct {
+	newBuilder BuilderFunc
+	values     map[string]string
+}
+
+// NewRegistryFixture constructs a RegistryFixture from a BuilderFunc and the variable values every registry it builds
+// starts from. The test fails if any of the values don't belong to a registered option.
+func NewRegistryFixture(t testing.TB, newBuilder BuilderFunc, baseValues map[string]string) *RegistryFixture {
+	t.Helper()
+
+	fixture := &RegistryFixture{
+		newBuilder: newBuilder,
+		values:     make(map[string]string),
+	}
+	for name, value := range baseValues {
+		fixture.mustBeRecognized(t, name)
+		fixture.values[name] = value
+	}
+
+	return fixture
+}
+
+// Builder returns a RegistryBuilder with the fixture's option set, reading the fixture's current values
+func (fixture *RegistryFixture) Builder() config.RegistryBuilder {
+	return fixture.newBuilder(overrideSource(maps.Clone(fixture.values)))
+}
+
+// Registry builds a registry from the fixture's current values. The test fails if the configuration is invalid.
+func (fixture *RegistryFixture) Registry(t testing.TB) config.Registry {
+	t.Helper()
+
+	regBuilder := fixture.Builder()
+	registry, buildErr := regBuilder.VerifyAndBuild()
+	if buildErr != nil {
+		t.Fatalf("Could not build the test configuration registry: %v", buildErr)
+	}
+
+	return registry
+}
+
+// Override sets variable values until the passed test, or subtest, finishes, at which point the previous values are
+// restored. Passing an empty string sets the variable to an empty value; use Unset to remove a variable. The test fails
+// if any of the variables don't belong to a registered option.
+func (fixture *RegistryFixture) Override(t testing.TB, overrides map[string]string) {
+	t.Helper()
+
+	for name, value := range overrides {
+		fixture.mustBeRecognized(t, name)
+		fixture.restoreOnCleanup(t, name)
+		fixture.values[name] = value
+	}
+}
+
+// Unset removes variables until the passed test, or subtest, finishes, at which point the previous values are
+// restored. The test fails if any of the variables don't belong to a registered option.
+func (fixture *RegistryFixture) Unset(t testing.TB, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		fixture.mustBeRecognized(t, name)
+		fixture.restoreOnCleanup(t, name)
+		delete(fixture.values, name)
+	}
+}
+
+// restoreOnCleanup restores the current state of a variable once the passed test finishes
+func (fixture *RegistryFixture) restoreOnCleanup(t testing.TB, name string) {
+	previousValue, previouslySet := fixture.values[name]
+	t.Cleanup(func() {
+		if previouslySet {
+			fixture.values[name] = previousValue
+		} else {
+			delete(fixture.values, name)
+		}
+	})
+}
+
+// mustBeRecognized fails the test if a variable doesn't belong to an option registered by the fixture's BuilderFunc
+func (fixture *RegistryFixture) mustBeRecognized(t testing.TB, name string) {
+	t.Helper()
+
+	regBuilder := fixture.newBuilder()
+	if !regBuilder.Recognizes(name) {
+		t.Fatalf("%v is not a registered configuration variable, so setting it in a test has no effect", name)
+	}
+}
+
+// SwapRegistry points a global registry, such as options.Registry, at the passed registry until the passed test, or
+// subtest, finishes, at which point the previous registry is restored. Tests using it must not run in parallel with
+// other tests reading the same global.
+func SwapRegistry(t testing.TB, global **config.Registry, registry config.Registry) {
+	previousRegistry := *global
+	*global = &registry
+	t.Cleanup(func() {
+		*global = previousRegistry
+	})
+}
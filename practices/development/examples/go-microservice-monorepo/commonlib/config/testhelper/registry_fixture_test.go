@@ -0,0 +1,105 @@
+package testhelper
+
+import (
+	"fmt"
+	"testing"
+
+	"example.com/sample/commonlib/config"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	testModeOption    = config.NewOption("MODE", true)
+	testTimeoutOption = config.NewIntOption("TIMEOUT", false).WithDefault("30")
+)
+
+type RegistryFixtureSuite struct {
+	suite.Suite
+	fixture *RegistryFixture
+}
+
+func TestRegistryFixtureSuite(t *testing.T) {
+	suite.Run(t, new(RegistryFixtureSuite))
+}
+
+// fatalRecorder is a testing.TB which records failures instead of stopping the test
+type fatalRecorder struct {
+	testing.TB
+	failures []string
+}
+
+func (recorder *fatalRecorder) Helper() {}
+
+func (recorder *fatalRecorder) Fatalf(format string, args ...any) {
+	recorder.failures = append(recorder.failures, fmt.Sprintf(format, args...))
+}
+
+func (suite *RegistryFixtureSuite) SetupTest() {
+	suite.fixture = NewRegistryFixture(suite.T(), BuilderOf([]config.AnyOption{testModeOption, testTimeoutOption}),
+		map[string]string{"MODE": "fast"})
+}
+
+func (suite *RegistryFixtureSuite) TestRegistryUsesBaseValues() {
+	registry := suite.fixture.Registry(suite.T())
+
+	suite.Assert().Equal("fast", registry.GetRequired(testModeOption))
+	suite.Assert().Equal(30, config.GetRequiredTyped(registry, testTimeoutOption))
+}
+
+func (suite *RegistryFixtureSuite) TestOverridesAreScoped() {
+	suite.Run("Overridden", func() {
+		suite.fixture.Override(suite.T(), map[string]string{"MODE": "safe", "TIMEOUT": "5"})
+		registry := suite.fixture.Registry(suite.T())
+
+		suite.Assert().Equal("safe", registry.GetRequired(testModeOption))
+		suite.Assert().Equal(5, config.GetRequiredTyped(registry, testTimeoutOption))
+	})
+
+	registry := suite.fixture.Registry(suite.T())
+	suite.Assert().Equal("fast", registry.GetRequired(testModeOption))
+	suite.Assert().True(registry.IsDefault(testTimeoutOption))
+}
+
+func (suite *RegistryFixtureSuite) TestUnsetIsScoped() {
+	suite.Run("Unset", func() {
+		suite.fixture.Unset(suite.T(), "MODE")
+		regBuilder := suite.fixture.Builder()
+		_, buildErr := regBuilder.VerifyAndBuild()
+
+		var configErr config.ErrIncorrectConfiguration
+		suite.Require().ErrorAs(buildErr, &configErr)
+		suite.Assert().Equal([]string{"MODE"}, configErr.MissingRequiredVariables)
+	})
+
+	suite.Assert().Equal("fast", suite.fixture.Registry(suite.T()).GetRequired(testModeOption))
+}
+
+func (suite *RegistryFixtureSuite) TestUnregisteredVariablesFail() {
+	suite.Run("Base values", func() {
+		recorder := &fatalRecorder{TB: suite.T()}
+		NewRegistryFixture(recorder, BuilderOf([]config.AnyOption{testModeOption}), map[string]string{"MOED": "fast"})
+
+		suite.Assert().Len(recorder.failures, 1)
+	})
+
+	suite.Run("Overrides", func() {
+		recorder := &fatalRecorder{TB: suite.T()}
+		suite.fixture.Override(recorder, map[string]string{"TIMEOUT_SECONDS": "5"})
+
+		suite.Assert().Len(recorder.failures, 1)
+	})
+}
+
+func (suite *RegistryFixtureSuite) TestSwapRegistry() {
+	originalRegistry := suite.fixture.Registry(suite.T())
+	global := &originalRegistry
+
+	suite.Run("Swapped", func() {
+		suite.fixture.Override(suite.T(), map[string]string{"MODE": "safe"})
+		SwapRegistry(suite.T(), &global, suite.fixture.Registry(suite.T()))
+
+		suite.Assert().Equal("safe", global.GetRequired(testModeOption))
+	})
+
+	suite.Assert().Same(&originalRegistry, global)
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TypedOption is an Option whose value is parsed into a T once while the Registry is verified and built. Parse
+// failures are reported through ErrIncorrectConfiguration like any other validation failure, and the parsed value can
+// be retrieved with GetTyped or GetRequiredTyped. The raw string is still available through Registry.Get.
+type TypedOption[T any] struct {
+	Option
+}
+
+// NewTypedOption constructs a TypedOption, accepting the name of the represented environment variable, whether it's
+// required, and a function which parses the raw value into a T. Any validation function set on the option runs
+// against the raw value before it is parsed.
+func NewTypedOption[T any](envName string, required bool, parseFn func(value string) (T, error)) TypedOption[T] {
+	option := NewOption(envName, required)
+	option.parseFn = func(value string) (any, error) {
+		return parseFn(value)
+	}
+	var zeroValue T
+	option.typeName = fmt.Sprintf("%T", zeroValue)
+
+	return TypedOption[T]{Option: option}
+}
+
+// withTypeName returns a copy of the option with a friendlier type name for the registry schema
+func (opt TypedOption[T]) withTypeName(typeName string) TypedOption[T] {
+	opt.typeName = typeName
+	return opt
+}
+
+// WithValidation returns a copy of the option with the passed validation function, which runs against the raw value
+// before it is parsed.
+func (opt TypedOption[T]) WithValidation(validationFn func(value string) error) TypedOption[T] {
+	opt.SetValidation(validationFn)
+	return opt
+}
+
+// WithDefault returns a copy of the option with the passed raw default value, which is parsed just like a value from
+// the environment would be.
+func (opt TypedOption[T]) WithDefault(value string) TypedOption[T] {
+	opt.SetDefault(value)
+	return opt
+}
+
+// WithDescription returns a copy of the option with the passed description, which appears in the registry schema and
+// in documentation generated from it.
+func (opt TypedOption[T]) WithDescription(description string) TypedOption[T] {
+	opt.SetDescription(description)
+	return opt
+}
+
+// WithPrefix returns a copy of the option whose variable name starts with the passed prefix. See Option.WithPrefix for
+// more information.
+func (opt TypedOption[T]) WithPrefix(prefix string) TypedOption[T] {
+	opt.Option = opt.Option.WithPrefix(prefix)
+	return opt
+}
+
+// NewIntOption constructs a TypedOption holding a whole number
+func NewIntOption(envName string, required bool) TypedOption[int] {
+	return NewTypedOption(envName, required, func(value string) (int, error) {
+		intValue, parseErr := strconv.Atoi(strings.TrimSpace(value))
+		if parseErr != nil {
+			return 0, errors.New("value must be a whole number")
+		}
+		return intValue, nil
+	}).withTypeName("integer")
+}
+
+// NewBoolOption constructs a TypedOption holding a boolean. Accepted values are the ones understood by
+// strconv.ParseBool, such as "true" and "false".
+func NewBoolOption(envName string, required bool) TypedOption[bool] {
+	return NewTypedOption(envName, required, func(value string) (bool, error) {
+		boolValue, parseErr := strconv.ParseBool(strings.TrimSpace(value))
+		if parseErr != nil {
+			return false, errors.New("value must be 'true' or 'false'")
+		}
+		return boolValue, nil
+	}).withTypeName("boolean")
+}
+
+// NewDurationOption constructs a TypedOption holding a time.Duration written in the format understood by
+// time.ParseDuration, such as "30s" or "1h15m".
+func NewDurationOption(envName string, required bool) TypedOption[time.Duration] {
+	return NewTypedOption(envName, required, func(value string) (time.Duration, error) {
+		duration, parseErr := time.ParseDuration(strings.TrimSpace(value))
+		if parseErr != nil {
+			return 0, errors.New("value must be a duration such as 30s or 5m")
+		}
+		return duration, nil
+	}).withTypeName("duration")
+}
+
+// NewURLOption constructs a TypedOption holding an absolute URL
+func NewURLOption(envName string, required bool) TypedOption[*url.URL] {
+	return NewTypedOption(envName, required, func(value string) (*url.URL, error) {
+		parsedURL, parseErr := url.Parse(strings.TrimSpace(value))
+		if parseErr != nil || !parsedURL.IsAbs() {
+			return nil, errors.New("value must be an absolute URL")
+		}
+		return parsedURL, nil
+	}).withTypeName("URL")
+}
+
+// NewStringListOption constructs a TypedOption holding a comma-separated list of strings. Whitespace around each
+// item is trimmed and empty items are dropped.
+func NewStringListOption(envName string, required bool) TypedOption[[]string] {
+	return NewTypedOption(envName, required, parseStringList).withTypeName("list")
+}
+
+// parseStringList splits a comma-separated list, trimming whitespace around each item and dropping empty items
+func parseStringList(value string) ([]string, error) {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if trimmedItem := strings.TrimSpace(item); trimmedItem != "" {
+			items = append(items, trimmedItem)
+		}
+	}
+	return items, nil
+}
+
+// NewEnumOption constructs a TypedOption whose value must be one of the allowed values. The option's type can be any
+// string type, so callers can define their own enum types.
+func NewEnumOption[Enum ~string](envName string, required bool, allowedValues ...Enum) TypedOption[Enum] {
+	allowedList := make([]string, 0, len(allowedValues))
+	for _, allowedValue := range allowedValues {
+		allowedList = append(allowedList, string(allowedValue))
+	}
+
+	option := NewTypedOption(envName, required, func(value string) (Enum, error) {
+		for _, allowedValue := range allowedValues {
+			if string(allowedValue) == value {
+				return allowedValue, nil
+			}
+		}
+
+		return "", fmt.Errorf("value must be one of %v", strings.Join(allowedList, ", "))
+	}).withTypeName("enum")
+	option.allowedValues = allowedList
+	return option
+}
+
+// GetTyped retrieves the parsed value of the specified TypedOption. The first return value contains the option's value
+// if it's present, and the second value is true if the value was actually present or false otherwise. This function
+// will panic if an unregistered option is passed.
+func GetTyped[T any](registry Registry, option TypedOption[T]) (T, bool) {
+	registry.mustBeRegistered(option)
+
+	resolved, isPresent := registry.values[option.envName]
+	if !isPresent {
+		var zeroValue T
+		return zeroValue, false
+	}
+
+	return resolved.parsed.(T), true
+}
+
+// GetRequiredTyped retrieves the parsed value of the specified TypedOption, which must either be required, have a
+// default value, or have a profile default for every profile while a profile option is registered. Since the option is
+// guaranteed to have a value, the "presence" boolean is not returned like it is in GetTyped. This function will panic
+// if it is passed an optional option without a default or an unregistered option.
+func GetRequiredTyped[T any](registry Registry, option TypedOption[T]) T {
+	if !registry.alwaysPresent(option.Option) {
+		panic(fmt.Sprintf("Non-required option %v without a default was passed to GetRequiredTyped", option.envName))
+	}
+	// We don't need to check presence since the construction of the registry verified the option is present
+	value, _ := GetTyped(registry, option)
+	return value
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jellydator/validation"
+	"github.com/stretchr/testify/suite"
+)
+
+type TypedOptionSuite struct {
+	suite.Suite
+}
+
+func TestTypedOptionSuite(t *testing.T) {
+	suite.Run(t, new(TypedOptionSuite))
+}
+
+type flavor string
+
+func (suite *TypedOptionSuite) TestValuesAreParsed() {
+	intOption := NewIntOption("AN_INT", true)
+	boolOption := NewBoolOption("A_BOOL", true)
+	durationOption := NewDurationOption("A_DURATION", true)
+	urlOption := NewURLOption("A_URL", true)
+	listOption := NewStringListOption("A_LIST", true)
+	enumOption := NewEnumOption("AN_ENUM", true, flavor("chocolate"), flavor("vanilla"))
+
+	builder := NewMockRegistryBuilder(map[string]string{
+		intOption.VariableName():      "42",
+		boolOption.VariableName():     "true",
+		durationOption.VariableName(): "1m30s",
+		urlOption.VariableName():      "https://example.com/path",
+		listOption.VariableName():     "a, b,,c",
+		enumOption.VariableName():     "vanilla",
+	})
+	builder.AddOptions([]AnyOption{intOption, boolOption, durationOption, urlOption, listOption, enumOption})
+	registry, buildErr := builder.VerifyAndBuild()
+	suite.Require().NoError(buildErr)
+
+	suite.Assert().Equal(42, GetRequiredTyped(registry, intOption))
+	suite.Assert().True(GetRequiredTyped(registry, boolOption))
+	suite.Assert().Equal(90*time.Second, GetRequiredTyped(registry, durationOption))
+	suite.Assert().Equal("example.com", GetRequiredTyped(registry, urlOption).Host)
+	suite.Assert().Equal([]string{"a", "b", "c"}, GetRequiredTyped(registry, listOption))
+	suite.Assert().Equal(flavor("vanilla"), GetRequiredTyped(registry, enumOption))
+
+	// The raw value is still available
+	rawValue, _ := registry.Get(intOption)
+	suite.Assert().Equal("42", rawValue)
+}
+
+func (suite *TypedOptionSuite) TestParseFailuresAreInvalidVariables() {
+	intOption := NewIntOption("AN_INT", false)
+	urlOption := NewURLOption("A_URL", false)
+	enumOption := NewEnumOption("AN_ENUM", false, flavor("chocolate"))
+
+	builder := NewMockRegistryBuilder(map[string]string{
+		intOption.VariableName():  "forty-two",
+		urlOption.VariableName():  "not a url",
+		enumOption.VariableName(): "mayonnaise",
+	})
+	builder.AddOptions([]AnyOption{intOption, urlOption, enumOption})
+	_, buildErr := builder.VerifyAndBuild()
+
+	var configErr ErrIncorrectConfiguration
+	suite.Require().ErrorAs(buildErr, &configErr)
+	suite.Require().Len(configErr.InvalidVariables, 3)
+}
+
+func (suite *TypedOptionSuite) TestValidationRunsBeforeParsing() {
+	option := NewIntOption("SMALL_NUMBER", true).WithValidation(func(value string) error {
+		return validation.Validate(value, validation.Length(1, 1))
+	})
+
+	builder := NewMockRegistryBuilder(map[string]string{option.VariableName(): "12"})
+	builder.AddOption(option)
+	_, buildErr := builder.VerifyAndBuild()
+
+	var configErr ErrIncorrectConfiguration
+	suite.Require().ErrorAs(buildErr, &configErr)
+	suite.Require().Len(configErr.InvalidVariables, 1)
+}
+
+func (suite *TypedOptionSuite) TestAbsentOptionalValue() {
+	option := NewIntOption("NOT_HERE", false)
+	builder := NewMockRegistryBuilder(nil)
+	builder.AddOption(option)
+	registry, buildErr := builder.VerifyAndBuild()
+	suite.Require().NoError(buildErr)
+
+	value, isPresent := GetTyped(registry, option)
+	suite.Assert().False(isPresent)
+	suite.Assert().Zero(value)
+	suite.Assert().Panics(func() {
+		_ = GetRequiredTyped(registry, option)
+	})
+}
+
+func (suite *TypedOptionSuite) TestTypedDefaultsAreParsed() {
+	option := NewDurationOption("TIMEOUT", false).WithDefault("5s")
+	builder := NewMockRegistryBuilder(nil)
+	builder.AddOption(option)
+	registry, buildErr := builder.VerifyAndBuild()
+	suite.Require().NoError(buildErr)
+
+	suite.Assert().Equal(5*time.Second, GetRequiredTyped(registry, option))
+}
+
+func (suite *TypedOptionSuite) TestPrefixedOptions() {
+	option := NewIntOption("DB_PORT", true).WithDefault("3306")
+	prefixedOption := option.WithPrefix("REPORTING")
+	alreadySeparatedOption := option.WithPrefix("REPORTING_")
+	unprefixedOption := option.WithPrefix("")
+
+	suite.Assert().Equal("REPORTING_DB_PORT", prefixedOption.VariableName())
+	suite.Assert().Equal("REPORTING_DB_PORT", alreadySeparatedOption.VariableName())
+	suite.Assert().Equal("DB_PORT", unprefixedOption.VariableName())
+
+	// The prefixed copy keeps everything else about the option
+	defaultValue, hasDefault := prefixedOption.DefaultValue()
+	suite.Assert().True(hasDefault)
+	suite.Assert().Equal("3306", defaultValue)
+}
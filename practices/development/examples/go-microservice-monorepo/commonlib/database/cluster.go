@@ -0,0 +1,115 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"example.com/sample/commonlib/config"
+	"example.com/sample/commonlib/config/sharedoptions"
+	"github.com/jmoiron/sqlx"
+)
+
+// Cluster is a primary database along with read replicas of it. Attach it to request contexts with
+// CreateDerivativeClusterContext and RetrieveFromContext sends reads to a replica and writes and transactions to the
+// primary. ReadOnly and Primary override that choice for a context.
+type Cluster struct {
+	// Primary accepts writes and runs transactions
+	Primary *sqlx.DB
+	// Replicas serve reads, taking turns. Reads go to the primary if there are none.
+	Replicas []*sqlx.DB
+	// ReadYourWrites sends a request's reads to the primary once it has written, so it doesn't read stale data from a
+	// replica which hasn't caught up yet
+	ReadYourWrites bool
+	// Instrumentation observes the statements run in contexts the cluster is attached to, if set
+	Instrumentation *Instrumentation
+	// StatementTimeout is the default timeout of statements run in contexts the cluster is attached to. Zero disables
+	// it. WithStatementTimeout overrides it for a context.
+	StatementTimeout time.Duration
+
+	nextReplica atomic.Uint64
+}
+
+// NewCluster constructs a Cluster from a primary database and its read replicas, with ReadYourWrites enabled
+func NewCluster(primary *sqlx.DB, replicas ...*sqlx.DB) *Cluster {
+	return &Cluster{
+		Primary:        primary,
+		Replicas:       replicas,
+		ReadYourWrites: true,
+	}
+}
+
+// Replica returns the next read replica in turn, or the primary if the cluster has no replicas
+func (cluster *Cluster) Replica() *sqlx.DB {
+	if len(cluster.Replicas) == 0 {
+		return cluster.Primary
+	}
+
+	replicaIndex := (cluster.nextReplica.Add(1) - 1) % uint64(len(cluster.Replicas))
+	return cluster.Replicas[replicaIndex]
+}
+
+// All lists the primary followed by every replica, for code which has to visit each database such as health checks
+func (cluster *Cluster) All() []*sqlx.DB {
+	return append([]*sqlx.DB{cluster.Primary}, cluster.Replicas...)
+}
+
+// Close closes the primary and every replica
+func (cluster *Cluster) Close() error {
+	var closeErrs []error
+	for _, db := range cluster.All() {
+		closeErrs = append(closeErrs, db.Close())
+	}
+	return errors.Join(closeErrs...)
+}
+
+// ConnectClusterFromConfig reads database configuration options from the environment, namely those listed in
+// sharedoptions.DBOptions, and constructs the primary database along with every replica in sharedoptions.DBReplicaHosts.
+// Statements slower than sharedoptions.DBSlowQueryThreshold are logged, and those slower than
+// sharedoptions.DBStatementTimeout are cancelled.
+func ConnectClusterFromConfig(registry config.Registry) (*Cluster, error) {
+	return ConnectClusterFromConfigGroup(registry, sharedoptions.DefaultDB)
+}
+
+// ConnectClusterFromConfigGroup is like ConnectClusterFromConfig, except it reads the options in the passed option group
+func ConnectClusterFromConfigGroup(registry config.Registry, group sharedoptions.DBOptionGroup) (*Cluster, error) {
+	primaryConfig := ConfigFromGroup(registry, group)
+	primary, primaryErr := Connect(primaryConfig)
+	if primaryErr != nil {
+		return nil, primaryErr
+	}
+
+	cluster := NewCluster(primary)
+	cluster.ReadYourWrites = config.GetRequiredTyped(registry, group.ReadYourWrites)
+	cluster.Instrumentation = NewInstrumentation(config.GetRequiredTyped(registry, group.SlowQueryThreshold))
+	cluster.StatementTimeout = config.GetRequiredTyped(registry, group.StatementTimeout)
+	replicaHosts, _ := config.GetTyped(registry, group.ReplicaHosts)
+	for _, replicaHost := range replicaHosts {
+		replica, replicaErr := Connect(replicaConfig(primaryConfig, replicaHost))
+		if replicaErr != nil {
+			_ = cluster.Close()
+			return nil, fmt.Errorf("could not connect to replica %v: %w", replicaHost, replicaErr)
+		}
+		cluster.Replicas = append(cluster.Replicas, replica)
+	}
+
+	return cluster, nil
+}
+
+// replicaConfig copies the primary's configuration to a replica at the passed host, which may include a port. The
+// replica uses the primary's port if it doesn't.
+func replicaConfig(primaryConfig Config, replicaHost string) Config {
+	replica := primaryConfig
+	replica.Host = replicaHost
+	if splitHost, portText, splitErr := net.SplitHostPort(replicaHost); splitErr == nil {
+		// The port was validated when the registry was built
+		port, _ := strconv.Atoi(portText)
+		replica.Host = splitHost
+		replica.OptionalSettings.Port = &port
+	}
+
+	return replica
+}
@@ -1,47 +1,114 @@
 package database
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
 	"fmt"
+	"net/url"
+	"os"
+	"slices"
 	"strconv"
 	"time"
 
 	"example.com/sample/commonlib/config"
 	"example.com/sample/commonlib/config/sharedoptions"
+	"example.com/sample/commonlib/logger"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
 
-	_ "github.com/go-sql-driver/mysql"
+const (
+	// DriverMySQL connects to MySQL or MariaDB. Its driver is always registered.
+	DriverMySQL = "mysql"
+	// DriverPostgres connects to PostgreSQL. Services using it must register a driver by importing
+	// github.com/jackc/pgx/v5/stdlib or github.com/lib/pq for side effects, in their main package.
+	DriverPostgres = "postgres"
 )
 
-// Config contains configuration options for the database.
+// postgresDriverNames lists the database/sql driver names Postgres can be reached through, in order of preference.
+// sqlx recognizes both, so Rebind translates placeholders to $1 style for either.
+var postgresDriverNames = []string{"pgx", "postgres"}
+
+// Config contains configuration options for the database. ConfigFromGroup reads it from the options in
+// sharedoptions.DBOptions, or those of another sharedoptions.DBOptionGroup.
 type Config struct {
 	// Username is the username used to connect to the database.
 	Username string
 
-	// Password is the password used when connecting to the database.
-	Password string
+	// Password is the password used when connecting to the database. It is a config.Secret so it's redacted if the
+	// configuration is ever printed.
+	Password config.Secret
 
-	// Host is the hostname or IP address of the MariaDB server to connect to.
+	// Driver is the kind of database to connect to, DriverMySQL or DriverPostgres. It's DriverMySQL if left empty.
+	Driver string
+
+	// Host is the hostname or IP address of the database server to connect to.
 	Host string
 
-	// Schema is the default schema that should be used for SQL queries.
+	// Schema is the default schema that should be used for SQL queries. On Postgres, it's the name of the database.
 	Schema string
 
 	// OptionalSettings contains settings that aren't required. You can safely leave this as the zero value if you want.
 	OptionalSettings OptionalSettings
 }
 
-// OptionalSettings are performance-based connection settings that can be tweaked if desired.
+// OptionalSettings are connection, security and performance settings that can be tweaked if desired.
 type OptionalSettings struct {
-	// Port is the database port to connect to, if something other than 3306.
+	// Port is the database port to connect to, if something other than the driver's default of 3306 or 5432.
 	Port *int
-	// MaxOpenConnections sets a limit on the number of total open MySQL connections. It is set to 20 by default, and
+	// MaxOpenConnections sets a limit on the number of total open database connections. It is set to 20 by default, and
 	// should be larger than MaxIdleConnections.
 	MaxOpenConnections *int
 	// MaxIdleConnections sets a limit on the limit of idle SQL connections in the database pool. It is set to 5 by default,
 	// and should be smaller than MaxOpenConnections.
 	MaxIdleConnections *int
+	// MultiStatements allows a single query to contain several statements separated by semicolons. It's off by default,
+	// since it makes SQL injection more damaging, and is meant for running migration files. Postgres always allows
+	// several statements in queries without arguments, so it has no effect there.
+	MultiStatements bool
+
+	// TLS is the TLS mode of connections: "false" or empty to connect in plain text, "true" to require TLS and verify the
+	// server's certificate, "skip-verify" to require TLS without verifying the certificate, or "preferred" to use TLS
+	// only if the server supports it. On Postgres, they map to the sslmode disable, verify-full, require and prefer.
+	TLS string
+	// TLSCAFile is the path of a PEM bundle of the certificate authorities to verify the server's certificate with,
+	// such as the one a managed database provider publishes. The system's certificate authorities are used if it's
+	// empty. It requires TLS to be "true".
+	TLSCAFile string
+
+	// DialTimeout limits how long establishing a connection may take. The operating system's limit applies if it's zero.
+	DialTimeout time.Duration
+	// ReadTimeout limits how long reading a response from a MySQL connection may take. There's no limit if it's zero.
+	ReadTimeout time.Duration
+	// WriteTimeout limits how long writing a request to a MySQL connection may take. There's no limit if it's zero.
+	WriteTimeout time.Duration
+
+	// ParseTime scans MySQL DATE and DATETIME columns into time.Time instead of []byte or string
+	ParseTime bool
+	// Location is the time zone MySQL DATETIME values are interpreted in, as a name from the IANA time zone database or
+	// "Local". It's UTC if left empty.
+	Location string
+	// Charset is the character set of MySQL connections, such as utf8mb4. The server's default is used if it's empty.
+	Charset string
+	// Collation is the collation of MySQL connections. The driver's default of utf8mb4_general_ci is used if it's empty.
+	Collation string
+
+	// ConnMaxLifetime is how long a connection may be reused before it's closed. It is set to 3 minutes by default, as
+	// less than 5 minutes is recommended by the MySQL driver: https://github.com/go-sql-driver/mysql#important-settings
+	ConnMaxLifetime *time.Duration
+	// ConnMaxIdleTime is how long a connection may sit idle in the pool before it's closed. There's no limit by default.
+	ConnMaxIdleTime *time.Duration
 }
 
+const (
+	tlsDisabled   = "false"
+	tlsVerified   = "true"
+	tlsSkipVerify = "skip-verify"
+	tlsPreferred  = "preferred"
+)
+
 // Connect connects to the database using the provided configuration.
 func Connect(config Config) (*sqlx.DB, error) {
 	dbHost := config.Host
@@ -49,22 +116,27 @@ func Connect(config Config) (*sqlx.DB, error) {
 		dbHost += fmt.Sprintf(":%v", *config.OptionalSettings.Port)
 	}
 
-	db, connectErr := sqlx.Open("mysql", fmt.Sprintf("%v:%v@tcp(%v)/%v", config.Username, config.Password, dbHost, config.Schema))
+	db, connectErr := open(config, dbHost)
 	if connectErr != nil {
 		return nil, fmt.Errorf("failed to connect to database with given credentials (user %v, host %v, schema %v): %w",
 			config.Username, dbHost, config.Schema, connectErr)
 	}
 
-	// Setting max connection lifetime to 3 minutes, as less than 5 minutes is recommended by the driver: https://github.com/go-sql-driver/mysql#important-settings
-	db.SetConnMaxLifetime(3 * time.Minute)
-
+	if config.OptionalSettings.ConnMaxLifetime != nil {
+		db.SetConnMaxLifetime(*config.OptionalSettings.ConnMaxLifetime)
+	} else {
+		db.SetConnMaxLifetime(3 * time.Minute)
+	}
+	if config.OptionalSettings.ConnMaxIdleTime != nil {
+		db.SetConnMaxIdleTime(*config.OptionalSettings.ConnMaxIdleTime)
+	}
 	if config.OptionalSettings.MaxIdleConnections != nil {
 		db.SetMaxIdleConns(*config.OptionalSettings.MaxIdleConnections)
 	} else {
 		db.SetMaxIdleConns(5)
 	}
 	if config.OptionalSettings.MaxOpenConnections != nil {
-		db.SetMaxOpenConns(*config.OptionalSettings.MaxIdleConnections)
+		db.SetMaxOpenConns(*config.OptionalSettings.MaxOpenConnections)
 	} else {
 		db.SetMaxOpenConns(20)
 	}
@@ -72,36 +144,222 @@ func Connect(config Config) (*sqlx.DB, error) {
 	return db, nil
 }
 
+// open opens a database of the configured kind, connecting to the passed host and optional port. Connections aren't
+// established until they're needed. Their rows release the context they were queried with once closed.
+func open(config Config, dbHost string) (*sqlx.DB, error) {
+	switch config.Driver {
+	case "", DriverMySQL:
+		mysqlCfg, cfgErr := mysqlConfig(config, dbHost)
+		if cfgErr != nil {
+			return nil, cfgErr
+		}
+		connector, connectorErr := mysql.NewConnector(mysqlCfg)
+		if connectorErr != nil {
+			return nil, connectorErr
+		}
+		return sqlx.NewDb(sql.OpenDB(releaseRows(connector)), DriverMySQL), nil
+	case DriverPostgres:
+		driverName, dsn, dsnErr := postgresDataSourceName(config, dbHost)
+		if dsnErr != nil {
+			return nil, dsnErr
+		}
+		connector, connectorErr := registeredConnector(driverName, dsn)
+		if connectorErr != nil {
+			return nil, connectorErr
+		}
+		return sqlx.NewDb(sql.OpenDB(releaseRows(connector)), driverName), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %v, it must be %v or %v", config.Driver, DriverMySQL, DriverPostgres)
+	}
+}
+
+// mysqlConfig translates the configuration into the MySQL driver's, which takes care of escaping credentials and
+// parameters. A TLS configuration trusting TLSCAFile is registered with the driver if there is one.
+func mysqlConfig(config Config, dbHost string) (*mysql.Config, error) {
+	settings := config.OptionalSettings
+	mysqlCfg := mysql.NewConfig()
+	mysqlCfg.User = config.Username
+	mysqlCfg.Passwd = config.Password.Reveal()
+	mysqlCfg.Net = "tcp"
+	mysqlCfg.Addr = dbHost
+	mysqlCfg.DBName = config.Schema
+	mysqlCfg.MultiStatements = settings.MultiStatements
+	mysqlCfg.Timeout = settings.DialTimeout
+	mysqlCfg.ReadTimeout = settings.ReadTimeout
+	mysqlCfg.WriteTimeout = settings.WriteTimeout
+	mysqlCfg.ParseTime = settings.ParseTime
+
+	if settings.Location != "" {
+		location, locationErr := time.LoadLocation(settings.Location)
+		if locationErr != nil {
+			return nil, fmt.Errorf("invalid database location %v: %w", settings.Location, locationErr)
+		}
+		mysqlCfg.Loc = location
+	}
+	if settings.Charset != "" {
+		mysqlCfg.Params = map[string]string{"charset": settings.Charset}
+	}
+	if settings.Collation != "" {
+		mysqlCfg.Collation = settings.Collation
+	}
+
+	tlsMode, tlsErr := validateTLS(settings)
+	if tlsErr != nil {
+		return nil, tlsErr
+	}
+	mysqlCfg.TLSConfig = tlsMode
+	if settings.TLSCAFile != "" {
+		rootCAs, caErr := loadCertificateAuthorities(settings.TLSCAFile)
+		if caErr != nil {
+			return nil, caErr
+		}
+		// Each host gets its own key, since the server name the certificate is verified against differs
+		tlsKey := fmt.Sprintf("database-%v", dbHost)
+		registerErr := mysql.RegisterTLSConfig(tlsKey, &tls.Config{RootCAs: rootCAs, ServerName: config.Host, MinVersion: tls.VersionTLS12})
+		if registerErr != nil {
+			return nil, registerErr
+		}
+		mysqlCfg.TLSConfig = tlsKey
+	}
+
+	return mysqlCfg, nil
+}
+
+// postgresDataSourceName picks the first registered Postgres driver and builds the URL it expects, connecting to the
+// passed host and optional port
+func postgresDataSourceName(config Config, dbHost string) (string, string, error) {
+	settings := config.OptionalSettings
+	tlsMode, tlsErr := validateTLS(settings)
+	if tlsErr != nil {
+		return "", "", tlsErr
+	}
+	query := url.Values{}
+	query.Set("sslmode", map[string]string{
+		tlsDisabled:   "disable",
+		tlsVerified:   "verify-full",
+		tlsSkipVerify: "require",
+		tlsPreferred:  "prefer",
+	}[tlsMode])
+	if settings.TLSCAFile != "" {
+		query.Set("sslrootcert", settings.TLSCAFile)
+	}
+	if settings.DialTimeout > 0 {
+		// connect_timeout is in whole seconds, and zero would mean no limit
+		query.Set("connect_timeout", strconv.Itoa(max(int(settings.DialTimeout.Round(time.Second)/time.Second), 1)))
+	}
+
+	registeredDrivers := sql.Drivers()
+	for _, driverName := range postgresDriverNames {
+		if slices.Contains(registeredDrivers, driverName) {
+			postgresURL := url.URL{
+				Scheme:   "postgres",
+				User:     url.UserPassword(config.Username, config.Password.Reveal()),
+				Host:     dbHost,
+				Path:     "/" + config.Schema,
+				RawQuery: query.Encode(),
+			}
+			return driverName, postgresURL.String(), nil
+		}
+	}
+	return "", "", fmt.Errorf("no Postgres driver is registered, import github.com/jackc/pgx/v5/stdlib or github.com/lib/pq for side effects to register one")
+}
+
+// validateTLS checks the TLS settings make sense together, returning the TLS mode with the empty mode meaning disabled
+func validateTLS(settings OptionalSettings) (string, error) {
+	switch settings.TLS {
+	case "", tlsDisabled:
+		if settings.TLSCAFile != "" {
+			return "", fmt.Errorf("a database TLS CA file is set but TLS is disabled, set the TLS mode to %v", tlsVerified)
+		}
+		return tlsDisabled, nil
+	case tlsVerified:
+		return tlsVerified, nil
+	case tlsSkipVerify, tlsPreferred:
+		if settings.TLSCAFile != "" {
+			return "", fmt.Errorf("a database TLS CA file is set but the TLS mode %v doesn't verify certificates, set it to %v",
+				settings.TLS, tlsVerified)
+		}
+		return settings.TLS, nil
+	default:
+		return "", fmt.Errorf("unsupported database TLS mode %v, it must be %v, %v, %v or %v", settings.TLS, tlsDisabled,
+			tlsVerified, tlsSkipVerify, tlsPreferred)
+	}
+}
+
+// loadCertificateAuthorities reads a PEM bundle of certificate authorities
+func loadCertificateAuthorities(caFile string) (*x509.CertPool, error) {
+	pemBundle, readErr := os.ReadFile(caFile)
+	if readErr != nil {
+		return nil, fmt.Errorf("could not read database TLS CA file: %w", readErr)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(pemBundle) {
+		return nil, fmt.Errorf("database TLS CA file %v contains no PEM certificates", caFile)
+	}
+	return rootCAs, nil
+}
+
 // ConnectFromConfig reads database configuration options from the environment, namely those listed in sharedoptions.DBOptions,
 // and constructs the database.
 func ConnectFromConfig(registry config.Registry) (*sqlx.DB, error) {
+	return ConnectFromConfigGroup(registry, sharedoptions.DefaultDB)
+}
+
+// ConnectFromConfigGroup reads the database configuration options in the passed option group from the environment and
+// constructs the database. Use it with a group from sharedoptions.NewDBOptionGroup to connect to additional databases.
+func ConnectFromConfigGroup(registry config.Registry, group sharedoptions.DBOptionGroup) (*sqlx.DB, error) {
+	return Connect(ConfigFromGroup(registry, group))
+}
+
+// ConfigFromGroup reads the database configuration options in the passed option group into a Config, for code which
+// needs to adjust the configuration before connecting, such as the migrate package.
+func ConfigFromGroup(registry config.Registry, group sharedoptions.DBOptionGroup) Config {
 	dbConfig := Config{
-		Username: registry.GetRequired(sharedoptions.DBUser),
-		Password: registry.GetRequired(sharedoptions.DBPassword),
-		Host:     registry.GetRequired(sharedoptions.DBHostname),
-		Schema:   registry.GetRequired(sharedoptions.DBSchema),
-	}
-	if value, isPresent := registry.Get(sharedoptions.DBMaxConnections); isPresent {
-		if intValue, parseErr := strconv.Atoi(value); parseErr == nil {
-			dbConfig.OptionalSettings.MaxOpenConnections = &intValue
-		} else {
-			return nil, fmt.Errorf("a non-number slipped past validation on max database connections option with value \"%v\": %w", value, parseErr)
-		}
+		Driver:   config.GetRequiredTyped(registry, group.Driver),
+		Username: registry.GetRequired(group.User),
+		Password: config.GetRequiredTyped(registry, group.Password),
+		Host:     registry.GetRequired(group.Hostname),
+		Schema:   registry.GetRequired(group.Schema),
 	}
-	if value, isPresent := registry.Get(sharedoptions.DBMaxIdleConnections); isPresent {
-		if intValue, parseErr := strconv.Atoi(value); parseErr == nil {
-			dbConfig.OptionalSettings.MaxIdleConnections = &intValue
-		} else {
-			return nil, fmt.Errorf("a non-number slipped past validation on max idle database connections option with value \"%v\": %w", value, parseErr)
-		}
+	maxOpenConnections := config.GetRequiredTyped(registry, group.MaxConnections)
+	dbConfig.OptionalSettings.MaxOpenConnections = &maxOpenConnections
+	maxIdleConnections := config.GetRequiredTyped(registry, group.MaxIdleConnections)
+	dbConfig.OptionalSettings.MaxIdleConnections = &maxIdleConnections
+	if value, isPresent := config.GetTyped(registry, group.Port); isPresent {
+		dbConfig.OptionalSettings.Port = &value
 	}
-	if value, isPresent := registry.Get(sharedoptions.DBPort); isPresent {
-		if intValue, parseErr := strconv.Atoi(value); parseErr == nil {
-			dbConfig.OptionalSettings.Port = &intValue
-		} else {
-			return nil, fmt.Errorf("a non-number slipped past validation on database port option with value \"%v\": %w", value, parseErr)
-		}
+	dbConfig.OptionalSettings.TLS = config.GetRequiredTyped(registry, group.TLS)
+	dbConfig.OptionalSettings.TLSCAFile, _ = registry.Get(group.TLSCAFile)
+	dbConfig.OptionalSettings.DialTimeout, _ = config.GetTyped(registry, group.DialTimeout)
+	dbConfig.OptionalSettings.ReadTimeout, _ = config.GetTyped(registry, group.ReadTimeout)
+	dbConfig.OptionalSettings.WriteTimeout, _ = config.GetTyped(registry, group.WriteTimeout)
+	dbConfig.OptionalSettings.ParseTime = config.GetRequiredTyped(registry, group.ParseTime)
+	dbConfig.OptionalSettings.Location, _ = registry.Get(group.Location)
+	dbConfig.OptionalSettings.Charset, _ = registry.Get(group.Charset)
+	dbConfig.OptionalSettings.Collation, _ = registry.Get(group.Collation)
+	connMaxLifetime := config.GetRequiredTyped(registry, group.ConnMaxLifetime)
+	dbConfig.OptionalSettings.ConnMaxLifetime = &connMaxLifetime
+	if value, isPresent := config.GetTyped(registry, group.ConnMaxIdleTime); isPresent {
+		dbConfig.OptionalSettings.ConnMaxIdleTime = &value
 	}
 
-	return Connect(dbConfig)
+	return dbConfig
+}
+
+// WatchPoolSettings keeps the connection pool limits of the passed database in sync with sharedoptions.DBMaxConnections
+// and sharedoptions.DBMaxIdleConnections in a reloadable registry, applying them every time a reload changes either one.
+func WatchPoolSettings(registry *config.ReloadableRegistry, db *sqlx.DB) {
+	WatchPoolSettingsGroup(registry, sharedoptions.DefaultDB, db)
+}
+
+// WatchPoolSettingsGroup is like WatchPoolSettings, except it applies the pool limits of the passed option group
+func WatchPoolSettingsGroup(registry *config.ReloadableRegistry, group sharedoptions.DBOptionGroup, db *sqlx.DB) {
+	registry.Subscribe(func(current config.Registry) {
+		maxOpenConnections := config.GetRequiredTyped(current, group.MaxConnections)
+		maxIdleConnections := config.GetRequiredTyped(current, group.MaxIdleConnections)
+		db.SetMaxOpenConns(maxOpenConnections)
+		db.SetMaxIdleConns(maxIdleConnections)
+		logger.Log.Info("Database pool limits changed by a configuration reload", zap.String("host", current.GetRequired(group.Hostname)),
+			zap.Int("maxOpenConnections", maxOpenConnections), zap.Int("maxIdleConnections", maxIdleConnections))
+	}, group.MaxConnections, group.MaxIdleConnections)
 }
@@ -0,0 +1,241 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultConnectivityInitialDelay is the delay after the first failed attempt when a ConnectivityChecker doesn't set
+	// one
+	defaultConnectivityInitialDelay = time.Second
+	// defaultConnectivityMaxDelay caps the delay between attempts when a ConnectivityChecker doesn't set a cap
+	defaultConnectivityMaxDelay = 30 * time.Second
+)
+
+// ErrConnectivityTimeout is returned by ConnectivityChecker.WaitUntilConnected when the database couldn't be reached
+// within the checker's MaxWait
+var ErrConnectivityTimeout = errors.New("timed out waiting for the database")
+
+// Pinger is the part of *sqlx.DB a ConnectivityChecker needs to check the database can be reached
+type Pinger interface {
+	// PingContext checks the database can be reached, connecting to it if necessary
+	PingContext(ctx context.Context) error
+}
+
+// ConnectivityState describes whether a ConnectivityChecker could last reach the database
+type ConnectivityState int
+
+const (
+	// StateConnecting means the database hasn't been reached yet
+	StateConnecting ConnectivityState = iota
+	// StateConnected means the database was reached the last time it was checked
+	StateConnected
+	// StateDisconnected means the database was reached before but can't be anymore, and the checker is reconnecting
+	StateDisconnected
+	// StateMisconfigured means the database was reached but rejected the connection, such as for a wrong password,
+	// which retrying won't fix. See IsMisconfigured.
+	StateMisconfigured
+)
+
+// String implements fmt.Stringer for ConnectivityState
+func (state ConnectivityState) String() string {
+	switch state {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateMisconfigured:
+		return "misconfigured"
+	default:
+		return fmt.Sprintf("ConnectivityState(%d)", int(state))
+	}
+}
+
+// ConnectivityEvent reports the outcome of one attempt to reach the database
+type ConnectivityEvent struct {
+	// State is the checker's state after the attempt
+	State ConnectivityState
+	// Attempt counts the attempts made since the database was last reached, starting from 1
+	Attempt int
+	// Elapsed is how long the checker has been trying to reach the database since it was last reached
+	Elapsed time.Duration
+	// RetryIn is how long until the next attempt, or zero if the checker won't retry
+	RetryIn time.Duration
+	// Err is the error the attempt failed with, or nil if it succeeded
+	Err error
+}
+
+// ConnectivityChecker checks a database can be reached, retrying with exponential backoff while it can't. Use
+// WaitUntilConnected at startup, then Monitor to notice lost connectivity and reconnect while the application runs.
+// Other components can read its State, register Check with a readiness probe, or follow its progress with OnEvent.
+//
+// Set the exported fields before calling any methods.
+type ConnectivityChecker struct {
+	// InitialDelay is how long to wait after the first failed attempt. It doubles after each failed attempt after that.
+	// Zero or less means 1 second.
+	InitialDelay time.Duration
+	// MaxDelay caps the delay between attempts. Zero or less means 30 seconds.
+	MaxDelay time.Duration
+	// MaxWait limits how long WaitUntilConnected tries to reach the database. Zero means it tries until its context is
+	// done.
+	MaxWait time.Duration
+	// MonitorInterval is how often Monitor checks the database can still be reached
+	MonitorInterval time.Duration
+
+	db Pinger
+
+	// lock guards the fields below it
+	lock      sync.Mutex
+	state     ConnectivityState
+	lastErr   error
+	listeners []func(ConnectivityEvent)
+}
+
+// NewConnectivityChecker constructs a ConnectivityChecker for the passed database. It waits 1 second after the first
+// failed attempt, up to 30 seconds between later ones, for at most 5 minutes, and checks connectivity every 15 seconds
+// while monitoring.
+func NewConnectivityChecker(db Pinger) *ConnectivityChecker {
+	return &ConnectivityChecker{
+		InitialDelay:    defaultConnectivityInitialDelay,
+		MaxDelay:        defaultConnectivityMaxDelay,
+		MaxWait:         5 * time.Minute,
+		MonitorInterval: 15 * time.Second,
+		db:              db,
+	}
+}
+
+// OnEvent registers a function called after every attempt to reach the database, such as to log progress. It's called
+// on the goroutine making the attempt, so it must not block.
+func (checker *ConnectivityChecker) OnEvent(listener func(ConnectivityEvent)) {
+	checker.lock.Lock()
+	defer checker.lock.Unlock()
+
+	checker.listeners = append(checker.listeners, listener)
+}
+
+// State returns the checker's current state along with the error of the last failed attempt, if it didn't succeed
+func (checker *ConnectivityChecker) State() (ConnectivityState, error) {
+	checker.lock.Lock()
+	defer checker.lock.Unlock()
+
+	return checker.state, checker.lastErr
+}
+
+// Check reports whether the database was reachable the last time it was checked, returning an error describing why
+// not if it wasn't. It doesn't contact the database itself, so it's cheap enough for readiness probes.
+func (checker *ConnectivityChecker) Check(context.Context) error {
+	state, lastErr := checker.State()
+	if state == StateConnected {
+		return nil
+	}
+	if lastErr != nil {
+		return fmt.Errorf("database is %v: %w", state, lastErr)
+	}
+	return fmt.Errorf("database is %v", state)
+}
+
+// WaitUntilConnected tries to reach the database until it succeeds, the checker's MaxWait passes, or the passed
+// context is done. It gives up straight away if the database is misconfigured, returning the error the database
+// responded with.
+func (checker *ConnectivityChecker) WaitUntilConnected(ctx context.Context) error {
+	if checker.MaxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, checker.MaxWait)
+		defer cancel()
+	}
+
+	connectErr := checker.connect(ctx, StateConnecting)
+	if connectErr != nil && errors.Is(connectErr, context.DeadlineExceeded) && checker.MaxWait > 0 {
+		return fmt.Errorf("%w after %v: %w", ErrConnectivityTimeout, checker.MaxWait, connectErr)
+	}
+	return connectErr
+}
+
+// Monitor checks the database can still be reached every MonitorInterval until the passed context is done. When it
+// can't be, the state changes to StateDisconnected and Monitor retries with backoff, without a time limit, until the
+// database can be reached again. database/sql replaces broken connections by itself, so succeeding to ping it again
+// means the application has reconnected.
+func (checker *ConnectivityChecker) Monitor(ctx context.Context) {
+	ticker := time.NewTicker(checker.MonitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if pingErr := checker.db.PingContext(ctx); pingErr != nil && ctx.Err() == nil {
+			// A misconfiguration appearing while running, such as a rotated password, may be fixed without a restart,
+			// so keep retrying either way
+			_ = checker.connect(ctx, StateDisconnected)
+		}
+	}
+}
+
+// connect pings the database until it succeeds, it's misconfigured, or the context is done. The state is set to
+// failedState while attempts fail.
+func (checker *ConnectivityChecker) connect(ctx context.Context, failedState ConnectivityState) error {
+	delay, maxDelay := checker.InitialDelay, checker.MaxDelay
+	if delay <= 0 {
+		delay = defaultConnectivityInitialDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultConnectivityMaxDelay
+	}
+
+	startTime := time.Now()
+	for attempt := 1; ; attempt++ {
+		pingErr := checker.db.PingContext(ctx)
+		if pingErr == nil {
+			checker.report(ConnectivityEvent{State: StateConnected, Attempt: attempt, Elapsed: time.Since(startTime)})
+			return nil
+		}
+
+		event := ConnectivityEvent{State: failedState, Attempt: attempt, Elapsed: time.Since(startTime), RetryIn: delay, Err: pingErr}
+		if IsMisconfigured(pingErr) {
+			event.State = StateMisconfigured
+			if failedState == StateConnecting {
+				event.RetryIn = 0
+				checker.report(event)
+				return pingErr
+			}
+		}
+		if ctx.Err() != nil {
+			event.RetryIn = 0
+			checker.report(event)
+			return fmt.Errorf("stopped waiting for the database (%w): %w", ctx.Err(), pingErr)
+		}
+		checker.report(event)
+
+		select {
+		case <-ctx.Done():
+			// Report giving up, so listeners aren't left expecting another attempt
+			event.RetryIn, event.Elapsed = 0, time.Since(startTime)
+			checker.report(event)
+			return fmt.Errorf("stopped waiting for the database (%w): %w", ctx.Err(), pingErr)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxDelay)
+	}
+}
+
+// report records the outcome of an attempt and passes it to the listeners
+func (checker *ConnectivityChecker) report(event ConnectivityEvent) {
+	checker.lock.Lock()
+	checker.state = event.State
+	checker.lastErr = event.Err
+	listeners := append([]func(ConnectivityEvent){}, checker.listeners...)
+	checker.lock.Unlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/suite"
+)
+
+// scriptedPinger fails pings with a list of errors in order, then succeeds unless told to keep failing
+type scriptedPinger struct {
+	lock     sync.Mutex
+	failures []error
+	failWith error
+	pings    int
+}
+
+func (pinger *scriptedPinger) PingContext(ctx context.Context) error {
+	pinger.lock.Lock()
+	defer pinger.lock.Unlock()
+
+	pinger.pings++
+	if len(pinger.failures) > 0 {
+		failure := pinger.failures[0]
+		pinger.failures = pinger.failures[1:]
+		return failure
+	}
+	return pinger.failWith
+}
+
+// failNext makes the next pings fail with the passed errors
+func (pinger *scriptedPinger) failNext(errs ...error) {
+	pinger.lock.Lock()
+	defer pinger.lock.Unlock()
+
+	pinger.failures = append(pinger.failures, errs...)
+}
+
+var errUnreachable = errors.New("dial tcp: connection refused")
+
+type ConnectivitySuite struct {
+	suite.Suite
+	pinger  *scriptedPinger
+	checker *ConnectivityChecker
+	events  chan ConnectivityEvent
+}
+
+func TestConnectivitySuite(t *testing.T) {
+	suite.Run(t, new(ConnectivitySuite))
+}
+
+func (suite *ConnectivitySuite) SetupTest() {
+	suite.pinger = &scriptedPinger{}
+	suite.checker = NewConnectivityChecker(suite.pinger)
+	suite.checker.InitialDelay = time.Millisecond
+	suite.checker.MaxDelay = 4 * time.Millisecond
+	suite.checker.MonitorInterval = time.Millisecond
+	suite.events = make(chan ConnectivityEvent, 100)
+	suite.checker.OnEvent(func(event ConnectivityEvent) {
+		suite.events <- event
+	})
+}
+
+// nextEvent waits for the checker's next event
+func (suite *ConnectivitySuite) nextEvent() ConnectivityEvent {
+	select {
+	case event := <-suite.events:
+		return event
+	case <-time.After(time.Second):
+		suite.FailNow("Timed out waiting for a connectivity event")
+		return ConnectivityEvent{}
+	}
+}
+
+func (suite *ConnectivitySuite) TestRetriesWithBackoff() {
+	suite.pinger.failNext(errUnreachable, errUnreachable, errUnreachable, errUnreachable)
+
+	suite.Require().NoError(suite.checker.WaitUntilConnected(context.Background()))
+
+	var delays []time.Duration
+	for attempt := 1; attempt <= 4; attempt++ {
+		event := suite.nextEvent()
+		suite.Assert().Equal(StateConnecting, event.State)
+		suite.Assert().Equal(attempt, event.Attempt)
+		suite.Assert().ErrorIs(event.Err, errUnreachable)
+		delays = append(delays, event.RetryIn)
+	}
+	suite.Assert().Equal([]time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond}, delays)
+
+	connected := suite.nextEvent()
+	suite.Assert().Equal(StateConnected, connected.State)
+	suite.Assert().Equal(5, connected.Attempt)
+	suite.Assert().NoError(suite.checker.Check(context.Background()))
+}
+
+func (suite *ConnectivitySuite) TestUnsetMaxDelayFallsBackToTheDefault() {
+	suite.checker.MaxDelay = 0
+	suite.pinger.failNext(errUnreachable, errUnreachable, errUnreachable)
+
+	suite.Require().NoError(suite.checker.WaitUntilConnected(context.Background()))
+
+	var delays []time.Duration
+	for attempt := 1; attempt <= 3; attempt++ {
+		delays = append(delays, suite.nextEvent().RetryIn)
+	}
+	suite.Assert().Equal([]time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond}, delays)
+}
+
+func (suite *ConnectivitySuite) TestUnsetInitialDelayFallsBackToTheDefault() {
+	suite.checker.InitialDelay = 0
+	suite.pinger.failNext(errUnreachable)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	suite.Require().ErrorIs(suite.checker.WaitUntilConnected(ctx), context.DeadlineExceeded)
+
+	suite.Assert().Equal(time.Second, suite.nextEvent().RetryIn)
+}
+
+func (suite *ConnectivitySuite) TestGivesUpAfterMaxWait() {
+	suite.pinger.failWith = errUnreachable
+	suite.checker.MaxWait = 20 * time.Millisecond
+
+	waitErr := suite.checker.WaitUntilConnected(context.Background())
+
+	suite.Assert().ErrorIs(waitErr, ErrConnectivityTimeout)
+	suite.Assert().ErrorIs(waitErr, errUnreachable)
+	suite.Assert().Error(suite.checker.Check(context.Background()))
+}
+
+func (suite *ConnectivitySuite) TestStopsWhenContextIsDone() {
+	suite.pinger.failWith = errUnreachable
+	suite.checker.MaxWait = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	waitErr := suite.checker.WaitUntilConnected(ctx)
+
+	suite.Assert().ErrorIs(waitErr, context.Canceled)
+	suite.Assert().NotErrorIs(waitErr, ErrConnectivityTimeout)
+}
+
+func (suite *ConnectivitySuite) TestGivesUpWhenMisconfigured() {
+	accessDenied := &mysql.MySQLError{Number: 1045, Message: "Access denied"}
+	suite.pinger.failWith = accessDenied
+
+	waitErr := suite.checker.WaitUntilConnected(context.Background())
+
+	suite.Assert().ErrorIs(waitErr, accessDenied)
+	suite.Assert().Equal(1, suite.pinger.pings)
+	state, lastErr := suite.checker.State()
+	suite.Assert().Equal(StateMisconfigured, state)
+	suite.Assert().ErrorIs(lastErr, accessDenied)
+}
+
+func (suite *ConnectivitySuite) TestMonitorReconnects() {
+	suite.Require().NoError(suite.checker.WaitUntilConnected(context.Background()))
+	suite.Require().Equal(StateConnected, suite.nextEvent().State)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	suite.pinger.failNext(errUnreachable, errUnreachable)
+	go suite.checker.Monitor(ctx)
+
+	// The monitor's failed ping is followed by a reconnection attempt, which fails once more before succeeding
+	disconnected := suite.nextEvent()
+	suite.Assert().Equal(StateDisconnected, disconnected.State)
+	suite.Assert().ErrorIs(disconnected.Err, errUnreachable)
+	suite.Assert().Equal(StateConnected, suite.nextEvent().State)
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
+	"example.com/sample/commonlib/logger"
 	"example.com/sample/commonlib/request/testhelper"
 	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
 )
 
 // ctxConnectionKey is where the main database connection is stored in a database context
@@ -82,6 +85,20 @@ func NamedQueryContext(ctx context.Context, cxn Connection, query string, arg an
 		return sqlx.NamedQueryContext(ctx, rawCxn, query, arg)
 	case *sqlx.Tx:
 		return sqlx.NamedQueryContext(ctx, rawCxn, query, arg)
+	case *clusterConnection:
+		return sqlx.NamedQueryContext(ctx, rawCxn.reader, query, arg)
+	case *boundConnection:
+		rowsCtx, cancel := rawCxn.rowsContext(ctx)
+		rows, queryErr := NamedQueryContext(rowsCtx, rawCxn.inner, query, arg)
+		if queryErr != nil {
+			cancel()
+		}
+		return rows, queryErr
+	case *instrumentedConnection:
+		start := time.Now()
+		rows, queryErr := NamedQueryContext(ctx, rawCxn.inner, query, arg)
+		rawCxn.observe("NamedQueryContext", query, start, -1, queryErr)
+		return rows, queryErr
 	default:
 		panic("NamedQueryContext only accepts database.Connection implementations of *sqlx.DB and *sqlx.Tx! Someone passed a different type!")
 	}
@@ -99,20 +116,36 @@ func CreateDerivativeContext(ctx context.Context, db *sqlx.DB) context.Context {
 }
 
 // CreateDerivativeMockContext derives a database context from another context, attaching a mock connection rather than
-// a real database connection.
+// a real database connection. Functions registered with AfterCommit and AfterRollback in the context are recorded in
+// the MockTransactionHooks returned by MockHooksFromContext.
 func CreateDerivativeMockContext(ctx context.Context, mockConnection *MockConnection) context.Context {
 	// Don't overwrite the DB if it's already present in the context
 	if ctx.Value(ctxConnectionKey{}) != nil {
 		return ctx
 	}
 
-	return context.WithValue(ctx, ctxConnectionKey{}, mockConnection)
+	// Hooks registered with AfterCommit and AfterRollback are recorded for the test to inspect, rather than run
+	mockCtx := context.WithValue(ctx, ctxConnectionKey{}, mockConnection)
+	return context.WithValue(mockCtx, ctxMockHooksKey{}, &MockTransactionHooks{})
 }
 
 // RetrieveFromContext extracts the database connection from the current context. It is expected that some mechanism
-// such as middleware.DatabaseContextMiddleware has already added the database to the context via CreateDerivativeContext.
-// If the database is not present in the context this function will panic.
+// such as middleware.DatabaseContextMiddleware has already added the database to the context via CreateDerivativeContext
+// or CreateDerivativeClusterContext. If the database is not present in the context this function will panic.
+//
+// If a Cluster was added, the returned connection sends reads to a replica and writes to the primary, unless the
+// context was derived with ReadOnly or Primary. Transactions run on the primary. If the context was derived with
+// WithInstrumentation, the returned connection reports every statement to the Instrumentation.
+//
+// The returned connection is bound to the passed context, so its methods which don't accept a context are cancelled
+// along with it, such as when the client of an HTTP request disconnects. Statements also time out after the context's
+// statement timeout, which a Cluster sets by default and WithStatementTimeout overrides.
 func RetrieveFromContext(ctx context.Context) Connection {
+	return instrument(ctx, bind(ctx, retrieveConnection(ctx)))
+}
+
+// retrieveConnection extracts the database connection from the current context, without instrumentation
+func retrieveConnection(ctx context.Context) Connection {
 	if txConnection := ctx.Value(ctxTransactionKey{}); txConnection != nil {
 		return txConnection.(*sqlx.Tx)
 	}
@@ -120,6 +153,8 @@ func RetrieveFromContext(ctx context.Context) Connection {
 		switch actualConn := dbConnection.(type) {
 		case *sqlx.DB:
 			return actualConn
+		case *Cluster:
+			return routeConnection(ctx, actualConn)
 		case *MockConnection:
 			return actualConn
 		}
@@ -128,18 +163,55 @@ func RetrieveFromContext(ctx context.Context) Connection {
 	panic("Database connection was not present in context! Make sure the database context middleware is installed.")
 }
 
+// TxOptions configures a transaction started by WithTransactionOptions or WithTransactionReturningOptions
+type TxOptions struct {
+	// Isolation is the transaction's isolation level. The zero value, sql.LevelDefault, uses the database's default.
+	Isolation sql.IsolationLevel
+	// ReadOnly starts a read-only transaction, which the database rejects writes in
+	ReadOnly bool
+	// Nested makes a call inside an active transaction run in a savepoint instead of doing nothing. If the inner
+	// operation fails, only its changes are rolled back, and the outer operation can carry on. Isolation and ReadOnly
+	// don't apply to nested calls, which share the settings of the outer transaction.
+	Nested bool
+	// Retry re-runs the whole operation in a new transaction if the transaction fails because of contention with
+	// another one, such as a deadlock. Only the call which started the transaction retries, since the database has
+	// already aborted the transaction by the time a nested call would see the error. The operation must be safe to run
+	// more than once.
+	Retry RetryPolicy
+}
+
+// sqlOptions converts the options to those accepted by database/sql, or nil if they're all defaults
+func (options TxOptions) sqlOptions() *sql.TxOptions {
+	if options.Isolation == sql.LevelDefault && !options.ReadOnly {
+		return nil
+	}
+	return &sql.TxOptions{Isolation: options.Isolation, ReadOnly: options.ReadOnly}
+}
+
+// ctxSavepointDepthKey is where the number of savepoints enclosing the current operation is stored in a transaction
+// context
+type ctxSavepointDepthKey struct{}
+
 // preparedTransactionContext contains information about a newly created transaction context
 type preparedTransactionContext struct {
 	transaction         *sqlx.Tx
 	passedContext       context.Context
 	isNestedTransaction bool
 	isMockContext       bool
+	// savepoint is the name of the savepoint a nested transaction runs in, if it runs in one
+	savepoint string
+	// hooks collects the functions registered with AfterCommit and AfterRollback in the transaction or savepoint
+	// started by this call, if it started one
+	hooks *transactionHooks
+	// enclosingHooks collects the hooks of the transaction or savepoint enclosing a savepoint started by this call
+	enclosingHooks *transactionHooks
 }
 
 // prepareTransactionContext evaluates the current context to see if a transaction has already been started,
 // starting a new one if there's not already an active transaction. This allows transaction functions to be
-// harmlessly reentrant
-func prepareTransactionContext(parentCtx context.Context) (preparedTransactionContext, error) {
+// harmlessly reentrant. If the options ask for nesting, a savepoint is created inside an active transaction instead.
+// A new transaction is bound to parentCtx, so database/sql rolls it back if the context is cancelled before it commits.
+func prepareTransactionContext(parentCtx context.Context, options TxOptions) (preparedTransactionContext, error) {
 	var preparedTxContext preparedTransactionContext
 	// If we're in a mock context (i.e. testing a controller) setting up transactions is a no-op
 	if testhelper.IsMockContext(parentCtx) {
@@ -148,19 +220,45 @@ func prepareTransactionContext(parentCtx context.Context) (preparedTransactionCo
 		return preparedTxContext, nil
 	}
 
-	switch rawCxn := RetrieveFromContext(parentCtx).(type) {
+	switch rawCxn := retrieveConnection(parentCtx).(type) {
 	case *sqlx.Tx:
 		preparedTxContext.transaction = rawCxn
 		preparedTxContext.isNestedTransaction = true
 		preparedTxContext.passedContext = parentCtx
+		if options.Nested {
+			// Savepoints are named by depth. A savepoint is always released or rolled back before the next one at the
+			// same depth is created, so names never clash.
+			depth, _ := parentCtx.Value(ctxSavepointDepthKey{}).(int)
+			preparedTxContext.savepoint = fmt.Sprintf("nested_tx_%v", depth+1)
+			if _, savepointErr := rawCxn.ExecContext(parentCtx, "SAVEPOINT "+preparedTxContext.savepoint); savepointErr != nil {
+				return preparedTxContext, savepointErr
+			}
+			preparedTxContext.passedContext = context.WithValue(parentCtx, ctxSavepointDepthKey{}, depth+1)
+			preparedTxContext.enclosingHooks, _ = parentCtx.Value(ctxTransactionHooksKey{}).(*transactionHooks)
+			preparedTxContext.hooks = &transactionHooks{}
+			preparedTxContext.passedContext = context.WithValue(preparedTxContext.passedContext, ctxTransactionHooksKey{}, preparedTxContext.hooks)
+		}
 	case *sqlx.DB:
-		newTx, txBeginErr := rawCxn.Beginx()
+		newTx, txBeginErr := rawCxn.BeginTxx(parentCtx, options.sqlOptions())
+		if txBeginErr != nil {
+			return preparedTxContext, txBeginErr
+		}
+
+		preparedTxContext.transaction = newTx
+		preparedTxContext.hooks = &transactionHooks{}
+		preparedTxContext.passedContext = context.WithValue(context.WithValue(parentCtx, ctxTransactionKey{}, newTx),
+			ctxTransactionHooksKey{}, preparedTxContext.hooks)
+	case *clusterConnection:
+		// Transactions may write, so they pin the request to the primary like any other write
+		newTx, txBeginErr := rawCxn.write().BeginTxx(parentCtx, options.sqlOptions())
 		if txBeginErr != nil {
 			return preparedTxContext, txBeginErr
 		}
 
 		preparedTxContext.transaction = newTx
-		preparedTxContext.passedContext = context.WithValue(parentCtx, ctxTransactionKey{}, newTx)
+		preparedTxContext.hooks = &transactionHooks{}
+		preparedTxContext.passedContext = context.WithValue(context.WithValue(parentCtx, ctxTransactionKey{}, newTx),
+			ctxTransactionHooksKey{}, preparedTxContext.hooks)
 	}
 
 	return preparedTxContext, nil
@@ -169,15 +267,27 @@ func prepareTransactionContext(parentCtx context.Context) (preparedTransactionCo
 // rollbackOnFailureOrCommit runs transaction finalization logic if the prepared transaction context is the one which
 // initially started the transaction. It evaluates the error returned from the nested operation, committing the changes
 // if no error was returned or rolling back if there was an error. If there is an error during rollback, the original error
-// is wrapped in such a way that it will still be accessible via errors.Is and errors.As.
-func rollbackOnFailureOrCommit(operationError error, preparedCtx preparedTransactionContext) error {
+// is wrapped in such a way that it will still be accessible via errors.Is and errors.As. A nested transaction running in
+// a savepoint is finalized the same way, by releasing the savepoint or rolling back to it, with the context the nested
+// operation was started with.
+func rollbackOnFailureOrCommit(parentCtx context.Context, operationError error, preparedCtx preparedTransactionContext) error {
 	// Do nothing in a mock context (i.e. testing a controller), transaction setup/teardown is a no-op in that case
 	if preparedCtx.isMockContext {
 		return operationError
 	}
 
 	returnedError := operationError
-	if !preparedCtx.isNestedTransaction {
+	switch {
+	case preparedCtx.savepoint != "":
+		if operationError != nil {
+			_, rollbackErr := preparedCtx.transaction.ExecContext(parentCtx, "ROLLBACK TO SAVEPOINT "+preparedCtx.savepoint)
+			if rollbackErr != nil {
+				returnedError = fmt.Errorf("rollback to savepoint failed when operation returned an error (%w): %w", operationError, rollbackErr)
+			}
+		} else {
+			_, returnedError = preparedCtx.transaction.ExecContext(parentCtx, "RELEASE SAVEPOINT "+preparedCtx.savepoint)
+		}
+	case !preparedCtx.isNestedTransaction:
 		if operationError != nil {
 			rollbackErr := preparedCtx.transaction.Rollback()
 			if rollbackErr != nil {
@@ -197,14 +307,21 @@ func rollbackOnFailureOrCommit(operationError error, preparedCtx preparedTransac
 // The error returned from the passed function will be returned from this function. This function may also return
 // database errors associated with starting or finalizing the transaction. If this occurs on a rollback, the original
 // error will be wrapped and accessible via errors.Is or errors.As.
+//
+// Functions registered with AfterCommit or AfterRollback during the transaction run once it's finalized.
 func WithTransaction(ctx context.Context, operation func(ctx context.Context) error) error {
-	preparedCtx, prepareErr := prepareTransactionContext(ctx)
-	if prepareErr != nil {
-		return prepareErr
-	}
+	return WithTransactionOptions(ctx, TxOptions{}, operation)
+}
 
-	operationError := operation(preparedCtx.passedContext)
-	return rollbackOnFailureOrCommit(operationError, preparedCtx)
+// WithTransactionOptions is like WithTransaction, except the transaction is configured by the passed options. With
+// TxOptions.Nested set, calling it inside another transaction runs the passed function in a savepoint, which is rolled
+// back on its own if the function returns an error. With TxOptions.Retry set, the passed function is run again in a new
+// transaction if the transaction deadlocks.
+func WithTransactionOptions(ctx context.Context, options TxOptions, operation func(ctx context.Context) error) error {
+	_, txErr := WithTransactionReturningOptions(ctx, options, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, operation(ctx)
+	})
+	return txErr
 }
 
 // WithTransactionReturning initiates a database transaction which finalizes after the passed function is executed. By "finalizes", this
@@ -215,12 +332,42 @@ func WithTransaction(ctx context.Context, operation func(ctx context.Context) er
 // database errors associated with starting or finalizing the transaction. If this occurs on a rollback, the original
 // error will be wrapped and accessible via errors.Is or errors.As.
 func WithTransactionReturning[ReturnValue any](ctx context.Context, operation func(ctx context.Context) (ReturnValue, error)) (ReturnValue, error) {
-	preparedCtx, prepareErr := prepareTransactionContext(ctx)
+	return WithTransactionReturningOptions(ctx, TxOptions{}, operation)
+}
+
+// WithTransactionReturningOptions is like WithTransactionReturning, except the transaction is configured by the passed
+// options. See WithTransactionOptions for more information.
+func WithTransactionReturningOptions[ReturnValue any](ctx context.Context, options TxOptions, operation func(ctx context.Context) (ReturnValue, error)) (ReturnValue, error) {
+	for attempt := 1; ; attempt++ {
+		returnValue, startedTransaction, txErr := runTransaction(ctx, options, operation)
+		if !startedTransaction || attempt >= options.Retry.MaxAttempts || !IsRetryable(txErr) {
+			return returnValue, txErr
+		}
+
+		delay := options.Retry.delay(attempt)
+		transactionRetries.Add(1)
+		logger.Log.Warn("Transaction failed because of contention with another transaction, retrying", zap.Int("attempt", attempt),
+			zap.Duration("delay", delay), zap.Error(txErr))
+		select {
+		case <-ctx.Done():
+			return returnValue, fmt.Errorf("gave up retrying transaction (%w): %w", txErr, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// runTransaction runs the passed function in a transaction once, also reporting whether the transaction was started
+// by this call rather than being joined
+func runTransaction[ReturnValue any](ctx context.Context, options TxOptions, operation func(ctx context.Context) (ReturnValue, error)) (ReturnValue, bool, error) {
+	preparedCtx, prepareErr := prepareTransactionContext(ctx, options)
 	if prepareErr != nil {
 		var zeroValue ReturnValue
-		return zeroValue, prepareErr
+		return zeroValue, false, prepareErr
 	}
 
 	returnValue, operationErr := operation(preparedCtx.passedContext)
-	return returnValue, rollbackOnFailureOrCommit(operationErr, preparedCtx)
+	startedTransaction := !preparedCtx.isMockContext && !preparedCtx.isNestedTransaction
+	txErr := rollbackOnFailureOrCommit(ctx, operationErr, preparedCtx)
+	preparedCtx.finishHooks(ctx, txErr == nil)
+	return returnValue, startedTransaction, txErr
 }
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TransactionSuite struct {
+	suite.Suite
+	recording *recording
+	dbContext context.Context
+}
+
+func TestTransactionSuite(t *testing.T) {
+	suite.Run(t, new(TransactionSuite))
+}
+
+func (suite *TransactionSuite) SetupTest() {
+	db, rec := openRecording(suite.T())
+	suite.recording = rec
+	suite.dbContext = CreateDerivativeContext(context.Background(), db)
+}
+
+// insert runs an insert statement on the connection in the passed context
+func (suite *TransactionSuite) insert(ctx context.Context, greeting string) error {
+	_, insertErr := RetrieveFromContext(ctx).Exec("INSERT INTO greetings (greetingText) VALUES (?)", greeting)
+	return insertErr
+}
+
+func (suite *TransactionSuite) TestCommitsOnSuccess() {
+	txErr := WithTransaction(suite.dbContext, func(ctx context.Context) error {
+		return suite.insert(ctx, "G'day")
+	})
+
+	suite.Require().NoError(txErr)
+	suite.Assert().Equal([]string{"BEGIN", "INSERT INTO greetings (greetingText) VALUES (?) [G'day]", "COMMIT"},
+		suite.recording.Statements())
+}
+
+func (suite *TransactionSuite) TestRollsBackOnFailure() {
+	expectedErr := errors.New("oops")
+	txErr := WithTransaction(suite.dbContext, func(ctx context.Context) error {
+		return expectedErr
+	})
+
+	suite.Assert().ErrorIs(txErr, expectedErr)
+	suite.Assert().Equal([]string{"BEGIN", "ROLLBACK"}, suite.recording.Statements())
+}
+
+func (suite *TransactionSuite) TestOptions() {
+	options := TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}
+	count, txErr := WithTransactionReturningOptions(suite.dbContext, options, func(ctx context.Context) (int, error) {
+		return 3, nil
+	})
+
+	suite.Require().NoError(txErr)
+	suite.Assert().Equal(3, count)
+	suite.Assert().Equal([]string{"BEGIN Serializable READ ONLY", "COMMIT"}, suite.recording.Statements())
+}
+
+func (suite *TransactionSuite) TestNestedCallsJoinTheOuterTransaction() {
+	txErr := WithTransaction(suite.dbContext, func(ctx context.Context) error {
+		_ = WithTransaction(ctx, func(ctx context.Context) error {
+			return errors.New("ignored by the outer operation")
+		})
+		return nil
+	})
+
+	suite.Require().NoError(txErr)
+	suite.Assert().Equal([]string{"BEGIN", "COMMIT"}, suite.recording.Statements())
+}
+
+func (suite *TransactionSuite) TestNestedSavepoints() {
+	nested := TxOptions{Nested: true}
+	txErr := WithTransaction(suite.dbContext, func(ctx context.Context) error {
+		innerErr := WithTransactionOptions(ctx, nested, func(ctx context.Context) error {
+			if insertErr := suite.insert(ctx, "Hello"); insertErr != nil {
+				return insertErr
+			}
+			return WithTransactionOptions(ctx, nested, func(ctx context.Context) error {
+				return suite.insert(ctx, "Hi")
+			})
+		})
+		suite.Require().NoError(innerErr)
+
+		failedErr := WithTransactionOptions(ctx, nested, func(ctx context.Context) error {
+			return errors.New("only this part is rolled back")
+		})
+		suite.Require().Error(failedErr)
+
+		return suite.insert(ctx, "G'day")
+	})
+
+	suite.Require().NoError(txErr)
+	suite.Assert().Equal([]string{
+		"BEGIN",
+		"SAVEPOINT nested_tx_1",
+		"INSERT INTO greetings (greetingText) VALUES (?) [Hello]",
+		"SAVEPOINT nested_tx_2",
+		"INSERT INTO greetings (greetingText) VALUES (?) [Hi]",
+		"RELEASE SAVEPOINT nested_tx_2",
+		"RELEASE SAVEPOINT nested_tx_1",
+		"SAVEPOINT nested_tx_1",
+		"ROLLBACK TO SAVEPOINT nested_tx_1",
+		"INSERT INTO greetings (greetingText) VALUES (?) [G'day]",
+		"COMMIT",
+	}, suite.recording.Statements())
+}
+
+func (suite *TransactionSuite) TestNestedOptionStartsTransactionOutsideOne() {
+	txErr := WithTransactionOptions(suite.dbContext, TxOptions{Nested: true}, func(ctx context.Context) error {
+		return nil
+	})
+
+	suite.Require().NoError(txErr)
+	suite.Assert().Equal([]string{"BEGIN", "COMMIT"}, suite.recording.Statements())
+}
+
+func (suite *TransactionSuite) TestCancelledContextsDoNotBeginTransactions() {
+	ctx, cancel := context.WithCancel(suite.dbContext)
+	cancel()
+
+	txErr := WithTransaction(ctx, func(ctx context.Context) error {
+		return nil
+	})
+
+	suite.Assert().ErrorIs(txErr, context.Canceled)
+	suite.Assert().Empty(suite.recording.Statements())
+}
+
+func (suite *TransactionSuite) TestCancelledContextsDoNotCreateSavepoints() {
+	_ = WithTransaction(suite.dbContext, func(ctx context.Context) error {
+		nestedCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		nestedErr := WithTransactionOptions(nestedCtx, TxOptions{Nested: true}, func(ctx context.Context) error {
+			return nil
+		})
+
+		suite.Assert().ErrorIs(nestedErr, context.Canceled)
+		return nil
+	})
+
+	suite.Assert().Equal([]string{"BEGIN", "COMMIT"}, suite.recording.Statements())
+}
+
+func (suite *TransactionSuite) TestSavepointRollbackFailureKeepsOperationError() {
+	expectedErr := errors.New("oops")
+	rollbackErr := errors.New("connection lost")
+	suite.recording.FailNext("ROLLBACK TO SAVEPOINT", rollbackErr)
+
+	_ = WithTransaction(suite.dbContext, func(ctx context.Context) error {
+		nestedErr := WithTransactionOptions(ctx, TxOptions{Nested: true}, func(ctx context.Context) error {
+			return expectedErr
+		})
+
+		suite.Assert().ErrorIs(nestedErr, expectedErr)
+		suite.Assert().ErrorIs(nestedErr, rollbackErr)
+		return nestedErr
+	})
+}
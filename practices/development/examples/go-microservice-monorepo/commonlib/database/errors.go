@@ -0,0 +1,156 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	// ErrDuplicateKey means a statement would have duplicated the value of a primary key or unique index. The *Error
+	// returned by ClassifyError names the violated constraint.
+	ErrDuplicateKey = errors.New("duplicate key")
+	// ErrForeignKeyViolation means a statement referenced a row which doesn't exist, or removed a row which is still
+	// referenced
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	// ErrDeadlock means the statement's transaction deadlocked with another one and was rolled back
+	ErrDeadlock = errors.New("deadlock")
+	// ErrLockTimeout means the statement gave up waiting for a lock held by another transaction
+	ErrLockTimeout = errors.New("lock timeout")
+	// ErrConnectionLost means the connection to the database broke, so the statement's outcome may be unknown
+	ErrConnectionLost = errors.New("database connection lost")
+	// ErrDataTooLong means a value didn't fit in its column
+	ErrDataTooLong = errors.New("data too long for column")
+)
+
+// Error is a driver error classified by ClassifyError. errors.Is matches it against its Kind, and errors.As can still
+// extract the driver's error, such as a *mysql.MySQLError.
+type Error struct {
+	// Kind is the sentinel the error was classified as, such as ErrDuplicateKey
+	Kind error
+	// Constraint is the name of the violated unique index or constraint, if the database reported it
+	Constraint string
+	// Err is the original error returned by the driver
+	Err error
+}
+
+// Error implements error for Error
+func (err *Error) Error() string {
+	if err.Constraint != "" {
+		return fmt.Sprintf("%v on %v: %v", err.Kind, err.Constraint, err.Err)
+	}
+	return fmt.Sprintf("%v: %v", err.Kind, err.Err)
+}
+
+// Unwrap returns both the Kind and the original error, so errors.Is and errors.As find either
+func (err *Error) Unwrap() []error {
+	return []error{err.Kind, err.Err}
+}
+
+// ClassifyError translates an error returned by the database driver into an *Error wrapping it, so callers can check
+// for the sentinels in this package with errors.Is rather than the error codes of each database. Errors which don't
+// match a sentinel, or are already classified, are returned unchanged, as is nil.
+//
+//	if errors.Is(database.ClassifyError(insertErr), database.ErrDuplicateKey) {
+//		return ErrAlreadyExists
+//	}
+func ClassifyError(err error) error {
+	var classifiedErr *Error
+	if err == nil || errors.As(err, &classifiedErr) {
+		return err
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return classifyMySQLError(err, mysqlErr)
+	}
+
+	var postgresErr sqlStateError
+	if errors.As(err, &postgresErr) {
+		return classifyPostgresError(err, postgresErr)
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return &Error{Kind: ErrConnectionLost, Err: err}
+	}
+	return err
+}
+
+// classifyMySQLError classifies err by the code of the MySQL error it wraps
+func classifyMySQLError(err error, mysqlErr *mysql.MySQLError) error {
+	switch mysqlErr.Number {
+	// ER_DUP_ENTRY and ER_DUP_ENTRY_WITH_KEY_NAME
+	case 1062, 1586:
+		return &Error{Kind: ErrDuplicateKey, Constraint: mysqlDuplicateKeyName(mysqlErr.Message), Err: err}
+	// ER_NO_REFERENCED_ROW, ER_ROW_IS_REFERENCED, ER_ROW_IS_REFERENCED_2 and ER_NO_REFERENCED_ROW_2
+	case 1216, 1217, 1451, 1452:
+		return &Error{Kind: ErrForeignKeyViolation, Constraint: quotedName(mysqlErr.Message, "CONSTRAINT `", "`"), Err: err}
+	// ER_LOCK_DEADLOCK
+	case 1213:
+		return &Error{Kind: ErrDeadlock, Err: err}
+	// ER_LOCK_WAIT_TIMEOUT
+	case 1205:
+		return &Error{Kind: ErrLockTimeout, Err: err}
+	// ER_DATA_TOO_LONG
+	case 1406:
+		return &Error{Kind: ErrDataTooLong, Err: err}
+	// ER_SERVER_SHUTDOWN
+	case 1053:
+		return &Error{Kind: ErrConnectionLost, Err: err}
+	default:
+		return err
+	}
+}
+
+// classifyPostgresError classifies err by the SQLSTATE of the Postgres error it wraps
+func classifyPostgresError(err error, postgresErr sqlStateError) error {
+	state := postgresErr.SQLState()
+	switch {
+	// unique_violation
+	case state == "23505":
+		return &Error{Kind: ErrDuplicateKey, Constraint: quotedName(postgresErr.Error(), `constraint "`, `"`), Err: err}
+	// foreign_key_violation
+	case state == "23503":
+		return &Error{Kind: ErrForeignKeyViolation, Constraint: quotedName(postgresErr.Error(), `constraint "`, `"`), Err: err}
+	// deadlock_detected
+	case state == "40P01":
+		return &Error{Kind: ErrDeadlock, Err: err}
+	// lock_not_available
+	case state == "55P03":
+		return &Error{Kind: ErrLockTimeout, Err: err}
+	// string_data_right_truncation
+	case state == "22001":
+		return &Error{Kind: ErrDataTooLong, Err: err}
+	// Class 08 is a connection exception, and 57P01 and 57P02 mean the server is shutting down
+	case strings.HasPrefix(state, "08"), state == "57P01", state == "57P02":
+		return &Error{Kind: ErrConnectionLost, Err: err}
+	default:
+		return err
+	}
+}
+
+// mysqlDuplicateKeyName extracts the key name from a message like "Duplicate entry 'Hi' for key 'greetings.name'".
+// MySQL 8 prefixes the key with its table, which is removed so the name matches the one in the migration.
+func mysqlDuplicateKeyName(message string) string {
+	keyName := quotedName(message, "for key '", "'")
+	if tableEnd := strings.LastIndex(keyName, "."); tableEnd >= 0 {
+		keyName = keyName[tableEnd+1:]
+	}
+	return keyName
+}
+
+// quotedName returns the text between the passed opening quote and the closing quote after it, or an empty string if
+// the message doesn't contain them
+func quotedName(message string, openQuote string, closeQuote string) string {
+	_, afterOpen, found := strings.Cut(message, openQuote)
+	if !found {
+		return ""
+	}
+	if nameEnd := strings.Index(afterOpen, closeQuote); nameEnd >= 0 {
+		return afterOpen[:nameEnd]
+	}
+	return ""
+}
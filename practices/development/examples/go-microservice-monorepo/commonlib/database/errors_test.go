@@ -0,0 +1,140 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/suite"
+)
+
+type ErrorsSuite struct {
+	suite.Suite
+}
+
+func TestErrorsSuite(t *testing.T) {
+	suite.Run(t, new(ErrorsSuite))
+}
+
+func (suite *ErrorsSuite) TestClassifyError() {
+	subtests := []struct {
+		testName   string
+		err        error
+		kind       error
+		constraint string
+	}{
+		{
+			testName:   "MySQL duplicate entries",
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'Hi' for key 'greetings.greetings_greetingText_unique'"},
+			kind:       ErrDuplicateKey,
+			constraint: "greetings_greetingText_unique",
+		},
+		{
+			testName:   "MySQL duplicate entries before MySQL 8",
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"},
+			kind:       ErrDuplicateKey,
+			constraint: "PRIMARY",
+		},
+		{
+			testName: "MySQL foreign key violations",
+			err: &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails " +
+				"(`test`.`replies`, CONSTRAINT `replies_greeting_fk` FOREIGN KEY (`greetingId`) REFERENCES `greetings` (`id`))"},
+			kind:       ErrForeignKeyViolation,
+			constraint: "replies_greeting_fk",
+		},
+		{
+			testName: "MySQL deadlocks",
+			err:      &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"},
+			kind:     ErrDeadlock,
+		},
+		{
+			testName: "MySQL lock wait timeouts",
+			err:      &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"},
+			kind:     ErrLockTimeout,
+		},
+		{
+			testName: "MySQL data too long",
+			err:      &mysql.MySQLError{Number: 1406, Message: "Data too long for column 'greetingText' at row 1"},
+			kind:     ErrDataTooLong,
+		},
+		{
+			testName: "MySQL invalid connections",
+			err:      mysql.ErrInvalidConn,
+			kind:     ErrConnectionLost,
+		},
+		{
+			testName: "Bad connections",
+			err:      fmt.Errorf("querying: %w", driver.ErrBadConn),
+			kind:     ErrConnectionLost,
+		},
+		{
+			testName:   "Postgres unique violations",
+			err:        postgresUniqueViolation{},
+			kind:       ErrDuplicateKey,
+			constraint: "greetings_greetingText_unique",
+		},
+		{
+			testName: "Postgres deadlocks",
+			err:      postgresError{state: "40P01"},
+			kind:     ErrDeadlock,
+		},
+		{
+			testName: "Postgres lock timeouts",
+			err:      postgresError{state: "55P03"},
+			kind:     ErrLockTimeout,
+		},
+		{
+			testName: "Postgres string truncation",
+			err:      postgresError{state: "22001"},
+			kind:     ErrDataTooLong,
+		},
+		{
+			testName: "Postgres connection failures",
+			err:      postgresError{state: "08006"},
+			kind:     ErrConnectionLost,
+		},
+	}
+
+	for _, subtest := range subtests {
+		suite.Run(subtest.testName, func() {
+			wrappedErr := fmt.Errorf("running statement: %w", subtest.err)
+			classifiedErr := ClassifyError(wrappedErr)
+
+			suite.Assert().ErrorIs(classifiedErr, subtest.kind)
+			suite.Assert().ErrorIs(classifiedErr, subtest.err, "The original error is still wrapped")
+			var dbErr *Error
+			suite.Require().ErrorAs(classifiedErr, &dbErr)
+			suite.Assert().Equal(subtest.constraint, dbErr.Constraint)
+		})
+	}
+}
+
+func (suite *ErrorsSuite) TestClassifyErrorKeepsOtherErrors() {
+	otherErr := errors.New("something else")
+	syntaxErr := &mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"}
+
+	suite.Assert().NoError(ClassifyError(nil))
+	suite.Assert().Same(otherErr, ClassifyError(otherErr))
+	suite.Assert().Same(syntaxErr, ClassifyError(syntaxErr))
+	suite.Assert().Equal(postgresError{state: "42601"}, ClassifyError(postgresError{state: "42601"}))
+}
+
+func (suite *ErrorsSuite) TestClassifyErrorIsIdempotent() {
+	classifiedErr := ClassifyError(&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"})
+	wrappedErr := fmt.Errorf("in transaction: %w", classifiedErr)
+
+	suite.Assert().Same(wrappedErr, ClassifyError(wrappedErr))
+}
+
+// postgresUniqueViolation mimics the error a Postgres driver returns for a unique violation
+type postgresUniqueViolation struct{}
+
+func (postgresUniqueViolation) Error() string {
+	return `ERROR: duplicate key value violates unique constraint "greetings_greetingText_unique" (SQLSTATE 23505)`
+}
+
+func (postgresUniqueViolation) SQLState() string {
+	return "23505"
+}
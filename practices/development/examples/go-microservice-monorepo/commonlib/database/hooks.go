@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"sync"
+)
+
+// ctxTransactionHooksKey is where the hooks of the active transaction, or of the savepoint the current operation runs
+// in, are stored in a transaction context
+type ctxTransactionHooksKey struct{}
+
+// ctxMockHooksKey is where the hooks registered in a mock context are recorded
+type ctxMockHooksKey struct{}
+
+// AfterCommit registers a function to run once the outermost transaction in the context, as started by WithTransaction
+// or WithTransactionReturning, commits. Use it for side effects which mustn't happen unless the transaction's changes
+// do, such as invalidating a cache, publishing an event or sending a webhook. It's passed the context the outermost
+// transaction was started with, which has no transaction in it.
+//
+// Hooks registered in a nested transaction's savepoint are dropped if the savepoint is rolled back. Outside a
+// transaction, fn runs immediately. In a context derived with CreateDerivativeMockContext, fn is recorded in the
+// context's MockTransactionHooks instead.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if txHooks, inTransaction := ctx.Value(ctxTransactionHooksKey{}).(*transactionHooks); inTransaction {
+		txHooks.add(fn, nil)
+	} else if mockHooks := MockHooksFromContext(ctx); mockHooks != nil {
+		mockHooks.hooks.add(fn, nil)
+	} else {
+		fn(ctx)
+	}
+}
+
+// AfterRollback registers a function to run once the outermost transaction in the context, as started by
+// WithTransaction or WithTransactionReturning, is rolled back or fails to commit. Use it to undo side effects which
+// happened outside the database during the transaction. It's passed the context the outermost transaction was started
+// with, which has no transaction in it.
+//
+// Hooks registered in a nested transaction's savepoint also run when the outermost transaction commits if the
+// savepoint was rolled back, since its changes were undone either way. Outside a transaction, fn runs immediately, like
+// with AfterCommit. In a context derived with CreateDerivativeMockContext, fn is recorded in the context's
+// MockTransactionHooks instead.
+func AfterRollback(ctx context.Context, fn func(ctx context.Context)) {
+	if txHooks, inTransaction := ctx.Value(ctxTransactionHooksKey{}).(*transactionHooks); inTransaction {
+		txHooks.add(nil, fn)
+	} else if mockHooks := MockHooksFromContext(ctx); mockHooks != nil {
+		mockHooks.hooks.add(nil, fn)
+	} else {
+		fn(ctx)
+	}
+}
+
+// transactionHooks collects the functions registered with AfterCommit and AfterRollback for a transaction or savepoint
+type transactionHooks struct {
+	lock          sync.Mutex
+	afterCommit   []func(ctx context.Context)
+	afterRollback []func(ctx context.Context)
+}
+
+// add registers either hook, or both, skipping nil ones
+func (hooks *transactionHooks) add(afterCommit func(ctx context.Context), afterRollback func(ctx context.Context)) {
+	hooks.lock.Lock()
+	defer hooks.lock.Unlock()
+
+	if afterCommit != nil {
+		hooks.afterCommit = append(hooks.afterCommit, afterCommit)
+	}
+	if afterRollback != nil {
+		hooks.afterRollback = append(hooks.afterRollback, afterRollback)
+	}
+}
+
+// take removes and returns every registered hook
+func (hooks *transactionHooks) take() ([]func(ctx context.Context), []func(ctx context.Context)) {
+	hooks.lock.Lock()
+	defer hooks.lock.Unlock()
+
+	afterCommit, afterRollback := hooks.afterCommit, hooks.afterRollback
+	hooks.afterCommit, hooks.afterRollback = nil, nil
+	return afterCommit, afterRollback
+}
+
+// mergeInto hands the hooks of a finished savepoint over to the transaction or savepoint enclosing it. If the savepoint
+// was rolled back, its after-commit hooks are dropped and its after-rollback hooks run however the enclosing one ends.
+func (hooks *transactionHooks) mergeInto(enclosing *transactionHooks, rolledBack bool) {
+	afterCommit, afterRollback := hooks.take()
+
+	enclosing.lock.Lock()
+	defer enclosing.lock.Unlock()
+	if rolledBack {
+		enclosing.afterCommit = append(enclosing.afterCommit, afterRollback...)
+	} else {
+		enclosing.afterCommit = append(enclosing.afterCommit, afterCommit...)
+	}
+	enclosing.afterRollback = append(enclosing.afterRollback, afterRollback...)
+}
+
+// finishHooks hands the hooks registered during an operation run by WithTransaction over to the enclosing savepoint or
+// transaction, or runs them if the operation started the transaction. parentCtx is the context the operation was
+// started with.
+func (preparedCtx preparedTransactionContext) finishHooks(parentCtx context.Context, succeeded bool) {
+	switch {
+	case preparedCtx.savepoint != "" && preparedCtx.enclosingHooks != nil:
+		preparedCtx.hooks.mergeInto(preparedCtx.enclosingHooks, !succeeded)
+	case preparedCtx.hooks != nil && !preparedCtx.isNestedTransaction:
+		preparedCtx.hooks.run(parentCtx, succeeded)
+	}
+}
+
+// run runs the after-commit hooks if the transaction committed, or the after-rollback hooks otherwise
+func (hooks *transactionHooks) run(ctx context.Context, committed bool) {
+	afterCommit, afterRollback := hooks.take()
+	toRun := afterRollback
+	if committed {
+		toRun = afterCommit
+	}
+
+	for _, hook := range toRun {
+		hook(ctx)
+	}
+}
+
+// MockTransactionHooks records the functions registered with AfterCommit and AfterRollback in a mock context rather
+// than running them, so tests can check they were registered and run them as if the transaction finished
+type MockTransactionHooks struct {
+	hooks transactionHooks
+}
+
+// MockHooksFromContext returns the hooks recorded in a context derived with CreateDerivativeMockContext, or nil if it
+// wasn't derived with it
+func MockHooksFromContext(ctx context.Context) *MockTransactionHooks {
+	mockHooks, _ := ctx.Value(ctxMockHooksKey{}).(*MockTransactionHooks)
+	return mockHooks
+}
+
+// AfterCommitCount returns the number of functions registered with AfterCommit which haven't been run yet
+func (mockHooks *MockTransactionHooks) AfterCommitCount() int {
+	mockHooks.hooks.lock.Lock()
+	defer mockHooks.hooks.lock.Unlock()
+
+	return len(mockHooks.hooks.afterCommit)
+}
+
+// AfterRollbackCount returns the number of functions registered with AfterRollback which haven't been run yet
+func (mockHooks *MockTransactionHooks) AfterRollbackCount() int {
+	mockHooks.hooks.lock.Lock()
+	defer mockHooks.hooks.lock.Unlock()
+
+	return len(mockHooks.hooks.afterRollback)
+}
+
+// RunAfterCommit runs the functions registered with AfterCommit, as if the transaction committed, and forgets every
+// recorded function
+func (mockHooks *MockTransactionHooks) RunAfterCommit(ctx context.Context) {
+	mockHooks.hooks.run(ctx, true)
+}
+
+// RunAfterRollback runs the functions registered with AfterRollback, as if the transaction was rolled back, and forgets
+// every recorded function
+func (mockHooks *MockTransactionHooks) RunAfterRollback(ctx context.Context) {
+	mockHooks.hooks.run(ctx, false)
+}
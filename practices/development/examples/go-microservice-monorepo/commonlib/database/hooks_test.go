@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"example.com/sample/commonlib/logger"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap/zapcore"
+)
+
+type HooksSuite struct {
+	suite.Suite
+	recording *recording
+	dbContext context.Context
+	ran       []string
+}
+
+func TestHooksSuite(t *testing.T) {
+	suite.Run(t, new(HooksSuite))
+}
+
+func (suite *HooksSuite) SetupSuite() {
+	setupErr := logger.InitLogger(zapcore.InfoLevel, false)
+	suite.Require().NoError(setupErr)
+}
+
+func (suite *HooksSuite) SetupTest() {
+	db, rec := openRecording(suite.T())
+	suite.recording = rec
+	suite.dbContext = CreateDerivativeContext(context.Background(), db)
+	suite.ran = nil
+}
+
+// hook returns a hook recording that it ran under the passed name, and that it was passed a context without a
+// transaction
+func (suite *HooksSuite) hook(name string) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		suite.Assert().Nil(ctx.Value(ctxTransactionKey{}), "Hooks run outside the transaction")
+		suite.ran = append(suite.ran, name)
+	}
+}
+
+// register registers an after-commit and an after-rollback hook under the passed name
+func (suite *HooksSuite) register(ctx context.Context, name string) {
+	AfterCommit(ctx, suite.hook(name+" committed"))
+	AfterRollback(ctx, suite.hook(name+" rolled back"))
+}
+
+func (suite *HooksSuite) TestRunsAfterCommit() {
+	txErr := WithTransaction(suite.dbContext, func(ctx context.Context) error {
+		suite.register(ctx, "outer")
+		return WithTransaction(ctx, func(ctx context.Context) error {
+			suite.register(ctx, "joined")
+			suite.Assert().Empty(suite.ran, "Hooks wait for the outermost transaction")
+			return nil
+		})
+	})
+
+	suite.Require().NoError(txErr)
+	suite.Assert().Equal([]string{"outer committed", "joined committed"}, suite.ran)
+}
+
+func (suite *HooksSuite) TestRunsAfterRollback() {
+	expectedErr := errors.New("oops")
+	txErr := WithTransaction(suite.dbContext, func(ctx context.Context) error {
+		suite.register(ctx, "outer")
+		return expectedErr
+	})
+
+	suite.Assert().ErrorIs(txErr, expectedErr)
+	suite.Assert().Equal([]string{"outer rolled back"}, suite.ran)
+}
+
+func (suite *HooksSuite) TestRunsAfterRollbackWhenCommitFails() {
+	commitErr := errors.New("connection reset")
+	suite.recording.FailNext("COMMIT", commitErr)
+	txErr := WithTransaction(suite.dbContext, func(ctx context.Context) error {
+		suite.register(ctx, "outer")
+		return nil
+	})
+
+	suite.Assert().ErrorIs(txErr, commitErr)
+	suite.Assert().Equal([]string{"outer rolled back"}, suite.ran)
+}
+
+func (suite *HooksSuite) TestSavepoints() {
+	nested := TxOptions{Nested: true}
+	txErr := WithTransaction(suite.dbContext, func(ctx context.Context) error {
+		_ = WithTransactionOptions(ctx, nested, func(ctx context.Context) error {
+			suite.register(ctx, "failed savepoint")
+			return errors.New("oops")
+		})
+		return WithTransactionOptions(ctx, nested, func(ctx context.Context) error {
+			suite.register(ctx, "released savepoint")
+			return nil
+		})
+	})
+
+	suite.Require().NoError(txErr)
+	suite.Assert().Equal([]string{"failed savepoint rolled back", "released savepoint committed"}, suite.ran)
+}
+
+func (suite *HooksSuite) TestEachRetryHasItsOwnHooks() {
+	suite.recording.FailNext("INSERT", deadlockErr)
+	attempt := 0
+	txErr := WithTransactionOptions(suite.dbContext, TxOptions{Retry: RetryPolicy{MaxAttempts: 2}}, func(ctx context.Context) error {
+		attempt++
+		if attempt == 1 {
+			suite.register(ctx, "first attempt")
+		} else {
+			suite.register(ctx, "second attempt")
+		}
+		_, insertErr := RetrieveFromContext(ctx).Exec("INSERT INTO greetings (greetingText) VALUES (?)", "G'day")
+		return insertErr
+	})
+
+	suite.Require().NoError(txErr)
+	suite.Assert().Equal([]string{"first attempt rolled back", "second attempt committed"}, suite.ran)
+}
+
+func (suite *HooksSuite) TestRunsImmediatelyOutsideTransactions() {
+	suite.register(suite.dbContext, "no transaction")
+
+	suite.Assert().Equal([]string{"no transaction committed", "no transaction rolled back"}, suite.ran)
+}
+
+func (suite *HooksSuite) TestRecordsHooksInMockContexts() {
+	mockCtx := CreateDerivativeMockContext(context.Background(), NewMockConnection(gomock.NewController(suite.T())))
+	suite.register(mockCtx, "mock")
+	suite.register(mockCtx, "another mock")
+
+	mockHooks := MockHooksFromContext(mockCtx)
+	suite.Require().NotNil(mockHooks)
+	suite.Assert().Empty(suite.ran)
+	suite.Assert().Equal(2, mockHooks.AfterCommitCount())
+	suite.Assert().Equal(2, mockHooks.AfterRollbackCount())
+
+	mockHooks.RunAfterCommit(context.Background())
+	suite.Assert().Equal([]string{"mock committed", "another mock committed"}, suite.ran)
+	suite.Assert().Zero(mockHooks.AfterCommitCount())
+	suite.Assert().Zero(mockHooks.AfterRollbackCount())
+	suite.Assert().Nil(MockHooksFromContext(suite.dbContext))
+}
@@ -0,0 +1,282 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"time"
+
+	"example.com/sample/commonlib/logger"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// ctxInstrumentationKey is where the Instrumentation applied to connections is stored in a database context
+type ctxInstrumentationKey struct{}
+
+// QueryEvent describes a statement run through a Connection retrieved from an instrumented context
+type QueryEvent struct {
+	// Method is the name of the Connection method which ran the statement, such as Select or ExecContext
+	Method string
+	// Query is the statement which was run. Its arguments are left out, since they may hold personal data.
+	Query string
+	// Start is when the statement started running
+	Start time.Time
+	// Duration is how long the statement took. For methods returning rows to iterate over, such as Queryx, it only
+	// covers the time taken for the database to respond, and for prepared statements, only preparing them.
+	Duration time.Duration
+	// Rows is the number of rows affected by an Exec, or returned by a Get or a Select. It's -1 for methods which
+	// don't know, such as Queryx.
+	Rows int64
+	// InTransaction is true if the statement ran in a transaction
+	InTransaction bool
+	// Err is the error the statement failed with, if it did
+	Err error
+}
+
+// QueryHook is called with every statement run through a Connection retrieved from an instrumented context, once the
+// statement finishes. The context is the one the Connection was retrieved from, so hooks can tell which request ran
+// the statement, such as to attach a tracing span.
+type QueryHook func(ctx context.Context, event QueryEvent)
+
+// Instrumentation observes the statements run through Connections retrieved by RetrieveFromContext, logging slow ones
+// and passing every one to hooks for metrics and tracing. Attach it to a context with WithInstrumentation, or set it on
+// a Cluster to attach it to every request's context.
+type Instrumentation struct {
+	// SlowQueryThreshold is how long a statement may take before it's logged as a warning. Zero disables the log.
+	SlowQueryThreshold time.Duration
+	// Hooks are called with every statement. Add them before the Instrumentation is first used, since they're read
+	// without synchronization.
+	Hooks []QueryHook
+}
+
+// NewInstrumentation constructs an Instrumentation logging statements which take longer than the passed threshold,
+// and calling the passed hooks with every statement
+func NewInstrumentation(slowQueryThreshold time.Duration, hooks ...QueryHook) *Instrumentation {
+	return &Instrumentation{
+		SlowQueryThreshold: slowQueryThreshold,
+		Hooks:              hooks,
+	}
+}
+
+// WithInstrumentation derives a context whose Connections, as retrieved by RetrieveFromContext, are observed by the
+// passed Instrumentation
+func WithInstrumentation(ctx context.Context, instrumentation *Instrumentation) context.Context {
+	return context.WithValue(ctx, ctxInstrumentationKey{}, instrumentation)
+}
+
+// observe reports a finished statement to the slow query log and the hooks
+func (instrumentation *Instrumentation) observe(ctx context.Context, event QueryEvent) {
+	if instrumentation.SlowQueryThreshold > 0 && event.Duration >= instrumentation.SlowQueryThreshold {
+		fields := []zap.Field{zap.String("query", event.Query), zap.String("method", event.Method),
+			zap.Duration("duration", event.Duration), zap.Int64("rows", event.Rows), zap.Bool("inTransaction", event.InTransaction)}
+		if event.Err != nil {
+			fields = append(fields, zap.Error(event.Err))
+		}
+		logger.Log.Warn("Slow database query", fields...)
+	}
+
+	for _, hook := range instrumentation.Hooks {
+		hook(ctx, event)
+	}
+}
+
+// instrumentedConnection implements Connection by passing every call to another Connection and reporting it to an
+// Instrumentation
+type instrumentedConnection struct {
+	inner           Connection
+	ctx             context.Context
+	instrumentation *Instrumentation
+	inTransaction   bool
+}
+
+// instrument wraps a connection so its statements are reported to the context's Instrumentation, if it has one
+func instrument(ctx context.Context, connection Connection) Connection {
+	instrumentation, isInstrumented := ctx.Value(ctxInstrumentationKey{}).(*Instrumentation)
+	if !isInstrumented {
+		return connection
+	}
+
+	// Connections bound to the context wrap the transaction
+	unbound := connection
+	if boundCxn, isBound := connection.(*boundConnection); isBound {
+		unbound = boundCxn.inner
+	}
+	_, inTransaction := unbound.(*sqlx.Tx)
+	return &instrumentedConnection{
+		inner:           connection,
+		ctx:             ctx,
+		instrumentation: instrumentation,
+		inTransaction:   inTransaction,
+	}
+}
+
+// observe reports a statement which started at the passed time and has just finished
+func (cxn *instrumentedConnection) observe(method string, query string, start time.Time, rows int64, err error) {
+	cxn.instrumentation.observe(cxn.ctx, QueryEvent{
+		Method:        method,
+		Query:         query,
+		Start:         start,
+		Duration:      time.Since(start),
+		Rows:          rows,
+		InTransaction: cxn.inTransaction,
+		Err:           err,
+	})
+}
+
+// rowsFetched counts the rows fetched by a Get
+func rowsFetched(err error) int64 {
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0
+	} else if err != nil {
+		return -1
+	}
+	return 1
+}
+
+// rowsSelected counts the rows fetched by a Select into the slice pointed to by dest
+func rowsSelected(dest any, err error) int64 {
+	destValue := reflect.Indirect(reflect.ValueOf(dest))
+	if err != nil || destValue.Kind() != reflect.Slice {
+		return -1
+	}
+	return int64(destValue.Len())
+}
+
+// rowsAffected counts the rows affected by an Exec
+func rowsAffected(result sql.Result, err error) int64 {
+	if err != nil {
+		return -1
+	}
+	affected, affectedErr := result.RowsAffected()
+	if affectedErr != nil {
+		return -1
+	}
+	return affected
+}
+
+// Get implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) Get(dest any, query string, args ...any) error {
+	start := time.Now()
+	getErr := cxn.inner.Get(dest, query, args...)
+	cxn.observe("Get", query, start, rowsFetched(getErr), getErr)
+	return getErr
+}
+
+// GetContext implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	start := time.Now()
+	getErr := cxn.inner.GetContext(ctx, dest, query, args...)
+	cxn.observe("GetContext", query, start, rowsFetched(getErr), getErr)
+	return getErr
+}
+
+// Select implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) Select(dest any, query string, args ...any) error {
+	start := time.Now()
+	selectErr := cxn.inner.Select(dest, query, args...)
+	cxn.observe("Select", query, start, rowsSelected(dest, selectErr), selectErr)
+	return selectErr
+}
+
+// SelectContext implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	start := time.Now()
+	selectErr := cxn.inner.SelectContext(ctx, dest, query, args...)
+	cxn.observe("SelectContext", query, start, rowsSelected(dest, selectErr), selectErr)
+	return selectErr
+}
+
+// Exec implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) Exec(query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	result, execErr := cxn.inner.Exec(query, args...)
+	cxn.observe("Exec", query, start, rowsAffected(result, execErr), execErr)
+	return result, execErr
+}
+
+// ExecContext implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	result, execErr := cxn.inner.ExecContext(ctx, query, args...)
+	cxn.observe("ExecContext", query, start, rowsAffected(result, execErr), execErr)
+	return result, execErr
+}
+
+// NamedExec implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) NamedExec(query string, arg any) (sql.Result, error) {
+	start := time.Now()
+	result, execErr := cxn.inner.NamedExec(query, arg)
+	cxn.observe("NamedExec", query, start, rowsAffected(result, execErr), execErr)
+	return result, execErr
+}
+
+// NamedExecContext implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
+	start := time.Now()
+	result, execErr := cxn.inner.NamedExecContext(ctx, query, arg)
+	cxn.observe("NamedExecContext", query, start, rowsAffected(result, execErr), execErr)
+	return result, execErr
+}
+
+// Preparex implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) Preparex(query string) (*sqlx.Stmt, error) {
+	start := time.Now()
+	stmt, prepareErr := cxn.inner.Preparex(query)
+	cxn.observe("Preparex", query, start, -1, prepareErr)
+	return stmt, prepareErr
+}
+
+// PreparexContext implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error) {
+	start := time.Now()
+	stmt, prepareErr := cxn.inner.PreparexContext(ctx, query)
+	cxn.observe("PreparexContext", query, start, -1, prepareErr)
+	return stmt, prepareErr
+}
+
+// PrepareNamed implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
+	start := time.Now()
+	stmt, prepareErr := cxn.inner.PrepareNamed(query)
+	cxn.observe("PrepareNamed", query, start, -1, prepareErr)
+	return stmt, prepareErr
+}
+
+// PrepareNamedContext implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	start := time.Now()
+	stmt, prepareErr := cxn.inner.PrepareNamedContext(ctx, query)
+	cxn.observe("PrepareNamedContext", query, start, -1, prepareErr)
+	return stmt, prepareErr
+}
+
+// Queryx implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) Queryx(query string, args ...any) (*sqlx.Rows, error) {
+	start := time.Now()
+	rows, queryErr := cxn.inner.Queryx(query, args...)
+	cxn.observe("Queryx", query, start, -1, queryErr)
+	return rows, queryErr
+}
+
+// QueryxContext implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	start := time.Now()
+	rows, queryErr := cxn.inner.QueryxContext(ctx, query, args...)
+	cxn.observe("QueryxContext", query, start, -1, queryErr)
+	return rows, queryErr
+}
+
+// NamedQuery implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) NamedQuery(query string, arg any) (*sqlx.Rows, error) {
+	start := time.Now()
+	rows, queryErr := cxn.inner.NamedQuery(query, arg)
+	cxn.observe("NamedQuery", query, start, -1, queryErr)
+	return rows, queryErr
+}
+
+// Rebind implements Connection for instrumentedConnection
+func (cxn *instrumentedConnection) Rebind(query string) string {
+	return cxn.inner.Rebind(query)
+}
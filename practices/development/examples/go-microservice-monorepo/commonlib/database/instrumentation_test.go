@@ -0,0 +1,116 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"example.com/sample/commonlib/logger"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type InstrumentationSuite struct {
+	suite.Suite
+	events          []QueryEvent
+	instrumentation *Instrumentation
+	dbContext       context.Context
+	logOutput       *bytes.Buffer
+}
+
+func TestInstrumentationSuite(t *testing.T) {
+	suite.Run(t, new(InstrumentationSuite))
+}
+
+func (suite *InstrumentationSuite) SetupTest() {
+	suite.events = nil
+	suite.instrumentation = NewInstrumentation(0, func(ctx context.Context, event QueryEvent) {
+		suite.events = append(suite.events, event)
+	})
+
+	db, _ := openRecording(suite.T())
+	suite.dbContext = WithInstrumentation(CreateDerivativeContext(context.Background(), db), suite.instrumentation)
+
+	// Capture the slow query log
+	previousLogger := logger.Log
+	suite.logOutput = new(bytes.Buffer)
+	logger.Log = zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(suite.logOutput),
+		zapcore.DebugLevel))
+	suite.T().Cleanup(func() {
+		logger.Log = previousLogger
+	})
+}
+
+func (suite *InstrumentationSuite) TestReportsStatements() {
+	cxn := RetrieveFromContext(suite.dbContext)
+	_, execErr := cxn.Exec("DELETE FROM greetings")
+	suite.Require().NoError(execErr)
+	var greetings []string
+	selectErr := cxn.Select(&greetings, "SELECT greetingText FROM greetings")
+	suite.Require().NoError(selectErr)
+	var greeting string
+	getErr := cxn.Get(&greeting, "SELECT greetingText FROM greetings LIMIT 1")
+	suite.Require().ErrorIs(getErr, sql.ErrNoRows)
+
+	suite.Require().Len(suite.events, 3)
+	suite.Assert().Equal("Exec", suite.events[0].Method)
+	suite.Assert().Equal("DELETE FROM greetings", suite.events[0].Query)
+	suite.Assert().Equal(int64(1), suite.events[0].Rows)
+	suite.Assert().Equal("Select", suite.events[1].Method)
+	suite.Assert().Equal(int64(0), suite.events[1].Rows)
+	suite.Assert().Equal("Get", suite.events[2].Method)
+	suite.Assert().ErrorIs(suite.events[2].Err, sql.ErrNoRows)
+	suite.Assert().Equal(int64(0), suite.events[2].Rows)
+	for _, event := range suite.events {
+		suite.Assert().False(event.InTransaction)
+	}
+	suite.Assert().Empty(suite.logOutput.String(), "Slow query log is disabled")
+}
+
+func (suite *InstrumentationSuite) TestReportsStatementsInTransactions() {
+	txErr := WithTransaction(suite.dbContext, func(ctx context.Context) error {
+		_, execErr := RetrieveFromContext(ctx).Exec("DELETE FROM greetings")
+		return execErr
+	})
+
+	suite.Require().NoError(txErr)
+	suite.Require().Len(suite.events, 1)
+	suite.Assert().True(suite.events[0].InTransaction)
+}
+
+func (suite *InstrumentationSuite) TestReportsStatementsInTransactionsOfBoundConnections() {
+	db, _ := openRecording(suite.T())
+	cluster := NewCluster(db)
+	cluster.StatementTimeout = time.Minute
+	requestCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dbContext := WithInstrumentation(CreateDerivativeClusterContext(requestCtx, cluster), suite.instrumentation)
+
+	txErr := WithTransaction(dbContext, func(ctx context.Context) error {
+		_, execErr := RetrieveFromContext(ctx).Exec("DELETE FROM greetings")
+		return execErr
+	})
+
+	suite.Require().NoError(txErr)
+	suite.Require().Len(suite.events, 1)
+	suite.Assert().True(suite.events[0].InTransaction)
+}
+
+func (suite *InstrumentationSuite) TestLogsSlowQueries() {
+	suite.instrumentation.SlowQueryThreshold = time.Nanosecond
+	_, execErr := RetrieveFromContext(suite.dbContext).Exec("DELETE FROM greetings")
+
+	suite.Require().NoError(execErr)
+	suite.Assert().Contains(suite.logOutput.String(), "Slow database query")
+	suite.Assert().Contains(suite.logOutput.String(), "DELETE FROM greetings")
+}
+
+func (suite *InstrumentationSuite) TestUninstrumentedContext() {
+	db, _ := openRecording(suite.T())
+	cxn := RetrieveFromContext(CreateDerivativeContext(context.Background(), db))
+
+	suite.Assert().Same(db, cxn)
+}
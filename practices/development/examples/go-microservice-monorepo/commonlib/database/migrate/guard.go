@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrSchemaBehind is returned when migrations shipped with the application haven't been applied to the database
+var ErrSchemaBehind = errors.New("the database schema is behind the migrations shipped with the application")
+
+// SchemaReport compares the migrations applied to a database with the migrations shipped with the application
+type SchemaReport struct {
+	// Pending lists shipped migrations which haven't been applied, sorted by version
+	Pending []Migration
+	// Unrecognized lists applied versions which don't match a shipped migration, sorted by version. They usually mean
+	// a newer build migrated the database, which an older build can often run against safely.
+	Unrecognized []string
+}
+
+// UpToDate reports whether every shipped migration has been applied
+func (report SchemaReport) UpToDate() bool {
+	return len(report.Pending) == 0
+}
+
+// Err returns an error wrapping ErrSchemaBehind which lists the pending migrations, or nil if the schema is up to date
+func (report SchemaReport) Err() error {
+	if report.UpToDate() {
+		return nil
+	}
+
+	pendingNames := make([]string, 0, len(report.Pending))
+	for _, migration := range report.Pending {
+		pendingNames = append(pendingNames, fmt.Sprintf("%v_%v", migration.Version, migration.Name))
+	}
+	return fmt.Errorf("%w, %v migrations are pending: %v", ErrSchemaBehind, len(report.Pending), strings.Join(pendingNames, ", "))
+}
+
+// CheckSchema compares the migrations applied to the database with the Runner's migrations, without applying any. It
+// only reads from the database, so the database user doesn't need to be allowed to create tables.
+func (runner *Runner) CheckSchema(ctx context.Context) (SchemaReport, error) {
+	appliedVersions, versionsErr := runner.currentVersions(ctx)
+	if versionsErr != nil {
+		return SchemaReport{}, versionsErr
+	}
+
+	return schemaReportOf(statusOf(runner.migrations, appliedVersions), appliedVersions), nil
+}
+
+// SchemaUpToDate returns an error if the database schema is behind the Runner's migrations or can't be checked. Its
+// signature matches readiness.Check, so it can be registered as a readiness check directly.
+func (runner *Runner) SchemaUpToDate(ctx context.Context) error {
+	report, checkErr := runner.CheckSchema(ctx)
+	if checkErr != nil {
+		return checkErr
+	}
+
+	return report.Err()
+}
+
+// schemaReportOf builds a SchemaReport from the status of each shipped migration and the versions applied to the
+// database
+func schemaReportOf(statuses []MigrationStatus, appliedVersions []string) SchemaReport {
+	var report SchemaReport
+	var shippedVersions []string
+	for _, status := range statuses {
+		shippedVersions = append(shippedVersions, status.Version)
+		if !status.Applied {
+			report.Pending = append(report.Pending, status.Migration)
+		}
+	}
+	for _, version := range appliedVersions {
+		if !slices.Contains(shippedVersions, version) {
+			report.Unrecognized = append(report.Unrecognized, version)
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,167 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/suite"
+)
+
+type GuardSuite struct {
+	suite.Suite
+}
+
+func TestGuardSuite(t *testing.T) {
+	suite.Run(t, new(GuardSuite))
+}
+
+func (suite *GuardSuite) TestUpToDate() {
+	migrations := []Migration{{Version: "1", Name: "a"}, {Version: "2", Name: "b"}}
+	report := schemaReportOf(statusOf(migrations, []string{"1", "2"}), []string{"1", "2"})
+
+	suite.Assert().True(report.UpToDate())
+	suite.Assert().NoError(report.Err())
+}
+
+func (suite *GuardSuite) TestBehind() {
+	migrations := []Migration{{Version: "1", Name: "a"}, {Version: "2", Name: "b"}}
+	report := schemaReportOf(statusOf(migrations, []string{"1"}), []string{"1"})
+
+	suite.Assert().False(report.UpToDate())
+	suite.Assert().ErrorIs(report.Err(), ErrSchemaBehind)
+	suite.Assert().ErrorContains(report.Err(), "2_b")
+}
+
+func (suite *GuardSuite) TestAheadIsStillUpToDate() {
+	migrations := []Migration{{Version: "1", Name: "a"}}
+	report := schemaReportOf(statusOf(migrations, []string{"1", "2"}), []string{"1", "2"})
+
+	suite.Assert().True(report.UpToDate())
+	suite.Assert().Equal([]string{"2"}, report.Unrecognized)
+}
+
+func (suite *GuardSuite) TestCheckSchemaOnlyReads() {
+	db := &readOnlyDB{versionTables: 1, versions: []string{"1"}}
+	runner := suite.runnerFor(db, []Migration{{Version: "1", Name: "a"}, {Version: "2", Name: "b"}})
+
+	report, checkErr := runner.CheckSchema(context.Background())
+
+	suite.Require().NoError(checkErr)
+	suite.Assert().Equal([]Migration{{Version: "2", Name: "b"}}, report.Pending)
+	for _, query := range db.Queries() {
+		suite.Assert().True(strings.HasPrefix(query, "SELECT"), "%v changes the database", query)
+	}
+}
+
+func (suite *GuardSuite) TestMissingVersionTableMeansEveryMigrationIsPending() {
+	db := &readOnlyDB{versionTables: 0}
+	runner := suite.runnerFor(db, []Migration{{Version: "1", Name: "a"}})
+
+	schemaErr := runner.SchemaUpToDate(context.Background())
+
+	suite.Assert().ErrorIs(schemaErr, ErrSchemaBehind)
+	suite.Assert().Len(db.Queries(), 1, "schema_migrations isn't read or created if it doesn't exist")
+}
+
+// runnerFor constructs a MySQL Runner of the passed migrations reading from a readOnlyDB
+func (suite *GuardSuite) runnerFor(db *readOnlyDB, migrations []Migration) *Runner {
+	sqlDB := sql.OpenDB(db)
+	suite.T().Cleanup(func() {
+		_ = sqlDB.Close()
+	})
+	return &Runner{db: sqlx.NewDb(sqlDB, "mysql"), dialect: dialects["mysql"], migrations: migrations}
+}
+
+// readOnlyDB is a database/sql connector whose connections answer the queries reading schema_migrations, and record
+// every query so tests can check nothing else is run
+type readOnlyDB struct {
+	mutex         sync.Mutex
+	queries       []string
+	versionTables int64
+	versions      []string
+}
+
+// Queries returns every query run so far
+func (db *readOnlyDB) Queries() []string {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	return append([]string{}, db.queries...)
+}
+
+// Connect implements driver.Connector for readOnlyDB
+func (db *readOnlyDB) Connect(context.Context) (driver.Conn, error) {
+	return readOnlyConn{db: db}, nil
+}
+
+// Driver implements driver.Connector for readOnlyDB
+func (db *readOnlyDB) Driver() driver.Driver {
+	return nil
+}
+
+// readOnlyConn is a connection of readOnlyDB
+type readOnlyConn struct {
+	db *readOnlyDB
+}
+
+// Prepare implements driver.Conn for readOnlyConn
+func (conn readOnlyConn) Prepare(string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+// Close implements driver.Conn for readOnlyConn
+func (conn readOnlyConn) Close() error {
+	return nil
+}
+
+// Begin implements driver.Conn for readOnlyConn
+func (conn readOnlyConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+// QueryContext implements driver.QueryerContext for readOnlyConn, answering the count of schema_migrations tables and
+// the list of applied versions
+func (conn readOnlyConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	conn.db.mutex.Lock()
+	defer conn.db.mutex.Unlock()
+
+	conn.db.queries = append(conn.db.queries, query)
+	if strings.Contains(query, "information_schema") {
+		return &valueRows{values: []driver.Value{conn.db.versionTables}}, nil
+	}
+	versionRows := &valueRows{}
+	for _, version := range conn.db.versions {
+		versionRows.values = append(versionRows.values, version)
+	}
+	return versionRows, nil
+}
+
+// valueRows is a result set of one column
+type valueRows struct {
+	values []driver.Value
+}
+
+// Columns implements driver.Rows for valueRows
+func (rows *valueRows) Columns() []string {
+	return []string{"value"}
+}
+
+// Close implements driver.Rows for valueRows
+func (rows *valueRows) Close() error {
+	return nil
+}
+
+// Next implements driver.Rows for valueRows
+func (rows *valueRows) Next(dest []driver.Value) error {
+	if len(rows.values) == 0 {
+		return io.EOF
+	}
+	dest[0], rows.values = rows.values[0], rows.values[1:]
+	return nil
+}
@@ -0,0 +1,157 @@
+package migrate
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Markers separating the sections of a migration file, as written by dbmate
+const (
+	upMarker   = "-- migrate:up"
+	downMarker = "-- migrate:down"
+)
+
+// fileNamePattern matches migration file names, such as 20240122162558_create_tables.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(.+)\.sql$`)
+
+// Migration is a single migration file in dbmate's format. The file has a "-- migrate:up" section applying the
+// migration and an optional "-- migrate:down" section rolling it back. Either marker may be followed by
+// "transaction:false" to run that section outside a transaction, which some statements require.
+type Migration struct {
+	// Version identifies the migration. It's the numeric prefix of the file name, such as 20240122162558, and is what
+	// the schema_migrations table records.
+	Version string
+	// Name is the rest of the file name without its extension, such as create_tables
+	Name string
+	// UpSQL applies the migration
+	UpSQL string
+	// UpTransaction is true if UpSQL runs in a transaction, which is the default
+	UpTransaction bool
+	// DownSQL rolls the migration back. It's empty if the migration can't be rolled back.
+	DownSQL string
+	// DownTransaction is true if DownSQL runs in a transaction, which is the default
+	DownTransaction bool
+}
+
+// Load reads every migration file in the root directory of the passed file system, such as an embed.FS narrowed with
+// fs.Sub, sorted by version. Files which don't end in .sql are ignored.
+func Load(migrationFiles fs.FS) ([]Migration, error) {
+	entries, readErr := fs.ReadDir(migrationFiles, ".")
+	if readErr != nil {
+		return nil, fmt.Errorf("could not list migration files: %w", readErr)
+	}
+
+	var migrations []Migration
+	seenVersions := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		contents, fileErr := fs.ReadFile(migrationFiles, entry.Name())
+		if fileErr != nil {
+			return nil, fmt.Errorf("could not read migration file %v: %w", entry.Name(), fileErr)
+		}
+		migration, parseErr := Parse(entry.Name(), string(contents))
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		if otherFile, versionSeen := seenVersions[migration.Version]; versionSeen {
+			return nil, fmt.Errorf("migration files %v and %v have the same version", otherFile, entry.Name())
+		}
+
+		seenVersions[migration.Version] = entry.Name()
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return versionLess(migrations[i].Version, migrations[j].Version)
+	})
+	return migrations, nil
+}
+
+// Parse reads a single migration from the name and contents of its file
+func Parse(fileName string, contents string) (Migration, error) {
+	nameParts := fileNamePattern.FindStringSubmatch(path.Base(fileName))
+	if nameParts == nil {
+		return Migration{}, fmt.Errorf("migration file name %v must be a version number, an underscore, and a name ending in .sql", fileName)
+	}
+	migration := Migration{
+		Version:         nameParts[1],
+		Name:            nameParts[2],
+		UpTransaction:   true,
+		DownTransaction: true,
+	}
+
+	var upLines, downLines []string
+	var currentSection *[]string
+	foundUp := false
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, upMarker):
+			if foundUp {
+				return Migration{}, fmt.Errorf("migration file %v has more than one %v section", fileName, upMarker)
+			}
+			transaction, optionsErr := parseSectionOptions(strings.TrimPrefix(line, upMarker))
+			if optionsErr != nil {
+				return Migration{}, fmt.Errorf("migration file %v: %w", fileName, optionsErr)
+			}
+			migration.UpTransaction = transaction
+			currentSection, foundUp = &upLines, true
+		case strings.HasPrefix(line, downMarker):
+			if !foundUp || currentSection == &downLines {
+				return Migration{}, fmt.Errorf("migration file %v must have a single %v section after its %v section", fileName, downMarker, upMarker)
+			}
+			transaction, optionsErr := parseSectionOptions(strings.TrimPrefix(line, downMarker))
+			if optionsErr != nil {
+				return Migration{}, fmt.Errorf("migration file %v: %w", fileName, optionsErr)
+			}
+			migration.DownTransaction = transaction
+			currentSection = &downLines
+		case currentSection != nil:
+			*currentSection = append(*currentSection, line)
+		}
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		return Migration{}, fmt.Errorf("could not read migration file %v: %w", fileName, scanErr)
+	}
+	if !foundUp {
+		return Migration{}, fmt.Errorf("migration file %v is missing its %v section", fileName, upMarker)
+	}
+
+	migration.UpSQL = strings.TrimSpace(strings.Join(upLines, "\n"))
+	migration.DownSQL = strings.TrimSpace(strings.Join(downLines, "\n"))
+	return migration, nil
+}
+
+// parseSectionOptions reads the options following a section marker, returning whether the section runs in a transaction
+func parseSectionOptions(options string) (bool, error) {
+	transaction := true
+	for _, option := range strings.Fields(options) {
+		switch option {
+		case "transaction:false":
+			transaction = false
+		case "transaction:true":
+			transaction = true
+		default:
+			return false, fmt.Errorf("unknown migration section option %v", option)
+		}
+	}
+
+	return transaction, nil
+}
+
+// versionLess orders versions numerically, so versions of different lengths still sort correctly
+func versionLess(left string, right string) bool {
+	if len(left) != len(right) {
+		return len(left) < len(right)
+	}
+	return left < right
+}
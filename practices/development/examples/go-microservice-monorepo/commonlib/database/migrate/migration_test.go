@@ -0,0 +1,97 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MigrationSuite struct {
+	suite.Suite
+}
+
+func TestMigrationSuite(t *testing.T) {
+	suite.Run(t, new(MigrationSuite))
+}
+
+func (suite *MigrationSuite) TestParse() {
+	migration, parseErr := Parse("20240122162558_create_tables.sql", `-- migrate:up
+CREATE TABLE greetings (id int PRIMARY KEY);
+INSERT INTO greetings VALUES (1);
+
+-- migrate:down
+DROP TABLE greetings;
+`)
+	suite.Require().NoError(parseErr)
+
+	suite.Assert().Equal(Migration{
+		Version:         "20240122162558",
+		Name:            "create_tables",
+		UpSQL:           "CREATE TABLE greetings (id int PRIMARY KEY);\nINSERT INTO greetings VALUES (1);",
+		UpTransaction:   true,
+		DownSQL:         "DROP TABLE greetings;",
+		DownTransaction: true,
+	}, migration)
+}
+
+func (suite *MigrationSuite) TestParseTransactionOption() {
+	migration, parseErr := Parse("1_index.sql", "-- migrate:up transaction:false\nCREATE INDEX a ON b (c);\n-- migrate:down\nDROP INDEX a ON b;")
+	suite.Require().NoError(parseErr)
+
+	suite.Assert().False(migration.UpTransaction)
+	suite.Assert().True(migration.DownTransaction)
+}
+
+func (suite *MigrationSuite) TestParseWithoutDownSection() {
+	migration, parseErr := Parse("1_seed.sql", "-- a comment before the sections\n-- migrate:up\nINSERT INTO b VALUES (1);")
+	suite.Require().NoError(parseErr)
+
+	suite.Assert().Equal("INSERT INTO b VALUES (1);", migration.UpSQL)
+	suite.Assert().Empty(migration.DownSQL)
+}
+
+func (suite *MigrationSuite) TestParseErrors() {
+	subtests := []struct {
+		testName string
+		fileName string
+		contents string
+	}{
+		{testName: "Missing version", fileName: "create_tables.sql", contents: "-- migrate:up\nSELECT 1;"},
+		{testName: "Missing up section", fileName: "1_a.sql", contents: "SELECT 1;"},
+		{testName: "Down before up", fileName: "1_a.sql", contents: "-- migrate:down\nSELECT 1;\n-- migrate:up\nSELECT 2;"},
+		{testName: "Two up sections", fileName: "1_a.sql", contents: "-- migrate:up\nSELECT 1;\n-- migrate:up\nSELECT 2;"},
+		{testName: "Unknown option", fileName: "1_a.sql", contents: "-- migrate:up transaction:maybe\nSELECT 1;"},
+	}
+
+	for _, subtest := range subtests {
+		suite.Run(subtest.testName, func() {
+			_, parseErr := Parse(subtest.fileName, subtest.contents)
+			suite.Assert().Error(parseErr)
+		})
+	}
+}
+
+func (suite *MigrationSuite) TestLoadSortsByVersion() {
+	migrationFiles := fstest.MapFS{
+		"20240301000000_b.sql": {Data: []byte("-- migrate:up\nSELECT 2;")},
+		"900_a.sql":            {Data: []byte("-- migrate:up\nSELECT 1;")},
+		"README.md":            {Data: []byte("Not a migration")},
+	}
+	migrations, loadErr := Load(migrationFiles)
+	suite.Require().NoError(loadErr)
+
+	suite.Require().Len(migrations, 2)
+	suite.Assert().Equal("900", migrations[0].Version)
+	suite.Assert().Equal("20240301000000", migrations[1].Version)
+}
+
+func (suite *MigrationSuite) TestLoadRejectsDuplicateVersions() {
+	migrationFiles := fstest.MapFS{
+		"1_a.sql": {Data: []byte("-- migrate:up\nSELECT 1;")},
+		"1_b.sql": {Data: []byte("-- migrate:up\nSELECT 2;")},
+	}
+	_, loadErr := Load(migrationFiles)
+
+	suite.Assert().ErrorContains(loadErr, "same version")
+}
@@ -0,0 +1,317 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"example.com/sample/commonlib/database"
+	"github.com/jmoiron/sqlx"
+)
+
+// defaultLockTimeout is how long a Runner waits for another replica to finish migrating by default
+const defaultLockTimeout = 5 * time.Minute
+
+// ErrLockTimeout is returned when another process held the migration lock for longer than the lock timeout
+var ErrLockTimeout = errors.New("timed out waiting for another process to finish migrating")
+
+// lockRetryInterval is how often a Runner tries to take the migration lock on databases which can't wait for it
+const lockRetryInterval = time.Second
+
+// dialect holds the statements which differ between database drivers
+type dialect struct {
+	// createVersionTable creates the schema_migrations table if it doesn't exist
+	createVersionTable string
+	// countVersionTables counts the schema_migrations tables in the current schema, which is 0 before the first
+	// migration and 1 after
+	countVersionTables string
+	// lock takes the migration lock on the passed connection, waiting up to the passed timeout. It returns false if the
+	// lock couldn't be taken in time.
+	lock func(ctx context.Context, conn *sqlx.Conn, timeout time.Duration) (bool, error)
+	// unlock releases the migration lock
+	unlock string
+}
+
+// postgresDialect is shared by every driver name Postgres can be reached through
+var postgresDialect = dialect{
+	createVersionTable: "CREATE TABLE IF NOT EXISTS schema_migrations (version varchar(128) NOT NULL PRIMARY KEY)",
+	countVersionTables: "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'",
+	// Advisory locks are global to the server and keyed by number, so the key is a hash of a name including the database
+	lock: func(ctx context.Context, conn *sqlx.Conn, timeout time.Duration) (bool, error) {
+		// pg_advisory_lock can't be told how long to wait, so try to take the lock repeatedly instead
+		deadline := time.Now().Add(timeout)
+		for {
+			var lockTaken bool
+			if lockErr := conn.GetContext(ctx, &lockTaken, "SELECT pg_try_advisory_lock(hashtext(current_database() || '.schema_migrations'))"); lockErr != nil {
+				return false, lockErr
+			}
+			if lockTaken || time.Now().After(deadline) {
+				return lockTaken, nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return false, ctx.Err()
+			case <-time.After(lockRetryInterval):
+			}
+		}
+	},
+	unlock: "SELECT pg_advisory_unlock(hashtext(current_database() || '.schema_migrations'))",
+}
+
+// dialects holds the dialect of every supported driver, by driver name
+var dialects = map[string]dialect{
+	"mysql": {
+		createVersionTable: "CREATE TABLE IF NOT EXISTS schema_migrations (version varchar(128) NOT NULL PRIMARY KEY)",
+		countVersionTables: "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'schema_migrations'",
+		// Lock names are global to the server, so the name includes the schema to keep schemas from blocking each other
+		lock: func(ctx context.Context, conn *sqlx.Conn, timeout time.Duration) (bool, error) {
+			var lockTaken *int
+			lockErr := conn.GetContext(ctx, &lockTaken, "SELECT GET_LOCK(CONCAT(DATABASE(), '.schema_migrations'), ?)", int(timeout.Seconds()))
+			return lockTaken != nil && *lockTaken == 1, lockErr
+		},
+		unlock: "SELECT RELEASE_LOCK(CONCAT(DATABASE(), '.schema_migrations'))",
+	},
+	"pgx":      postgresDialect,
+	"postgres": postgresDialect,
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	// Applied is true if the migration's version is recorded in the schema_migrations table
+	Applied bool
+}
+
+// Runner applies and rolls back migrations, recording applied versions in the schema_migrations table the same way
+// dbmate does, so the two can be used interchangeably. Runs of Up and Down hold a database lock, so when several replicas
+// start at once only one migrates and the others wait for it to finish.
+//
+// On MySQL, statements which change the schema, such as CREATE TABLE, commit the surrounding transaction implicitly, so
+// a failed migration may be left partly applied even though it ran in a transaction.
+type Runner struct {
+	db         *sqlx.DB
+	ownsDB     bool
+	dialect    dialect
+	migrations []Migration
+
+	// LockTimeout limits how long Up and Down wait for another process to finish migrating. It defaults to 5 minutes.
+	LockTimeout time.Duration
+}
+
+// NewRunner constructs a Runner applying the migration files in the root of the passed file system to a database. The
+// database must allow several statements per query if any migration file contains several statements; Open connects
+// that way.
+func NewRunner(db *sqlx.DB, migrationFiles fs.FS) (*Runner, error) {
+	migrationDialect, dialectSupported := dialects[db.DriverName()]
+	if !dialectSupported {
+		return nil, fmt.Errorf("migrations aren't supported for the %v database driver", db.DriverName())
+	}
+
+	migrations, loadErr := Load(migrationFiles)
+	if loadErr != nil {
+		return nil, loadErr
+	}
+
+	return &Runner{
+		db:          db,
+		dialect:     migrationDialect,
+		migrations:  migrations,
+		LockTimeout: defaultLockTimeout,
+	}, nil
+}
+
+// Open connects to the database described by the passed configuration and constructs a Runner for it. The connection
+// is separate from the application's own and allows several statements per query. Close the Runner once it's no longer
+// needed.
+func Open(config database.Config, migrationFiles fs.FS) (*Runner, error) {
+	config.OptionalSettings.MultiStatements = true
+	db, connectErr := database.Connect(config)
+	if connectErr != nil {
+		return nil, connectErr
+	}
+
+	runner, runnerErr := NewRunner(db, migrationFiles)
+	if runnerErr != nil {
+		_ = db.Close()
+		return nil, runnerErr
+	}
+
+	runner.ownsDB = true
+	return runner, nil
+}
+
+// Close closes the database connection if the Runner was constructed with Open
+func (runner *Runner) Close() error {
+	if !runner.ownsDB {
+		return nil
+	}
+	return runner.db.Close()
+}
+
+// Migrations returns every migration the Runner knows about, sorted by version
+func (runner *Runner) Migrations() []Migration {
+	return append([]Migration{}, runner.migrations...)
+}
+
+// Status reports whether each migration has been applied, sorted by version
+func (runner *Runner) Status(ctx context.Context) ([]MigrationStatus, error) {
+	appliedVersions, versionsErr := runner.currentVersions(ctx)
+	if versionsErr != nil {
+		return nil, versionsErr
+	}
+
+	return statusOf(runner.migrations, appliedVersions), nil
+}
+
+// currentVersions lists the versions applied to the database without taking the migration lock or changing the
+// schema, so it works for database users which can't create tables. If the schema_migrations table doesn't exist yet,
+// no versions have been applied.
+func (runner *Runner) currentVersions(ctx context.Context) ([]string, error) {
+	var versionTables int
+	if countErr := runner.db.GetContext(ctx, &versionTables, runner.dialect.countVersionTables); countErr != nil {
+		return nil, fmt.Errorf("could not look for the schema_migrations table: %w", countErr)
+	}
+	if versionTables == 0 {
+		return nil, nil
+	}
+
+	return runner.appliedVersions(ctx, runner.db)
+}
+
+// Up applies every migration which hasn't been applied yet, in order of version, returning the migrations it applied.
+// It stops at the first migration which fails, returning the migrations applied before it along with the error.
+func (runner *Runner) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	lockedErr := runner.withLock(ctx, func(conn *sqlx.Conn) error {
+		appliedVersions, versionsErr := runner.appliedVersions(ctx, conn)
+		if versionsErr != nil {
+			return versionsErr
+		}
+
+		for _, migration := range pendingMigrations(runner.migrations, appliedVersions) {
+			recordVersion := func(executor sqlx.ExecerContext) error {
+				_, recordErr := executor.ExecContext(ctx, runner.db.Rebind("INSERT INTO schema_migrations (version) VALUES (?)"), migration.Version)
+				return recordErr
+			}
+			if runErr := runSection(ctx, conn, migration.UpSQL, migration.UpTransaction, recordVersion); runErr != nil {
+				return fmt.Errorf("could not apply migration %v_%v: %w", migration.Version, migration.Name, runErr)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+
+	return applied, lockedErr
+}
+
+// Down rolls back the most recently applied migration, returning it. The second return value is false if no
+// migrations are applied.
+func (runner *Runner) Down(ctx context.Context) (Migration, bool, error) {
+	var rolledBack Migration
+	var found bool
+	lockedErr := runner.withLock(ctx, func(conn *sqlx.Conn) error {
+		appliedVersions, versionsErr := runner.appliedVersions(ctx, conn)
+		if versionsErr != nil || len(appliedVersions) == 0 {
+			return versionsErr
+		}
+
+		latestVersion := appliedVersions[len(appliedVersions)-1]
+		migration, shipped := findMigration(runner.migrations, latestVersion)
+		if !shipped {
+			return fmt.Errorf("could not roll back migration %v, there's no migration file with that version", latestVersion)
+		}
+		if migration.DownSQL == "" {
+			return fmt.Errorf("could not roll back migration %v_%v, it has no %v section", migration.Version, migration.Name, downMarker)
+		}
+
+		forgetVersion := func(executor sqlx.ExecerContext) error {
+			_, forgetErr := executor.ExecContext(ctx, runner.db.Rebind("DELETE FROM schema_migrations WHERE version = ?"), migration.Version)
+			return forgetErr
+		}
+		if runErr := runSection(ctx, conn, migration.DownSQL, migration.DownTransaction, forgetVersion); runErr != nil {
+			return fmt.Errorf("could not roll back migration %v_%v: %w", migration.Version, migration.Name, runErr)
+		}
+		rolledBack, found = migration, true
+		return nil
+	})
+
+	return rolledBack, found, lockedErr
+}
+
+// withLock runs the passed function on a single connection while holding the migration lock, after making sure the
+// schema_migrations table exists. The lock belongs to the connection, which is why everything runs on it.
+func (runner *Runner) withLock(ctx context.Context, operation func(conn *sqlx.Conn) error) error {
+	conn, connErr := runner.db.Connx(ctx)
+	if connErr != nil {
+		return fmt.Errorf("could not connect to the database: %w", connErr)
+	}
+	defer conn.Close()
+
+	lockTaken, lockErr := runner.dialect.lock(ctx, conn, runner.LockTimeout)
+	if lockErr != nil {
+		return fmt.Errorf("could not take the migration lock: %w", lockErr)
+	}
+	if !lockTaken {
+		return ErrLockTimeout
+	}
+	// Release the lock even if the context was cancelled, rather than leaving it until the connection closes
+	defer func() {
+		_, _ = conn.ExecContext(context.WithoutCancel(ctx), runner.dialect.unlock)
+	}()
+
+	if _, createErr := conn.ExecContext(ctx, runner.dialect.createVersionTable); createErr != nil {
+		return fmt.Errorf("could not create the schema_migrations table: %w", createErr)
+	}
+
+	return operation(conn)
+}
+
+// appliedVersions lists the versions recorded in the schema_migrations table, sorted by version
+func (runner *Runner) appliedVersions(ctx context.Context, queryer sqlx.QueryerContext) ([]string, error) {
+	var versions []string
+	if selectErr := sqlx.SelectContext(ctx, queryer, &versions, "SELECT version FROM schema_migrations"); selectErr != nil {
+		return nil, fmt.Errorf("could not read the schema_migrations table: %w", selectErr)
+	}
+
+	sortVersions(versions)
+	return versions, nil
+}
+
+// runSection runs a section of a migration followed by the statement updating schema_migrations, in a transaction if
+// the section allows it
+func runSection(ctx context.Context, conn *sqlx.Conn, sectionSQL string, transaction bool, updateVersions func(sqlx.ExecerContext) error) error {
+	if !transaction {
+		if sectionSQL != "" {
+			if _, execErr := conn.ExecContext(ctx, sectionSQL); execErr != nil {
+				return execErr
+			}
+		}
+		return updateVersions(conn)
+	}
+
+	tx, beginErr := conn.BeginTxx(ctx, nil)
+	if beginErr != nil {
+		return beginErr
+	}
+	if sectionSQL != "" {
+		if _, execErr := tx.ExecContext(ctx, sectionSQL); execErr != nil {
+			return rollback(tx, execErr)
+		}
+	}
+	if updateErr := updateVersions(tx); updateErr != nil {
+		return rollback(tx, updateErr)
+	}
+
+	return tx.Commit()
+}
+
+// rollback rolls back a transaction after an error, keeping the original error accessible via errors.Is and errors.As
+func rollback(tx *sqlx.Tx, operationErr error) error {
+	if rollbackErr := tx.Rollback(); rollbackErr != nil {
+		return fmt.Errorf("rollback failed when migration returned an error (%w): %w", operationErr, rollbackErr)
+	}
+	return operationErr
+}
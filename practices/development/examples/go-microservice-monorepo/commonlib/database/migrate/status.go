@@ -0,0 +1,50 @@
+package migrate
+
+import (
+	"slices"
+	"sort"
+)
+
+// statusOf reports whether each migration's version is among the applied versions
+func statusOf(migrations []Migration, appliedVersions []string) []MigrationStatus {
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		statuses = append(statuses, MigrationStatus{
+			Migration: migration,
+			Applied:   slices.Contains(appliedVersions, migration.Version),
+		})
+	}
+
+	return statuses
+}
+
+// pendingMigrations lists the migrations whose versions aren't among the applied versions, in order of version. Like
+// dbmate, this includes migrations older than the most recently applied one, such as ones merged from another branch.
+func pendingMigrations(migrations []Migration, appliedVersions []string) []Migration {
+	var pending []Migration
+	for _, status := range statusOf(migrations, appliedVersions) {
+		if !status.Applied {
+			pending = append(pending, status.Migration)
+		}
+	}
+
+	return pending
+}
+
+// findMigration finds the migration with the passed version. The second return value is false if there isn't one.
+func findMigration(migrations []Migration, version string) (Migration, bool) {
+	for _, migration := range migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+
+	return Migration{}, false
+}
+
+// sortVersions sorts versions numerically
+func sortVersions(versions []string) {
+	sort.Slice(versions, func(i, j int) bool {
+		return versionLess(versions[i], versions[j])
+	})
+}
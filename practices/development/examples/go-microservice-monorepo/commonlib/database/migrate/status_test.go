@@ -0,0 +1,42 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StatusSuite struct {
+	suite.Suite
+	migrations []Migration
+}
+
+func TestStatusSuite(t *testing.T) {
+	suite.Run(t, new(StatusSuite))
+}
+
+func (suite *StatusSuite) SetupTest() {
+	suite.migrations = []Migration{{Version: "1"}, {Version: "2"}, {Version: "3"}}
+}
+
+func (suite *StatusSuite) TestStatusOf() {
+	statuses := statusOf(suite.migrations, []string{"1", "3"})
+
+	suite.Require().Len(statuses, 3)
+	suite.Assert().True(statuses[0].Applied)
+	suite.Assert().False(statuses[1].Applied)
+	suite.Assert().True(statuses[2].Applied)
+}
+
+func (suite *StatusSuite) TestPendingIncludesOlderMigrations() {
+	pending := pendingMigrations(suite.migrations, []string{"1", "3"})
+
+	suite.Assert().Equal([]Migration{{Version: "2"}}, pending)
+}
+
+func (suite *StatusSuite) TestSortVersionsNumerically() {
+	versions := []string{"20240101000000", "10", "9"}
+	sortVersions(versions)
+
+	suite.Assert().Equal([]string{"9", "10", "20240101000000"}, versions)
+}
@@ -0,0 +1,272 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// recordingDriver is a database/sql driver which records the statements run against it rather than running them, so
+// tests can check what was sent to the database. Each data source name has its own recording.
+type recordingDriver struct {
+	mutex      sync.Mutex
+	recordings map[string]*recording
+}
+
+var recorder = &recordingDriver{recordings: make(map[string]*recording)}
+
+func init() {
+	sql.Register("recording", recorder)
+}
+
+// recording is the list of statements run against one data source name, along with errors to fail statements with
+type recording struct {
+	mutex      sync.Mutex
+	statements []string
+	failures   map[string][]error
+	blocking   []string
+	queryCtx   context.Context
+}
+
+// openRecording opens a database whose statements are recorded, unique to the passed test. Like databases opened by
+// Connect, its rows release their context once closed.
+func openRecording(t testing.TB) (*sqlx.DB, *recording) {
+	db := sqlx.NewDb(sql.OpenDB(releaseRows(dsnConnector{dsn: t.Name(), driver: recorder})), "recording")
+	t.Cleanup(func() {
+		_ = db.Close()
+		recorder.mutex.Lock()
+		delete(recorder.recordings, t.Name())
+		recorder.mutex.Unlock()
+	})
+
+	return db, recorder.recordingFor(t.Name())
+}
+
+// recordingFor returns the recording for a data source name, creating it if it doesn't exist
+func (drv *recordingDriver) recordingFor(name string) *recording {
+	drv.mutex.Lock()
+	defer drv.mutex.Unlock()
+
+	if drv.recordings[name] == nil {
+		drv.recordings[name] = &recording{failures: make(map[string][]error)}
+	}
+	return drv.recordings[name]
+}
+
+// Open implements driver.Driver for recordingDriver
+func (drv *recordingDriver) Open(name string) (driver.Conn, error) {
+	return &recordingConn{recording: drv.recordingFor(name)}, nil
+}
+
+// FailNext makes the next statements starting with the passed prefix fail with the passed errors, one per statement
+func (rec *recording) FailNext(prefix string, errs ...error) {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+
+	rec.failures[prefix] = append(rec.failures[prefix], errs...)
+}
+
+// BlockOn makes statements starting with the passed prefix block until their context is done, like a slow query
+func (rec *recording) BlockOn(prefix string) {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+
+	rec.blocking = append(rec.blocking, prefix)
+}
+
+// wait blocks until the context is done if the statement should block, returning the context's error
+func (rec *recording) wait(ctx context.Context, statement string) error {
+	rec.mutex.Lock()
+	blocks := slices.ContainsFunc(rec.blocking, func(prefix string) bool {
+		return strings.HasPrefix(statement, prefix)
+	})
+	rec.mutex.Unlock()
+
+	if !blocks {
+		return nil
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// LastQueryContext returns the context the last query returning rows was run with
+func (rec *recording) LastQueryContext() context.Context {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+
+	return rec.queryCtx
+}
+
+// Statements returns every statement recorded so far
+func (rec *recording) Statements() []string {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+
+	return append([]string{}, rec.statements...)
+}
+
+// record records a statement, returning the error it should fail with, if any
+func (rec *recording) record(statement string) error {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+
+	rec.statements = append(rec.statements, statement)
+	for prefix, errs := range rec.failures {
+		if strings.HasPrefix(statement, prefix) && len(errs) > 0 {
+			rec.failures[prefix] = errs[1:]
+			return errs[0]
+		}
+	}
+	return nil
+}
+
+// recordingConn is a connection of recordingDriver
+type recordingConn struct {
+	recording *recording
+}
+
+// Prepare implements driver.Conn for recordingConn. Preparing a statement isn't recorded, only running it is.
+func (conn *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{conn: conn, query: query}, nil
+}
+
+// Close implements driver.Conn for recordingConn
+func (conn *recordingConn) Close() error {
+	return nil
+}
+
+// Begin implements driver.Conn for recordingConn
+func (conn *recordingConn) Begin() (driver.Tx, error) {
+	return conn.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements driver.ConnBeginTx for recordingConn
+func (conn *recordingConn) BeginTx(_ context.Context, options driver.TxOptions) (driver.Tx, error) {
+	statement := "BEGIN"
+	if options.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		statement += " " + sql.IsolationLevel(options.Isolation).String()
+	}
+	if options.ReadOnly {
+		statement += " READ ONLY"
+	}
+	if beginErr := conn.recording.record(statement); beginErr != nil {
+		return nil, beginErr
+	}
+	return &recordingTx{recording: conn.recording}, nil
+}
+
+// ExecContext implements driver.ExecerContext for recordingConn
+func (conn *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	statement := query
+	for _, arg := range args {
+		statement += fmt.Sprintf(" [%v]", arg.Value)
+	}
+	if execErr := conn.recording.record(statement); execErr != nil {
+		return nil, execErr
+	}
+	if waitErr := conn.recording.wait(ctx, statement); waitErr != nil {
+		return nil, waitErr
+	}
+	return driver.RowsAffected(1), nil
+}
+
+// QueryContext implements driver.QueryerContext for recordingConn. Every query returns no rows. Like the MySQL driver
+// without InterpolateParams, queries with arguments are skipped so database/sql prepares them instead.
+func (conn *recordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) > 0 {
+		return nil, driver.ErrSkip
+	}
+	return conn.query(ctx, query)
+}
+
+// query records a query, returning no rows
+func (conn *recordingConn) query(ctx context.Context, query string) (driver.Rows, error) {
+	conn.recording.mutex.Lock()
+	conn.recording.queryCtx = ctx
+	conn.recording.mutex.Unlock()
+	if queryErr := conn.recording.record(query); queryErr != nil {
+		return nil, queryErr
+	}
+	if waitErr := conn.recording.wait(ctx, query); waitErr != nil {
+		return nil, waitErr
+	}
+	return emptyRows{}, nil
+}
+
+// recordingStmt is a prepared statement of recordingDriver
+type recordingStmt struct {
+	conn  *recordingConn
+	query string
+}
+
+// Close implements driver.Stmt for recordingStmt
+func (stmt *recordingStmt) Close() error {
+	return nil
+}
+
+// NumInput implements driver.Stmt for recordingStmt. The number of arguments isn't checked.
+func (stmt *recordingStmt) NumInput() int {
+	return -1
+}
+
+// Exec implements driver.Stmt for recordingStmt
+func (stmt *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("recordingStmt only supports ExecContext")
+}
+
+// Query implements driver.Stmt for recordingStmt
+func (stmt *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("recordingStmt only supports QueryContext")
+}
+
+// ExecContext implements driver.StmtExecContext for recordingStmt
+func (stmt *recordingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return stmt.conn.ExecContext(ctx, stmt.query, args)
+}
+
+// QueryContext implements driver.StmtQueryContext for recordingStmt
+func (stmt *recordingStmt) QueryContext(ctx context.Context, _ []driver.NamedValue) (driver.Rows, error) {
+	return stmt.conn.query(ctx, stmt.query)
+}
+
+// recordingTx is a transaction of recordingDriver
+type recordingTx struct {
+	recording *recording
+}
+
+// Commit implements driver.Tx for recordingTx
+func (tx *recordingTx) Commit() error {
+	return tx.recording.record("COMMIT")
+}
+
+// Rollback implements driver.Tx for recordingTx
+func (tx *recordingTx) Rollback() error {
+	return tx.recording.record("ROLLBACK")
+}
+
+// emptyRows is a result set with no rows
+type emptyRows struct{}
+
+// Columns implements driver.Rows for emptyRows
+func (emptyRows) Columns() []string {
+	return nil
+}
+
+// Close implements driver.Rows for emptyRows
+func (emptyRows) Close() error {
+	return nil
+}
+
+// Next implements driver.Rows for emptyRows
+func (emptyRows) Next([]driver.Value) error {
+	return io.EOF
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	// defaultRetryBaseDelay is the delay before the first retry when a RetryPolicy doesn't set one
+	defaultRetryBaseDelay = 50 * time.Millisecond
+	// defaultRetryMaxDelay caps the delay between retries when a RetryPolicy doesn't set a cap
+	defaultRetryMaxDelay = time.Second
+)
+
+// DefaultRetryPolicy retries a transaction up to twice, waiting around 50ms and then around 100ms
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3}
+
+// transactionRetries counts the transactions retried by every RetryPolicy, for TransactionRetries
+var transactionRetries atomic.Uint64
+
+// RetryPolicy re-runs a transaction which failed because of contention with other transactions, such as a deadlock or
+// a lock wait timeout, as reported by IsRetryable. The zero value doesn't retry.
+type RetryPolicy struct {
+	// MaxAttempts is the most times the operation runs, including the first. Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, which doubles for every retry after it. Each delay is randomized
+	// by up to half, so transactions which collided don't collide again. It's 50ms if left zero.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts. It's 1s if left zero.
+	MaxDelay time.Duration
+}
+
+// delay returns how long to wait after the passed attempt failed, counting from 1
+func (policy RetryPolicy) delay(failedAttempt int) time.Duration {
+	baseDelay, maxDelay := policy.BaseDelay, policy.MaxDelay
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+
+	delay := baseDelay
+	for doubling := 1; doubling < failedAttempt && delay < maxDelay; doubling++ {
+		delay *= 2
+	}
+	delay = min(delay, maxDelay)
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// TransactionRetries returns how many times transactions have been retried since the application started
+func TransactionRetries() uint64 {
+	return transactionRetries.Load()
+}
+
+// IsRetryable reports whether an error means the transaction lost out to another one and would likely succeed if run
+// again, namely a deadlock or a lock wait timeout on MySQL and MariaDB, or a deadlock, serialization failure or lock
+// timeout on Postgres
+func IsRetryable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		// ER_LOCK_DEADLOCK and ER_LOCK_WAIT_TIMEOUT
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+
+	var postgresErr sqlStateError
+	if errors.As(err, &postgresErr) {
+		switch postgresErr.SQLState() {
+		// deadlock_detected, serialization_failure and lock_not_available
+		case "40P01", "40001", "55P03":
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/sample/commonlib/logger"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap/zapcore"
+)
+
+// deadlockErr is the error MySQL and MariaDB return when a transaction is chosen as a deadlock victim
+var deadlockErr = &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock; try restarting transaction"}
+
+type RetrySuite struct {
+	suite.Suite
+	recording *recording
+	dbContext context.Context
+	options   TxOptions
+}
+
+func TestRetrySuite(t *testing.T) {
+	suite.Run(t, new(RetrySuite))
+}
+
+func (suite *RetrySuite) SetupSuite() {
+	setupErr := logger.InitLogger(zapcore.InfoLevel, false)
+	suite.Require().NoError(setupErr)
+}
+
+func (suite *RetrySuite) SetupTest() {
+	db, rec := openRecording(suite.T())
+	suite.recording = rec
+	suite.dbContext = CreateDerivativeContext(context.Background(), db)
+	suite.options = TxOptions{Retry: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}}
+}
+
+// insert runs an insert statement on the connection in the passed context
+func (suite *RetrySuite) insert(ctx context.Context) error {
+	_, insertErr := RetrieveFromContext(ctx).Exec("INSERT INTO greetings (greetingText) VALUES ('G''day')")
+	return insertErr
+}
+
+func (suite *RetrySuite) TestRetriesDeadlocks() {
+	suite.recording.FailNext("INSERT", deadlockErr)
+	retriesBefore := TransactionRetries()
+
+	attempts := 0
+	txErr := WithTransactionOptions(suite.dbContext, suite.options, func(ctx context.Context) error {
+		attempts++
+		return suite.insert(ctx)
+	})
+
+	suite.Require().NoError(txErr)
+	suite.Assert().Equal(2, attempts)
+	suite.Assert().Equal(retriesBefore+1, TransactionRetries())
+	suite.Assert().Equal([]string{"BEGIN", "INSERT INTO greetings (greetingText) VALUES ('G''day')", "ROLLBACK",
+		"BEGIN", "INSERT INTO greetings (greetingText) VALUES ('G''day')", "COMMIT"}, suite.recording.Statements())
+}
+
+func (suite *RetrySuite) TestGivesUpAfterMaxAttempts() {
+	suite.recording.FailNext("INSERT", deadlockErr, deadlockErr, deadlockErr)
+
+	attempts := 0
+	_, txErr := WithTransactionReturningOptions(suite.dbContext, suite.options, func(ctx context.Context) (int, error) {
+		attempts++
+		return attempts, suite.insert(ctx)
+	})
+
+	suite.Assert().ErrorIs(txErr, deadlockErr)
+	suite.Assert().Equal(3, attempts)
+}
+
+func (suite *RetrySuite) TestDoesNotRetryOtherErrors() {
+	expectedErr := errors.New("oops")
+
+	attempts := 0
+	txErr := WithTransactionOptions(suite.dbContext, suite.options, func(ctx context.Context) error {
+		attempts++
+		return expectedErr
+	})
+
+	suite.Assert().ErrorIs(txErr, expectedErr)
+	suite.Assert().Equal(1, attempts)
+}
+
+func (suite *RetrySuite) TestDoesNotRetryByDefault() {
+	suite.recording.FailNext("INSERT", deadlockErr)
+
+	attempts := 0
+	txErr := WithTransaction(suite.dbContext, func(ctx context.Context) error {
+		attempts++
+		return suite.insert(ctx)
+	})
+
+	suite.Assert().ErrorIs(txErr, deadlockErr)
+	suite.Assert().Equal(1, attempts)
+}
+
+func (suite *RetrySuite) TestOnlyOutermostTransactionRetries() {
+	suite.recording.FailNext("INSERT", deadlockErr)
+
+	outerAttempts, innerAttempts := 0, 0
+	txErr := WithTransactionOptions(suite.dbContext, suite.options, func(ctx context.Context) error {
+		outerAttempts++
+		return WithTransactionOptions(ctx, suite.options, func(ctx context.Context) error {
+			innerAttempts++
+			return suite.insert(ctx)
+		})
+	})
+
+	suite.Require().NoError(txErr)
+	suite.Assert().Equal(2, outerAttempts)
+	suite.Assert().Equal(2, innerAttempts)
+}
+
+func (suite *RetrySuite) TestStopsWhenContextIsDone() {
+	suite.recording.FailNext("INSERT", deadlockErr)
+	ctx, cancel := context.WithCancel(suite.dbContext)
+	suite.options.Retry.BaseDelay = time.Hour
+
+	attempts := 0
+	txErr := WithTransactionOptions(ctx, suite.options, func(ctx context.Context) error {
+		attempts++
+		// Cancel once the statement has run, since statements are cancelled along with the context
+		insertErr := suite.insert(ctx)
+		cancel()
+		return insertErr
+	})
+
+	suite.Assert().ErrorIs(txErr, deadlockErr)
+	suite.Assert().ErrorIs(txErr, context.Canceled)
+	suite.Assert().Equal(1, attempts)
+}
+
+func (suite *RetrySuite) TestDelayBacksOff() {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+
+	suite.Assert().InDelta(75*time.Millisecond, policy.delay(1), float64(25*time.Millisecond))
+	suite.Assert().InDelta(150*time.Millisecond, policy.delay(2), float64(50*time.Millisecond))
+	suite.Assert().InDelta(225*time.Millisecond, policy.delay(3), float64(75*time.Millisecond))
+	suite.Assert().InDelta(225*time.Millisecond, policy.delay(10), float64(75*time.Millisecond))
+}
+
+func (suite *RetrySuite) TestIsRetryable() {
+	suite.Assert().True(IsRetryable(deadlockErr))
+	suite.Assert().True(IsRetryable(&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}))
+	suite.Assert().True(IsRetryable(postgresError{state: "40001"}))
+	suite.Assert().False(IsRetryable(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}))
+	suite.Assert().False(IsRetryable(postgresError{state: "23505"}))
+	suite.Assert().False(IsRetryable(errors.New("oops")))
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ctxRouteKey is where an explicit choice between the primary and the replicas of a Cluster is stored in a context
+type ctxRouteKey struct{}
+
+// ctxPinKey is where the flag recording that a request has written to the primary of a Cluster is stored
+type ctxPinKey struct{}
+
+// route chooses which databases of a Cluster serve a context
+type route int
+
+const (
+	// routeAutomatic sends reads to a replica and writes and transactions to the primary
+	routeAutomatic route = iota
+	// routePrimary sends everything to the primary
+	routePrimary
+	// routeReadOnly sends everything to a replica
+	routeReadOnly
+)
+
+// ReadOnly derives a context whose database work is served by a read replica, including transactions and prepared
+// statements, even if the request has already written. Writes fail if the replica is read-only, so only use it for
+// work which doesn't write and can tolerate replication lag. It has no effect without a Cluster in the context, or
+// inside a transaction which has already started.
+func ReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxRouteKey{}, routeReadOnly)
+}
+
+// Primary derives a context whose database work is served by the primary, including reads, for work which must see
+// the very latest data. It has no effect without a Cluster in the context.
+func Primary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxRouteKey{}, routePrimary)
+}
+
+// CreateDerivativeClusterContext derives a database context from another context, embedding a Cluster so that
+// RetrieveFromContext routes work between its primary and replicas. Call it once per request, since a request's reads
+// are pinned to the primary once it writes if the cluster has ReadYourWrites enabled. The cluster's Instrumentation and
+// StatementTimeout, if it has them, are attached too.
+func CreateDerivativeClusterContext(ctx context.Context, cluster *Cluster) context.Context {
+	// Don't overwrite the DB if it's already present in the context
+	if ctx.Value(ctxConnectionKey{}) != nil {
+		return ctx
+	}
+
+	clusterCtx := context.WithValue(ctx, ctxConnectionKey{}, cluster)
+	if cluster.ReadYourWrites {
+		clusterCtx = context.WithValue(clusterCtx, ctxPinKey{}, new(atomic.Bool))
+	}
+	if cluster.Instrumentation != nil {
+		clusterCtx = WithInstrumentation(clusterCtx, cluster.Instrumentation)
+	}
+	if cluster.StatementTimeout > 0 {
+		clusterCtx = WithStatementTimeout(clusterCtx, cluster.StatementTimeout)
+	}
+	return clusterCtx
+}
+
+// routeConnection picks the databases of a Cluster which serve the passed context
+func routeConnection(ctx context.Context, cluster *Cluster) *clusterConnection {
+	pinned, _ := ctx.Value(ctxPinKey{}).(*atomic.Bool)
+	selectedRoute, _ := ctx.Value(ctxRouteKey{}).(route)
+
+	switch {
+	case selectedRoute == routeReadOnly:
+		replica := cluster.Replica()
+		return &clusterConnection{reader: replica, writer: replica}
+	case selectedRoute == routePrimary, pinned != nil && pinned.Load():
+		return &clusterConnection{reader: cluster.Primary, writer: cluster.Primary, pinned: pinned}
+	default:
+		return &clusterConnection{reader: cluster.Replica(), writer: cluster.Primary, pinned: pinned}
+	}
+}
+
+// clusterConnection implements Connection for a Cluster, sending reads to one database and anything which may write,
+// including prepared statements, to another. Writing pins the rest of the request to the primary if the cluster has
+// ReadYourWrites enabled.
+type clusterConnection struct {
+	reader *sqlx.DB
+	writer *sqlx.DB
+	pinned *atomic.Bool
+}
+
+// write returns the database to write to, pinning the request to it
+func (cxn *clusterConnection) write() *sqlx.DB {
+	if cxn.pinned != nil {
+		cxn.pinned.Store(true)
+	}
+	return cxn.writer
+}
+
+// Get implements Connection for clusterConnection
+func (cxn *clusterConnection) Get(dest any, query string, args ...any) error {
+	return cxn.reader.Get(dest, query, args...)
+}
+
+// GetContext implements Connection for clusterConnection
+func (cxn *clusterConnection) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return cxn.reader.GetContext(ctx, dest, query, args...)
+}
+
+// Select implements Connection for clusterConnection
+func (cxn *clusterConnection) Select(dest any, query string, args ...any) error {
+	return cxn.reader.Select(dest, query, args...)
+}
+
+// SelectContext implements Connection for clusterConnection
+func (cxn *clusterConnection) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return cxn.reader.SelectContext(ctx, dest, query, args...)
+}
+
+// Exec implements Connection for clusterConnection
+func (cxn *clusterConnection) Exec(query string, args ...any) (sql.Result, error) {
+	return cxn.write().Exec(query, args...)
+}
+
+// ExecContext implements Connection for clusterConnection
+func (cxn *clusterConnection) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return cxn.write().ExecContext(ctx, query, args...)
+}
+
+// NamedExec implements Connection for clusterConnection
+func (cxn *clusterConnection) NamedExec(query string, arg any) (sql.Result, error) {
+	return cxn.write().NamedExec(query, arg)
+}
+
+// NamedExecContext implements Connection for clusterConnection
+func (cxn *clusterConnection) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
+	return cxn.write().NamedExecContext(ctx, query, arg)
+}
+
+// Preparex implements Connection for clusterConnection. The statement may write, so it's prepared on the writer.
+func (cxn *clusterConnection) Preparex(query string) (*sqlx.Stmt, error) {
+	return cxn.write().Preparex(query)
+}
+
+// PreparexContext implements Connection for clusterConnection. The statement may write, so it's prepared on the writer.
+func (cxn *clusterConnection) PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error) {
+	return cxn.write().PreparexContext(ctx, query)
+}
+
+// PrepareNamed implements Connection for clusterConnection. The statement may write, so it's prepared on the writer.
+func (cxn *clusterConnection) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
+	return cxn.write().PrepareNamed(query)
+}
+
+// PrepareNamedContext implements Connection for clusterConnection. The statement may write, so it's prepared on the
+// writer.
+func (cxn *clusterConnection) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	return cxn.write().PrepareNamedContext(ctx, query)
+}
+
+// Queryx implements Connection for clusterConnection
+func (cxn *clusterConnection) Queryx(query string, args ...any) (*sqlx.Rows, error) {
+	return cxn.reader.Queryx(query, args...)
+}
+
+// QueryxContext implements Connection for clusterConnection
+func (cxn *clusterConnection) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	return cxn.reader.QueryxContext(ctx, query, args...)
+}
+
+// NamedQuery implements Connection for clusterConnection
+func (cxn *clusterConnection) NamedQuery(query string, arg any) (*sqlx.Rows, error) {
+	return cxn.reader.NamedQuery(query, arg)
+}
+
+// Rebind implements Connection for clusterConnection
+func (cxn *clusterConnection) Rebind(query string) string {
+	return cxn.reader.Rebind(query)
+}
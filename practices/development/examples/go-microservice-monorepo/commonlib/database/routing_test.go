@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/suite"
+)
+
+type RoutingSuite struct {
+	suite.Suite
+	primary  *sqlx.DB
+	replicas []*sqlx.DB
+	cluster  *Cluster
+}
+
+func TestRoutingSuite(t *testing.T) {
+	suite.Run(t, new(RoutingSuite))
+}
+
+func (suite *RoutingSuite) SetupTest() {
+	// Connections are never opened, so the fake Postgres driver is enough to tell the databases apart
+	suite.primary = sqlx.MustOpen("postgres", "primary")
+	suite.replicas = []*sqlx.DB{sqlx.MustOpen("postgres", "replica1"), sqlx.MustOpen("postgres", "replica2")}
+	suite.cluster = NewCluster(suite.primary, suite.replicas...)
+}
+
+func (suite *RoutingSuite) TearDownTest() {
+	suite.Require().NoError(suite.cluster.Close())
+}
+
+// retrieve retrieves the cluster connection from a context
+func (suite *RoutingSuite) retrieve(ctx context.Context) *clusterConnection {
+	cxn, isClusterConnection := RetrieveFromContext(ctx).(*clusterConnection)
+	suite.Require().True(isClusterConnection)
+	return cxn
+}
+
+func (suite *RoutingSuite) TestReplicasTakeTurns() {
+	suite.Assert().Same(suite.replicas[0], suite.cluster.Replica())
+	suite.Assert().Same(suite.replicas[1], suite.cluster.Replica())
+	suite.Assert().Same(suite.replicas[0], suite.cluster.Replica())
+}
+
+func (suite *RoutingSuite) TestReadsFromPrimaryWithoutReplicas() {
+	cluster := NewCluster(suite.primary)
+
+	suite.Assert().Same(suite.primary, cluster.Replica())
+}
+
+func (suite *RoutingSuite) TestReadsFromReplicaAndWritesToPrimary() {
+	ctx := CreateDerivativeClusterContext(context.Background(), suite.cluster)
+	cxn := suite.retrieve(ctx)
+
+	suite.Assert().Same(suite.replicas[0], cxn.reader)
+	suite.Assert().Same(suite.primary, cxn.writer)
+}
+
+func (suite *RoutingSuite) TestPrimaryOverride() {
+	ctx := Primary(CreateDerivativeClusterContext(context.Background(), suite.cluster))
+	cxn := suite.retrieve(ctx)
+
+	suite.Assert().Same(suite.primary, cxn.reader)
+	suite.Assert().Same(suite.primary, cxn.writer)
+}
+
+func (suite *RoutingSuite) TestReadOnlyOverride() {
+	ctx := ReadOnly(CreateDerivativeClusterContext(context.Background(), suite.cluster))
+	cxn := suite.retrieve(ctx)
+
+	suite.Assert().Same(suite.replicas[0], cxn.reader)
+	suite.Assert().Same(suite.replicas[0], cxn.writer)
+}
+
+func (suite *RoutingSuite) TestWritingPinsRequestToPrimary() {
+	ctx := CreateDerivativeClusterContext(context.Background(), suite.cluster)
+	// The fake driver can't connect, but the request is pinned as soon as it tries to write
+	_, _ = suite.retrieve(ctx).Exec("UPDATE greetings SET greetingText = ?", "G'day")
+
+	suite.Assert().Same(suite.primary, suite.retrieve(ctx).reader)
+	suite.Assert().Contains(suite.replicas, suite.retrieve(ReadOnly(ctx)).reader, "ReadOnly overrides the pin")
+
+	otherRequestCtx := CreateDerivativeClusterContext(context.Background(), suite.cluster)
+	suite.Assert().Contains(suite.replicas, suite.retrieve(otherRequestCtx).reader, "Other requests aren't pinned")
+}
+
+func (suite *RoutingSuite) TestWritingWithoutReadYourWrites() {
+	suite.cluster.ReadYourWrites = false
+	ctx := CreateDerivativeClusterContext(context.Background(), suite.cluster)
+	_, _ = suite.retrieve(ctx).Exec("UPDATE greetings SET greetingText = ?", "G'day")
+
+	suite.Assert().Contains(suite.replicas, suite.retrieve(ctx).reader)
+}
+
+func (suite *RoutingSuite) TestReplicaConfig() {
+	primaryPort := 3306
+	primaryConfig := Config{Host: "primary.example.com", OptionalSettings: OptionalSettings{Port: &primaryPort}}
+
+	withPort := replicaConfig(primaryConfig, "replica.example.com:3307")
+	suite.Assert().Equal("replica.example.com", withPort.Host)
+	suite.Assert().Equal(3307, *withPort.OptionalSettings.Port)
+
+	withoutPort := replicaConfig(primaryConfig, "replica.example.com")
+	suite.Assert().Equal("replica.example.com", withoutPort.Host)
+	suite.Assert().Equal(3306, *withoutPort.OptionalSettings.Port)
+}
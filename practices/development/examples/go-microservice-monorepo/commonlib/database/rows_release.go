@@ -0,0 +1,351 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+)
+
+// ctxReleaseRowsKey is where the function releasing the context of a statement returning rows is stored, so the rows
+// can release it once they're closed rather than once the statement timeout passes
+type ctxReleaseRowsKey struct{}
+
+// releaseRowsConnector wraps the connector of a database opened by Connect, so the rows returned by its connections
+// release the context they were queried with when they're closed. database/sql returns rows as a concrete type, so
+// closing them can't be noticed anywhere above the driver.
+type releaseRowsConnector struct {
+	inner driver.Connector
+}
+
+// releaseRows wraps a connector so its rows release their context when closed
+func releaseRows(connector driver.Connector) driver.Connector {
+	return releaseRowsConnector{inner: connector}
+}
+
+// registeredConnector returns a connector for a driver registered with database/sql under the passed name
+func registeredConnector(driverName string, dsn string) (driver.Connector, error) {
+	registered, openErr := sql.Open(driverName, dsn)
+	if openErr != nil {
+		return nil, openErr
+	}
+	registeredDriver := registered.Driver()
+	_ = registered.Close()
+
+	if driverCtx, hasConnector := registeredDriver.(driver.DriverContext); hasConnector {
+		return driverCtx.OpenConnector(dsn)
+	}
+	return dsnConnector{dsn: dsn, driver: registeredDriver}, nil
+}
+
+// Connect implements driver.Connector for releaseRowsConnector
+func (connector releaseRowsConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, connErr := connector.inner.Connect(ctx)
+	if connErr != nil {
+		return nil, connErr
+	}
+	return &releaseRowsConn{inner: conn}, nil
+}
+
+// Driver implements driver.Connector for releaseRowsConnector
+func (connector releaseRowsConnector) Driver() driver.Driver {
+	return connector.inner.Driver()
+}
+
+// dsnConnector opens connections of a driver which doesn't implement driver.DriverContext
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+// Connect implements driver.Connector for dsnConnector
+func (connector dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return connector.driver.Open(connector.dsn)
+}
+
+// Driver implements driver.Connector for dsnConnector
+func (connector dsnConnector) Driver() driver.Driver {
+	return connector.driver
+}
+
+// releaseRowsConn passes every call to the driver's connection, wrapping the rows and statements it returns. Optional
+// interfaces the driver's connection doesn't implement fall back to what database/sql would do without them.
+type releaseRowsConn struct {
+	inner driver.Conn
+}
+
+// Prepare implements driver.Conn for releaseRowsConn
+func (conn *releaseRowsConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, prepareErr := conn.inner.Prepare(query)
+	if prepareErr != nil {
+		return nil, prepareErr
+	}
+	return &releaseRowsStmt{inner: stmt, conn: conn}, nil
+}
+
+// PrepareContext implements driver.ConnPrepareContext for releaseRowsConn
+func (conn *releaseRowsConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	preparer, hasContext := conn.inner.(driver.ConnPrepareContext)
+	if !hasContext {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return conn.Prepare(query)
+	}
+
+	stmt, prepareErr := preparer.PrepareContext(ctx, query)
+	if prepareErr != nil {
+		return nil, prepareErr
+	}
+	return &releaseRowsStmt{inner: stmt, conn: conn}, nil
+}
+
+// Close implements driver.Conn for releaseRowsConn
+func (conn *releaseRowsConn) Close() error {
+	return conn.inner.Close()
+}
+
+// Begin implements driver.Conn for releaseRowsConn
+func (conn *releaseRowsConn) Begin() (driver.Tx, error) {
+	return conn.inner.Begin()
+}
+
+// BeginTx implements driver.ConnBeginTx for releaseRowsConn
+func (conn *releaseRowsConn) BeginTx(ctx context.Context, options driver.TxOptions) (driver.Tx, error) {
+	if beginner, hasContext := conn.inner.(driver.ConnBeginTx); hasContext {
+		return beginner.BeginTx(ctx, options)
+	}
+	if options.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sql: driver does not support non-default isolation level")
+	}
+	if options.ReadOnly {
+		return nil, errors.New("sql: driver does not support read-only transactions")
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return conn.Begin()
+}
+
+// ExecContext implements driver.ExecerContext for releaseRowsConn
+func (conn *releaseRowsConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if execer, hasContext := conn.inner.(driver.ExecerContext); hasContext {
+		return execer.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+// QueryContext implements driver.QueryerContext for releaseRowsConn
+func (conn *releaseRowsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, hasContext := conn.inner.(driver.QueryerContext)
+	if !hasContext {
+		return nil, driver.ErrSkip
+	}
+	return releasingRows(ctx)(queryer.QueryContext(ctx, query, args))
+}
+
+// Ping implements driver.Pinger for releaseRowsConn
+func (conn *releaseRowsConn) Ping(ctx context.Context) error {
+	if pinger, canPing := conn.inner.(driver.Pinger); canPing {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession implements driver.SessionResetter for releaseRowsConn
+func (conn *releaseRowsConn) ResetSession(ctx context.Context) error {
+	if resetter, canReset := conn.inner.(driver.SessionResetter); canReset {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid implements driver.Validator for releaseRowsConn
+func (conn *releaseRowsConn) IsValid() bool {
+	if validator, canValidate := conn.inner.(driver.Validator); canValidate {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue implements driver.NamedValueChecker for releaseRowsConn
+func (conn *releaseRowsConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, canCheck := conn.inner.(driver.NamedValueChecker); canCheck {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// releaseRowsStmt passes every call to the driver's prepared statement, wrapping the rows it returns. Drivers such as
+// MySQL's prepare statements with arguments rather than running them directly.
+type releaseRowsStmt struct {
+	inner driver.Stmt
+	conn  *releaseRowsConn
+}
+
+// Close implements driver.Stmt for releaseRowsStmt
+func (stmt *releaseRowsStmt) Close() error {
+	return stmt.inner.Close()
+}
+
+// NumInput implements driver.Stmt for releaseRowsStmt
+func (stmt *releaseRowsStmt) NumInput() int {
+	return stmt.inner.NumInput()
+}
+
+// Exec implements driver.Stmt for releaseRowsStmt
+func (stmt *releaseRowsStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return stmt.inner.Exec(args)
+}
+
+// Query implements driver.Stmt for releaseRowsStmt
+func (stmt *releaseRowsStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return stmt.inner.Query(args)
+}
+
+// ExecContext implements driver.StmtExecContext for releaseRowsStmt
+func (stmt *releaseRowsStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if execer, hasContext := stmt.inner.(driver.StmtExecContext); hasContext {
+		return execer.ExecContext(ctx, args)
+	}
+	values, valuesErr := namedValuesToValues(args)
+	if valuesErr != nil {
+		return nil, valuesErr
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return stmt.Exec(values)
+}
+
+// QueryContext implements driver.StmtQueryContext for releaseRowsStmt
+func (stmt *releaseRowsStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, hasContext := stmt.inner.(driver.StmtQueryContext); hasContext {
+		return releasingRows(ctx)(queryer.QueryContext(ctx, args))
+	}
+	values, valuesErr := namedValuesToValues(args)
+	if valuesErr != nil {
+		return nil, valuesErr
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return releasingRows(ctx)(stmt.Query(values))
+}
+
+// CheckNamedValue implements driver.NamedValueChecker for releaseRowsStmt. database/sql checks the connection's
+// arguments if its statement can't, so the wrapper does too.
+func (stmt *releaseRowsStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, canCheck := stmt.inner.(driver.NamedValueChecker); canCheck {
+		return checker.CheckNamedValue(value)
+	}
+	return stmt.conn.CheckNamedValue(value)
+}
+
+// ColumnConverter implements driver.ColumnConverter for releaseRowsStmt
+func (stmt *releaseRowsStmt) ColumnConverter(index int) driver.ValueConverter {
+	if converter, canConvert := stmt.inner.(driver.ColumnConverter); canConvert {
+		return converter.ColumnConverter(index)
+	}
+	return driver.DefaultParameterConverter
+}
+
+// namedValuesToValues converts arguments for drivers which don't support named arguments
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for index, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sql: driver does not support the use of Named Parameters")
+		}
+		values[index] = arg.Value
+	}
+	return values, nil
+}
+
+// releasingRows returns a function wrapping the rows returned by a query run with the passed context, so closing them
+// releases the context. If the query failed, the context is released immediately, unless the driver skipped it with
+// driver.ErrSkip, which makes database/sql prepare the statement and run it with the same context instead.
+func releasingRows(ctx context.Context) func(rows driver.Rows, queryErr error) (driver.Rows, error) {
+	return func(rows driver.Rows, queryErr error) (driver.Rows, error) {
+		release, releasable := ctx.Value(ctxReleaseRowsKey{}).(context.CancelFunc)
+		if !releasable || errors.Is(queryErr, driver.ErrSkip) {
+			return rows, queryErr
+		}
+		if queryErr != nil {
+			release()
+			return nil, queryErr
+		}
+		return &releaseRowsRows{Rows: rows, release: release}, nil
+	}
+}
+
+// releaseRowsRows releases the context its rows were queried with when they're closed. Optional interfaces the driver's
+// rows don't implement fall back to what database/sql would do without them.
+type releaseRowsRows struct {
+	driver.Rows
+	release context.CancelFunc
+}
+
+// Close implements driver.Rows for releaseRowsRows
+func (rows *releaseRowsRows) Close() error {
+	defer rows.release()
+	return rows.Rows.Close()
+}
+
+// HasNextResultSet implements driver.RowsNextResultSet for releaseRowsRows
+func (rows *releaseRowsRows) HasNextResultSet() bool {
+	if resultSets, hasResultSets := rows.Rows.(driver.RowsNextResultSet); hasResultSets {
+		return resultSets.HasNextResultSet()
+	}
+	return false
+}
+
+// NextResultSet implements driver.RowsNextResultSet for releaseRowsRows
+func (rows *releaseRowsRows) NextResultSet() error {
+	if resultSets, hasResultSets := rows.Rows.(driver.RowsNextResultSet); hasResultSets {
+		return resultSets.NextResultSet()
+	}
+	return io.EOF
+}
+
+// ColumnTypeScanType implements driver.RowsColumnTypeScanType for releaseRowsRows
+func (rows *releaseRowsRows) ColumnTypeScanType(index int) reflect.Type {
+	if columnTypes, hasTypes := rows.Rows.(driver.RowsColumnTypeScanType); hasTypes {
+		return columnTypes.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(any)).Elem()
+}
+
+// ColumnTypeDatabaseTypeName implements driver.RowsColumnTypeDatabaseTypeName for releaseRowsRows
+func (rows *releaseRowsRows) ColumnTypeDatabaseTypeName(index int) string {
+	if columnTypes, hasTypes := rows.Rows.(driver.RowsColumnTypeDatabaseTypeName); hasTypes {
+		return columnTypes.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+// ColumnTypeLength implements driver.RowsColumnTypeLength for releaseRowsRows
+func (rows *releaseRowsRows) ColumnTypeLength(index int) (int64, bool) {
+	if columnTypes, hasTypes := rows.Rows.(driver.RowsColumnTypeLength); hasTypes {
+		return columnTypes.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+// ColumnTypeNullable implements driver.RowsColumnTypeNullable for releaseRowsRows
+func (rows *releaseRowsRows) ColumnTypeNullable(index int) (bool, bool) {
+	if columnTypes, hasTypes := rows.Rows.(driver.RowsColumnTypeNullable); hasTypes {
+		return columnTypes.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+// ColumnTypePrecisionScale implements driver.RowsColumnTypePrecisionScale for releaseRowsRows
+func (rows *releaseRowsRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if columnTypes, hasTypes := rows.Rows.(driver.RowsColumnTypePrecisionScale); hasTypes {
+		return columnTypes.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ctxStatementTimeoutKey is where the timeout of statements run in a database context is stored
+type ctxStatementTimeoutKey struct{}
+
+// WithStatementTimeout derives a context whose statements are cancelled if they take longer than the passed timeout.
+// It applies to Connections retrieved from the context by RetrieveFromContext, and to the context methods of any
+// Connection when passed the derived context. Zero disables the timeout. Use it to override the default timeout of a
+// Cluster for a single call:
+//
+//	reportErr := database.RetrieveFromContext(database.WithStatementTimeout(ctx, 2*time.Minute)).Select(&rows, reportQuery)
+func WithStatementTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, ctxStatementTimeoutKey{}, timeout)
+}
+
+// boundConnection implements Connection by running every statement with a context derived from the one the connection
+// was retrieved from, so statements are cancelled along with the request and time out after the context's statement
+// timeout. Methods which accept a context use theirs instead, still applying the statement timeout.
+type boundConnection struct {
+	inner   Connection
+	ctx     context.Context
+	timeout time.Duration
+}
+
+// bind binds a connection to the passed context, unless it couldn't make a difference or the connection is a mock,
+// whose expectations name the methods adapters call
+func bind(ctx context.Context, connection Connection) Connection {
+	timeout, _ := ctx.Value(ctxStatementTimeoutKey{}).(time.Duration)
+	if _, isMock := connection.(*MockConnection); isMock || (ctx.Done() == nil && timeout <= 0) {
+		return connection
+	}
+
+	return &boundConnection{inner: connection, ctx: ctx, timeout: timeout}
+}
+
+// timeoutFor returns the statement timeout of statements run with the passed context, which has a timeout of its own
+// if it was derived with WithStatementTimeout
+func (cxn *boundConnection) timeoutFor(ctx context.Context) time.Duration {
+	if override, hasOverride := ctx.Value(ctxStatementTimeoutKey{}).(time.Duration); hasOverride {
+		return override
+	}
+	return cxn.timeout
+}
+
+// statementContext derives the context a statement runs with from the passed one. Call the returned function once the
+// statement is finished.
+func (cxn *boundConnection) statementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := cxn.timeoutFor(ctx)
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// rowsContext derives the context a statement returning rows runs with. The rows are closed when the context is
+// cancelled, so the statement timeout covers iterating over the rows too. Rows of a database opened by Connect release
+// the context when they're closed, otherwise it's released once the statement timeout passes. Call the returned
+// function if the statement fails.
+func (cxn *boundConnection) rowsContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := cxn.timeoutFor(ctx)
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	rowsCtx, cancel := context.WithTimeout(ctx, timeout)
+	return context.WithValue(rowsCtx, ctxReleaseRowsKey{}, cancel), cancel
+}
+
+// Get implements Connection for boundConnection
+func (cxn *boundConnection) Get(dest any, query string, args ...any) error {
+	return cxn.GetContext(cxn.ctx, dest, query, args...)
+}
+
+// GetContext implements Connection for boundConnection
+func (cxn *boundConnection) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	stmtCtx, cancel := cxn.statementContext(ctx)
+	defer cancel()
+	return cxn.inner.GetContext(stmtCtx, dest, query, args...)
+}
+
+// Select implements Connection for boundConnection
+func (cxn *boundConnection) Select(dest any, query string, args ...any) error {
+	return cxn.SelectContext(cxn.ctx, dest, query, args...)
+}
+
+// SelectContext implements Connection for boundConnection
+func (cxn *boundConnection) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	stmtCtx, cancel := cxn.statementContext(ctx)
+	defer cancel()
+	return cxn.inner.SelectContext(stmtCtx, dest, query, args...)
+}
+
+// Exec implements Connection for boundConnection
+func (cxn *boundConnection) Exec(query string, args ...any) (sql.Result, error) {
+	return cxn.ExecContext(cxn.ctx, query, args...)
+}
+
+// ExecContext implements Connection for boundConnection
+func (cxn *boundConnection) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	stmtCtx, cancel := cxn.statementContext(ctx)
+	defer cancel()
+	return cxn.inner.ExecContext(stmtCtx, query, args...)
+}
+
+// NamedExec implements Connection for boundConnection
+func (cxn *boundConnection) NamedExec(query string, arg any) (sql.Result, error) {
+	return cxn.NamedExecContext(cxn.ctx, query, arg)
+}
+
+// NamedExecContext implements Connection for boundConnection
+func (cxn *boundConnection) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
+	stmtCtx, cancel := cxn.statementContext(ctx)
+	defer cancel()
+	return cxn.inner.NamedExecContext(stmtCtx, query, arg)
+}
+
+// Preparex implements Connection for boundConnection. Only preparing the statement is bound to the context, not
+// running it.
+func (cxn *boundConnection) Preparex(query string) (*sqlx.Stmt, error) {
+	return cxn.PreparexContext(cxn.ctx, query)
+}
+
+// PreparexContext implements Connection for boundConnection
+func (cxn *boundConnection) PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error) {
+	stmtCtx, cancel := cxn.statementContext(ctx)
+	defer cancel()
+	return cxn.inner.PreparexContext(stmtCtx, query)
+}
+
+// PrepareNamed implements Connection for boundConnection. Only preparing the statement is bound to the context, not
+// running it.
+func (cxn *boundConnection) PrepareNamed(query string) (*sqlx.NamedStmt, error) {
+	return cxn.PrepareNamedContext(cxn.ctx, query)
+}
+
+// PrepareNamedContext implements Connection for boundConnection
+func (cxn *boundConnection) PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error) {
+	stmtCtx, cancel := cxn.statementContext(ctx)
+	defer cancel()
+	return cxn.inner.PrepareNamedContext(stmtCtx, query)
+}
+
+// Queryx implements Connection for boundConnection
+func (cxn *boundConnection) Queryx(query string, args ...any) (*sqlx.Rows, error) {
+	return cxn.QueryxContext(cxn.ctx, query, args...)
+}
+
+// QueryxContext implements Connection for boundConnection
+func (cxn *boundConnection) QueryxContext(ctx context.Context, query string, args ...any) (*sqlx.Rows, error) {
+	rowsCtx, cancel := cxn.rowsContext(ctx)
+	rows, queryErr := cxn.inner.QueryxContext(rowsCtx, query, args...)
+	if queryErr != nil {
+		cancel()
+	}
+	return rows, queryErr
+}
+
+// NamedQuery implements Connection for boundConnection
+func (cxn *boundConnection) NamedQuery(query string, arg any) (*sqlx.Rows, error) {
+	return NamedQueryContext(cxn.ctx, cxn, query, arg)
+}
+
+// Rebind implements Connection for boundConnection
+func (cxn *boundConnection) Rebind(query string) string {
+	return cxn.inner.Rebind(query)
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type StatementTimeoutSuite struct {
+	suite.Suite
+	db        *sqlx.DB
+	recording *recording
+}
+
+func TestStatementTimeoutSuite(t *testing.T) {
+	suite.Run(t, new(StatementTimeoutSuite))
+}
+
+func (suite *StatementTimeoutSuite) SetupTest() {
+	suite.db, suite.recording = openRecording(suite.T())
+	suite.recording.BlockOn("SELECT SLEEP")
+}
+
+func (suite *StatementTimeoutSuite) TestStatementsAreCancelledWithTheContext() {
+	ctx, cancel := context.WithCancel(CreateDerivativeContext(context.Background(), suite.db))
+	cxn := RetrieveFromContext(ctx)
+	cancel()
+
+	_, execErr := cxn.Exec("SELECT SLEEP(60)")
+
+	suite.Assert().ErrorIs(execErr, context.Canceled)
+}
+
+func (suite *StatementTimeoutSuite) TestStatementsTimeOut() {
+	ctx := WithStatementTimeout(CreateDerivativeContext(context.Background(), suite.db), 10*time.Millisecond)
+
+	var slept []int
+	selectErr := RetrieveFromContext(ctx).Select(&slept, "SELECT SLEEP(60)")
+
+	suite.Assert().ErrorIs(selectErr, context.DeadlineExceeded)
+}
+
+func (suite *StatementTimeoutSuite) TestTimeoutCanBeOverriddenPerCall() {
+	ctx := WithStatementTimeout(CreateDerivativeContext(context.Background(), suite.db), time.Hour)
+	cxn := RetrieveFromContext(ctx)
+
+	_, execErr := cxn.ExecContext(WithStatementTimeout(ctx, 10*time.Millisecond), "SELECT SLEEP(60)")
+
+	suite.Assert().ErrorIs(execErr, context.DeadlineExceeded)
+}
+
+func (suite *StatementTimeoutSuite) TestClusterSetsTheDefaultTimeout() {
+	cluster := NewCluster(suite.db)
+	cluster.StatementTimeout = 10 * time.Millisecond
+	ctx := CreateDerivativeClusterContext(context.Background(), cluster)
+
+	var slept int
+	getErr := RetrieveFromContext(ctx).Get(&slept, "SELECT SLEEP(60)")
+
+	suite.Assert().ErrorIs(getErr, context.DeadlineExceeded)
+}
+
+func (suite *StatementTimeoutSuite) TestRowsOutliveTheCall() {
+	ctx := WithStatementTimeout(CreateDerivativeContext(context.Background(), suite.db), time.Hour)
+
+	rows, queryErr := RetrieveFromContext(ctx).Queryx("SELECT greetingText FROM greetings")
+	suite.Require().NoError(queryErr)
+	defer rows.Close()
+
+	suite.Assert().False(rows.Next())
+	suite.Assert().NoError(rows.Err())
+}
+
+func (suite *StatementTimeoutSuite) TestRowsReleaseTheirContextWhenClosed() {
+	ctx := WithStatementTimeout(CreateDerivativeContext(context.Background(), suite.db), time.Hour)
+
+	rows, queryErr := RetrieveFromContext(ctx).Queryx("SELECT greetingText FROM greetings")
+	suite.Require().NoError(queryErr)
+	rowsCtx := suite.recording.LastQueryContext()
+	suite.Require().NoError(rowsCtx.Err(), "The context lives as long as the rows")
+	suite.Require().NoError(rows.Close())
+
+	suite.Assert().ErrorIs(rowsCtx.Err(), context.Canceled)
+}
+
+func (suite *StatementTimeoutSuite) TestQueriesWithArgumentsAreNotReleasedEarly() {
+	ctx := WithStatementTimeout(CreateDerivativeContext(context.Background(), suite.db), time.Hour)
+
+	rows, queryErr := RetrieveFromContext(ctx).Queryx("SELECT greetingText FROM greetings WHERE id = ?", 1)
+	suite.Require().NoError(queryErr, "The driver skipping the query isn't a failure")
+	rowsCtx := suite.recording.LastQueryContext()
+	suite.Require().NoError(rowsCtx.Err())
+	suite.Require().NoError(rows.Close())
+
+	suite.Assert().ErrorIs(rowsCtx.Err(), context.Canceled)
+}
+
+func (suite *StatementTimeoutSuite) TestFailedQueriesReleaseTheirContext() {
+	suite.recording.FailNext("SELECT", errors.New("query failed"))
+	ctx := WithStatementTimeout(CreateDerivativeContext(context.Background(), suite.db), time.Hour)
+
+	_, queryErr := RetrieveFromContext(ctx).NamedQuery("SELECT greetingText FROM greetings", map[string]any{})
+	suite.Require().Error(queryErr)
+
+	suite.Assert().ErrorIs(suite.recording.LastQueryContext().Err(), context.Canceled)
+}
+
+func (suite *StatementTimeoutSuite) TestMockConnectionsAreNotBound() {
+	mockConnection := NewMockConnection(gomock.NewController(suite.T()))
+	ctx := WithStatementTimeout(CreateDerivativeMockContext(context.Background(), mockConnection), time.Second)
+
+	suite.Assert().Same(mockConnection, RetrieveFromContext(ctx))
+}
@@ -1,7 +1,9 @@
 package database
 
 import (
+	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"example.com/sample/commonlib/logger"
@@ -11,26 +13,63 @@ import (
 )
 
 // MustBeConnected asserts that the database is actually connected. It attempts to ping the database once every 10 seconds
-// for 5 minutes, and if a connection is never successfully established this function panics and kills the server.
+// for 5 minutes, and if a connection is never successfully established, or the database reports the connection options
+// are wrong, this function logs a fatal error and kills the server.
+//
+// Deprecated: use a ConnectivityChecker, which can be cancelled and leaves the decision to shut down to the caller.
 func MustBeConnected(db *sqlx.DB) {
-	startTime := time.Now()
-	for {
-		// Give up after 5 minutes
-		if time.Now().After(startTime.Add(5 * time.Minute)) {
-			logger.Log.Fatal("Database could not be reached after 5 minutes! Shutting down.")
-		}
-		if connectErr := db.Ping(); connectErr != nil {
-			// Make sure it's not something like a "password is wrong" error or something
-			var target *mysql.MySQLError
-			if errors.As(connectErr, &target) {
-				logger.Log.Fatal("Database successfully connected, but connection options may be wrong. Shutting down.", zap.Error(connectErr))
-			}
-
-			// If the problem wasn't something a successful connection told us, retry connecting in 10 seconds
-			logger.Log.Warn("Could not reach the database, retrying in 10s...", zap.Error(connectErr))
-			time.Sleep(10 * time.Second)
-		} else {
-			break
-		}
+	checker := NewConnectivityChecker(db)
+	checker.InitialDelay = 10 * time.Second
+	checker.MaxDelay = 10 * time.Second
+	checker.OnEvent(LogConnectivityEvent)
+	if connectErr := checker.WaitUntilConnected(context.Background()); connectErr != nil {
+		logger.Log.Fatal("Database could not be reached! Shutting down.", zap.Error(connectErr))
 	}
 }
+
+// LogConnectivityEvent logs the progress of a ConnectivityChecker on the global logger. Register it with
+// ConnectivityChecker.OnEvent.
+func LogConnectivityEvent(event ConnectivityEvent) {
+	switch {
+	case event.State == StateConnected && event.Attempt > 1:
+		logger.Log.Info("Database reached", zap.Int("attempts", event.Attempt), zap.Duration("elapsed", event.Elapsed))
+	case event.State == StateConnected:
+		// Reaching the database on the first try is the normal case, so it isn't worth logging
+	case event.State == StateMisconfigured:
+		logger.Log.Error("Database reached, but connection options may be wrong", zap.Int("attempt", event.Attempt),
+			zap.Duration("retryIn", event.RetryIn), zap.Error(event.Err))
+	case event.RetryIn > 0:
+		logger.Log.Warn("Could not reach the database, retrying", zap.Stringer("state", event.State), zap.Int("attempt", event.Attempt),
+			zap.Duration("retryIn", event.RetryIn), zap.Error(event.Err))
+	default:
+		logger.Log.Error("Could not reach the database, giving up", zap.Int("attempts", event.Attempt),
+			zap.Duration("elapsed", event.Elapsed), zap.Error(event.Err))
+	}
+}
+
+// sqlStateError is implemented by the errors of Postgres drivers, such as *pgconn.PgError and *pq.Error, which carry the
+// SQLSTATE code the server responded with
+type sqlStateError interface {
+	error
+	SQLState() string
+}
+
+// IsMisconfigured reports whether an error connecting to the database came from the database server itself, meaning it
+// was reached but rejected the connection, such as for a wrong password or an unknown schema. Retrying won't fix
+// those, unlike network errors or a server which is still starting up.
+func IsMisconfigured(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return true
+	}
+
+	var postgresErr sqlStateError
+	if errors.As(err, &postgresErr) {
+		state := postgresErr.SQLState()
+		// Class 08 is a connection exception, 57P03 means the server is starting up, and 53300 means it's out of
+		// connection slots, all of which may clear up on their own
+		return !strings.HasPrefix(state, "08") && state != "57P03" && state != "53300"
+	}
+
+	return false
+}
@@ -53,20 +53,17 @@ func InitLogger(level zapcore.Level, isProduction bool) error {
 }
 
 // InitLoggerFromConfig initializes the global logger, Log, via shared options in a config.Registry. Notably,
-// it requires the registry to have the options sharedoptions.LogLevel and sharedoptions.IsInProduction registered.
+// it requires the registry to have the options sharedoptions.LogLevel and sharedoptions.AppEnv registered.
+// Once the logger is initialized, the registry's warnings, such as uses of deprecated option names, are logged.
 func InitLoggerFromConfig(registry config.Registry) error {
-	logLevelStr, levelPresent := registry.Get(sharedoptions.LogLevel)
-	if !levelPresent {
-		logLevelStr = "info"
-	}
-	parsedLevel, parseErr := LevelFromString(logLevelStr)
-	if parseErr != nil {
-		return fmt.Errorf("invalid log level bypassed validation: %v cause: %w", logLevelStr, parseErr)
+	level := config.GetRequiredTyped(registry, sharedoptions.LogLevel)
+	if loggerSetupErr := InitLogger(level, registry.Profile().IsProduction()); loggerSetupErr != nil {
+		return fmt.Errorf("logger setup failed: %w", loggerSetupErr)
 	}
-	isProductionStr := registry.GetRequired(sharedoptions.IsInProduction)
 
-	if loggerSetupErr := InitLogger(parsedLevel, isProductionStr == "true"); loggerSetupErr != nil {
-		return fmt.Errorf("logger setup failed: %w", loggerSetupErr)
+	// Configuration warnings can only be reported once there's a logger to report them with
+	for _, warning := range registry.Warnings() {
+		Log.Warn("Configuration warning", zap.String("warning", warning))
 	}
 
 	return nil
@@ -76,3 +73,13 @@ func InitLoggerFromConfig(registry config.Registry) error {
 func AdjustLevel(level zapcore.Level) {
 	logLevel.SetLevel(level)
 }
+
+// WatchLevel keeps the global logger's level in sync with sharedoptions.LogLevel in a reloadable registry, adjusting it
+// every time a reload changes the option.
+func WatchLevel(registry *config.ReloadableRegistry) {
+	registry.Subscribe(func(current config.Registry) {
+		newLevel := config.GetRequiredTyped(current, sharedoptions.LogLevel)
+		AdjustLevel(newLevel)
+		Log.Info("Log level changed by a configuration reload", zap.Stringer("level", newLevel))
+	}, sharedoptions.LogLevel)
+}
@@ -1,9 +1,23 @@
 package dtos
 
 import (
+	"net/http"
+	"sync/atomic"
+
 	"github.com/labstack/echo/v4"
 )
 
+// hideServerErrorDetails determines whether Respond omits the underlying error from responses to server errors
+var hideServerErrorDetails atomic.Bool
+
+// HideServerErrorDetails determines whether error responses with a 5xx status omit the message of the error that caused
+// them, which can reveal internals such as SQL statements. Details are shown by default, which helps while developing,
+// and should be hidden in production. Client error responses always include details, since they explain what the
+// client needs to fix.
+func HideServerErrorDetails(hide bool) {
+	hideServerErrorDetails.Store(hide)
+}
+
 // APIErrorHelper helps easily create a standard HTTP error response type with
 // canned messages and auto-propagation of error messages to an API user
 type APIErrorHelper struct {
@@ -28,7 +42,8 @@ type emptyBody struct{}
 // appropriate HTTP response code
 func (errHelp APIErrorHelper) Respond(c echo.Context) error {
 	var errDetail string
-	if errHelp.Error != nil {
+	hideDetail := errHelp.Status >= http.StatusInternalServerError && hideServerErrorDetails.Load()
+	if errHelp.Error != nil && !hideDetail {
 		errDetail = errHelp.Error.Error()
 	}
 
@@ -0,0 +1,50 @@
+package dtos
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type APIResponsesSuite struct {
+	suite.Suite
+}
+
+func TestAPIResponsesSuite(t *testing.T) {
+	suite.Run(t, new(APIResponsesSuite))
+}
+
+func (suite *APIResponsesSuite) TearDownTest() {
+	HideServerErrorDetails(false)
+}
+
+func (suite *APIResponsesSuite) respond(errHelp APIErrorHelper) apiError {
+	recorder := httptest.NewRecorder()
+	ctx := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), recorder)
+	suite.Require().NoError(errHelp.Respond(ctx))
+
+	var body apiError
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &body))
+	return body
+}
+
+func (suite *APIResponsesSuite) TestDetailsAreShownByDefault() {
+	body := suite.respond(APIErrorHelper{Status: http.StatusInternalServerError, Error: errors.New("query failed")})
+
+	suite.Assert().Equal("query failed", body.Detail)
+}
+
+func (suite *APIResponsesSuite) TestServerErrorDetailsCanBeHidden() {
+	HideServerErrorDetails(true)
+
+	serverBody := suite.respond(APIErrorHelper{Status: http.StatusInternalServerError, Error: errors.New("query failed")})
+	suite.Assert().Empty(serverBody.Detail)
+
+	clientBody := suite.respond(APIErrorHelper{Status: http.StatusBadRequest, Error: errors.New("name is required")})
+	suite.Assert().Equal("name is required", clientBody.Detail)
+}
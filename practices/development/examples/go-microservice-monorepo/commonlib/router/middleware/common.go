@@ -2,18 +2,19 @@ package middleware
 
 import (
 	"example.com/sample/commonlib/config"
-	"github.com/jmoiron/sqlx"
+	"example.com/sample/commonlib/database"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
-// StandardMiddleware returns a set of pre-configured middleware that can be applied across many microservices
-func StandardMiddleware(options config.Registry, databaseConnection *sqlx.DB) []echo.MiddlewareFunc {
+// StandardMiddleware returns a set of pre-configured middleware that can be applied across many microservices. A
+// microservice without read replicas can pass a cluster built with database.NewCluster from its single database.
+func StandardMiddleware(options config.Provider, databaseCluster *database.Cluster) []echo.MiddlewareFunc {
 	return []echo.MiddlewareFunc{
 		middleware.Recover(),
 		CorsMiddleware(options),
 		LoggingMiddleware(),
 		AuthMiddleware(),
-		DatabaseContextMiddleware(databaseConnection),
+		ClusterContextMiddleware(databaseCluster),
 	}
 }
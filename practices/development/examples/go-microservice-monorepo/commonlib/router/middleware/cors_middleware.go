@@ -1,7 +1,7 @@
 package middleware
 
 import (
-	"strings"
+	"slices"
 
 	"example.com/sample/commonlib/config"
 	"example.com/sample/commonlib/config/sharedoptions"
@@ -10,22 +10,33 @@ import (
 )
 
 // CorsMiddleware is a middleware that auto-handles the CORS preflight a browser sends to only allow
-// certain frontend origins to access our API. It pulls the list of allowed origins from sharedoptions.AllowedOrigins
-// but if that's not present it uses localhost:3000 as the only acceptable origin
-func CorsMiddleware(options config.Registry) echo.MiddlewareFunc {
-	var allowedOrigins []string
-	rawOriginsList, listPresent := options.Get(sharedoptions.AllowedOrigins)
-	if listPresent {
-		allowedOrigins = strings.Split(rawOriginsList, ",")
-	} else {
-		allowedOrigins = []string{"http://localhost:8080"}
-	}
-
+// certain frontend origins to access our API. It pulls the list of allowed origins from sharedoptions.AllowedOrigins.
+// The list is read from the provider's current configuration on every request, so passing a config.ReloadableRegistry
+// applies changes to the list without a restart. An allowed origin of "*" allows every origin, but without credentials,
+// so browsers won't send cookies or authorization headers along with cross-origin requests.
+func CorsMiddleware(options config.Provider) echo.MiddlewareFunc {
 	// TODO tweak the CORS headers as necessary.
-	return middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins:     allowedOrigins,
+	listedOrigins := middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOriginFunc: func(origin string) (bool, error) {
+			return slices.Contains(config.GetRequiredTyped(options.Current(), sharedoptions.AllowedOrigins), origin), nil
+		},
 		AllowMethods:     middleware.DefaultCORSConfig.AllowMethods,
 		AllowHeaders:     []string{echo.HeaderContentType, echo.HeaderAuthorization},
 		AllowCredentials: true,
 	})
+	anyOrigin := middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins: []string{"*"},
+		AllowMethods: middleware.DefaultCORSConfig.AllowMethods,
+		AllowHeaders: []string{echo.HeaderContentType, echo.HeaderAuthorization},
+	})
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withListedOrigins, withAnyOrigin := listedOrigins(next), anyOrigin(next)
+		return func(c echo.Context) error {
+			if slices.Contains(config.GetRequiredTyped(options.Current(), sharedoptions.AllowedOrigins), "*") {
+				return withAnyOrigin(c)
+			}
+			return withListedOrigins(c)
+		}
+	}
 }
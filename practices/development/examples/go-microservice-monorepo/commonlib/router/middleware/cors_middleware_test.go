@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/sample/commonlib/config"
+	"example.com/sample/commonlib/config/sharedoptions"
+	"example.com/sample/commonlib/config/testhelper"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+type CorsMiddlewareSuite struct {
+	suite.Suite
+	fixture *testhelper.RegistryFixture
+}
+
+func TestCorsMiddlewareSuite(t *testing.T) {
+	suite.Run(t, new(CorsMiddlewareSuite))
+}
+
+func (suite *CorsMiddlewareSuite) SetupTest() {
+	corsOptions := []config.AnyOption{sharedoptions.AppEnv, sharedoptions.AllowedOrigins}
+	suite.fixture = testhelper.NewRegistryFixture(suite.T(), testhelper.BuilderOf(corsOptions, sharedoptions.ProductionCorsRule),
+		map[string]string{sharedoptions.AppEnv.VariableName(): string(config.ProfileTest)})
+}
+
+// preflight sends a CORS preflight request from the passed origin through the middleware, returning the headers the
+// middleware responded with
+func (suite *CorsMiddlewareSuite) preflight(options config.Provider, origin string) http.Header {
+	engine := echo.New()
+	engine.Use(CorsMiddleware(options))
+
+	request := httptest.NewRequest(http.MethodOptions, "/", nil)
+	request.Header.Set(echo.HeaderOrigin, origin)
+	request.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodGet)
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+
+	return recorder.Header()
+}
+
+func (suite *CorsMiddlewareSuite) TestAllowedOrigins() {
+	subtests := []struct {
+		testName       string
+		allowedOrigins string
+		origin         string
+		allowedOrigin  string
+	}{
+		{
+			testName:       "Allows listed origin",
+			allowedOrigins: "http://localhost,https://example.com",
+			origin:         "https://example.com",
+			allowedOrigin:  "https://example.com",
+		},
+		{
+			testName:       "Rejects unlisted origin",
+			allowedOrigins: "http://localhost,https://example.com",
+			origin:         "https://evil.example.com",
+			allowedOrigin:  "",
+		},
+		{
+			testName:       "Allows any origin with a wildcard",
+			allowedOrigins: "*",
+			origin:         "https://evil.example.com",
+			allowedOrigin:  "*",
+		},
+	}
+
+	for _, subtest := range subtests {
+		suite.Run(subtest.testName, func() {
+			suite.fixture.Override(suite.T(), map[string]string{sharedoptions.AllowedOrigins.VariableName(): subtest.allowedOrigins})
+			headers := suite.preflight(suite.fixture.Registry(suite.T()), subtest.origin)
+
+			suite.Assert().Equal(subtest.allowedOrigin, headers.Get(echo.HeaderAccessControlAllowOrigin))
+		})
+	}
+}
+
+func (suite *CorsMiddlewareSuite) TestWildcardDoesNotAllowCredentials() {
+	suite.fixture.Override(suite.T(), map[string]string{sharedoptions.AllowedOrigins.VariableName(): "https://example.com,*"})
+	headers := suite.preflight(suite.fixture.Registry(suite.T()), "https://evil.example.com")
+
+	suite.Assert().Equal("*", headers.Get(echo.HeaderAccessControlAllowOrigin), "The caller's origin must not be echoed back")
+	suite.Assert().Empty(headers.Get(echo.HeaderAccessControlAllowCredentials))
+}
+
+func (suite *CorsMiddlewareSuite) TestListedOriginsAllowCredentials() {
+	suite.fixture.Override(suite.T(), map[string]string{sharedoptions.AllowedOrigins.VariableName(): "https://example.com"})
+	headers := suite.preflight(suite.fixture.Registry(suite.T()), "https://example.com")
+
+	suite.Assert().Equal("true", headers.Get(echo.HeaderAccessControlAllowCredentials))
+}
+
+func (suite *CorsMiddlewareSuite) TestDefaultOrigin() {
+	headers := suite.preflight(suite.fixture.Registry(suite.T()), "http://localhost:8080")
+
+	suite.Assert().Equal("http://localhost:8080", headers.Get(echo.HeaderAccessControlAllowOrigin))
+}
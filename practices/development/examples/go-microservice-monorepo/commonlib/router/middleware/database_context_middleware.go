@@ -25,3 +25,17 @@ func DatabaseContextMiddleware(db *sqlx.DB) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// ClusterContextMiddleware is like DatabaseContextMiddleware, except it attaches a database cluster, so reads in driven
+// ports are served by read replicas and writes by the primary
+func ClusterContextMiddleware(cluster *database.Cluster) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			request := ctx.Request()
+			clusterCtx := database.CreateDerivativeClusterContext(request.Context(), cluster)
+			ctx.SetRequest(request.WithContext(clusterCtx))
+
+			return next(ctx)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package router
 
 import (
+	"fmt"
+
 	"example.com/sample/commonlib/config"
 	"example.com/sample/commonlib/config/sharedoptions"
 	"example.com/sample/commonlib/logger"
@@ -22,12 +24,9 @@ func New() Router {
 
 // Listen causes the router to start listening to HTTP requests
 func (rtr *Router) Listen(registry *config.Registry) {
-	portNumber, envVarPresent := registry.Get(sharedoptions.ListenPort)
-	if !envVarPresent {
-		portNumber = "8080"
-	}
+	portNumber := config.GetRequiredTyped(*registry, sharedoptions.ListenPort)
 
-	logger.Log.Fatal("Failed to run server!", zap.Error(rtr.engine.Start(":"+portNumber)))
+	logger.Log.Fatal("Failed to run server!", zap.Error(rtr.engine.Start(fmt.Sprintf(":%v", portNumber))))
 }
 
 // AttachControllers attaches routes from the provided controllers to this Router
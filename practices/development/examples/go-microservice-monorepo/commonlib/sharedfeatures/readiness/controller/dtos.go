@@ -0,0 +1,21 @@
+package controller
+
+// ReadinessResponse is the format of the response body reporting whether the application is ready
+type ReadinessResponse struct {
+	// Ready is true if the application is ready to serve requests
+	Ready bool `json:"ready"`
+	// Checks holds the outcome of each readiness check
+	Checks []CheckResponse `json:"checks"`
+}
+
+// CheckResponse is the outcome of a single readiness check
+type CheckResponse struct {
+	// Name identifies the check
+	Name string `json:"name"`
+	// Passed is true if the check passed
+	Passed bool `json:"passed"`
+	// Advisory is true if a failure of the check doesn't keep the application from being ready
+	Advisory bool `json:"advisory"`
+	// Detail explains why the check failed. It's empty if the check passed.
+	Detail string `json:"detail,omitempty"`
+}
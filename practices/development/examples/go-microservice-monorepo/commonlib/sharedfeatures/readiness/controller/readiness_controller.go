@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"net/http"
+
+	"example.com/sample/commonlib/sharedfeatures/readiness"
+	"github.com/labstack/echo/v4"
+)
+
+// ReadinessController is a REST controller that reports whether the application is ready to serve requests, for use
+// as a readiness probe by an orchestrator such as Kubernetes.
+type ReadinessController struct {
+	logicCore readiness.Core
+}
+
+// New constructs a new ReadinessController reporting the outcome of the checks registered with the passed Checker
+func New(checker *readiness.Checker) ReadinessController {
+	return ReadinessController{
+		logicCore: checker,
+	}
+}
+
+// newWithCore constructs a ReadinessController with a mocked core implementation
+func newWithCore(core readiness.Core) ReadinessController {
+	return ReadinessController{
+		logicCore: core,
+	}
+}
+
+// AttachRoutes implements router.Controller for ReadinessController. It defines this controller's routes
+func (ctrl ReadinessController) AttachRoutes(rtr *echo.Echo) {
+	rtr.GET("/api/v1/health/ready", ctrl.CheckReadiness)
+}
+
+// CheckReadiness is a route that runs every readiness check, responding with 200 if the application is ready and 503
+// if it isn't. The body lists the outcome of each check either way.
+func (ctrl ReadinessController) CheckReadiness(ctx echo.Context) error {
+	report := ctrl.logicCore.CheckReadiness(ctx.Request().Context())
+
+	responseBody := ReadinessResponse{
+		Ready:  report.Ready,
+		Checks: make([]CheckResponse, 0, len(report.Results)),
+	}
+	for _, result := range report.Results {
+		checkResponse := CheckResponse{Name: result.Name, Passed: result.Err == nil, Advisory: result.Advisory}
+		if result.Err != nil {
+			checkResponse.Detail = result.Err.Error()
+		}
+		responseBody.Checks = append(responseBody.Checks, checkResponse)
+	}
+
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	return ctx.JSON(status, responseBody)
+}
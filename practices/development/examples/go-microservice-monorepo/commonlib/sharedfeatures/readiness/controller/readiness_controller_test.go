@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	reqhelper "example.com/sample/commonlib/request/testhelper"
+	reshelper "example.com/sample/commonlib/response/testhelper"
+	"example.com/sample/commonlib/sharedfeatures/readiness"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type ReadinessControllerSuite struct {
+	suite.Suite
+	mockController *gomock.Controller
+	coreMock       *readiness.MockCore
+}
+
+func TestReadinessControllerSuite(t *testing.T) {
+	suite.Run(t, new(ReadinessControllerSuite))
+}
+
+func (suite *ReadinessControllerSuite) SetupTest() {
+	suite.mockController = gomock.NewController(suite.T())
+	suite.coreMock = readiness.NewMockCore(suite.mockController)
+}
+
+func (suite *ReadinessControllerSuite) TearDownTest() {
+	suite.mockController.Finish()
+}
+
+// checkReadiness calls the readiness route, returning the response status and body
+func (suite *ReadinessControllerSuite) checkReadiness() (int, ReadinessResponse) {
+	request, responseRecorder, buildErr := reqhelper.NewRequest(echo.GET, "/api/v1/health/ready").Build()
+	suite.Require().NoError(buildErr)
+
+	responseErr := newWithCore(suite.coreMock).CheckReadiness(request)
+	suite.Require().NoError(responseErr)
+
+	response := responseRecorder.Result()
+	var responseBody ReadinessResponse
+	reshelper.UnmarshalBody(&suite.Suite, response.Body, &responseBody)
+	return response.StatusCode, responseBody
+}
+
+func (suite *ReadinessControllerSuite) TestReady() {
+	suite.coreMock.EXPECT().CheckReadiness(gomock.Any()).Return(readiness.Report{
+		Ready:   true,
+		Results: []readiness.CheckResult{{Name: "database"}},
+	})
+
+	status, responseBody := suite.checkReadiness()
+
+	suite.Assert().Equal(http.StatusOK, status)
+	suite.Assert().Equal(ReadinessResponse{
+		Ready:  true,
+		Checks: []CheckResponse{{Name: "database", Passed: true}},
+	}, responseBody)
+}
+
+func (suite *ReadinessControllerSuite) TestNotReady() {
+	suite.coreMock.EXPECT().CheckReadiness(gomock.Any()).Return(readiness.Report{
+		Ready:   false,
+		Results: []readiness.CheckResult{{Name: "database schema", Err: errors.New("2 migrations are pending")}},
+	})
+
+	status, responseBody := suite.checkReadiness()
+
+	suite.Assert().Equal(http.StatusServiceUnavailable, status)
+	suite.Require().Len(responseBody.Checks, 1)
+	suite.Assert().False(responseBody.Checks[0].Passed)
+	suite.Assert().Equal("2 migrations are pending", responseBody.Checks[0].Detail)
+}
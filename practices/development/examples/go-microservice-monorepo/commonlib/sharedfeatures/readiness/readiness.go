@@ -0,0 +1,93 @@
+package readiness
+
+import (
+	"context"
+	"sync"
+)
+
+//go:generate mockgen -destination ./readiness_mocks.go -package readiness . Core
+
+// Check reports whether one part of the application is ready to serve requests. It returns nil when the part is ready,
+// or an error describing why it isn't.
+type Check func(ctx context.Context) error
+
+// CheckResult is the outcome of a single Check
+type CheckResult struct {
+	// Name identifies the check, such as "database schema"
+	Name string
+	// Err is the error the check returned, or nil if it passed
+	Err error
+	// Advisory is true if a failure of the check doesn't keep the application from being ready
+	Advisory bool
+}
+
+// Report is the outcome of every registered Check
+type Report struct {
+	// Ready is true if every check which isn't advisory passed
+	Ready bool
+	// Results holds the outcome of each check, in the order they were registered
+	Results []CheckResult
+}
+
+// Core contains logic for determining whether the application is ready to serve requests.
+type Core interface {
+	// CheckReadiness runs every registered check, reporting whether the application is ready
+	CheckReadiness(ctx context.Context) Report
+}
+
+// registeredCheck is a Check along with how it was registered
+type registeredCheck struct {
+	name     string
+	check    Check
+	advisory bool
+}
+
+// Checker is the Core implementation which runs checks registered by other components, such as the database
+// connection or the schema version guard. It's safe to register checks while readiness is being checked.
+type Checker struct {
+	// lock guards checks
+	lock   sync.Mutex
+	checks []registeredCheck
+}
+
+// NewChecker constructs a Checker without any checks, which reports the application as ready
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Register adds a check which must pass for the application to be ready
+func (checker *Checker) Register(name string, check Check) {
+	checker.register(registeredCheck{name: name, check: check})
+}
+
+// RegisterAdvisory adds a check whose failure is reported, but doesn't keep the application from being ready. It suits
+// problems which are worth surfacing but which the application can run with.
+func (checker *Checker) RegisterAdvisory(name string, check Check) {
+	checker.register(registeredCheck{name: name, check: check, advisory: true})
+}
+
+// register adds a check to the list of checks
+func (checker *Checker) register(check registeredCheck) {
+	checker.lock.Lock()
+	defer checker.lock.Unlock()
+
+	checker.checks = append(checker.checks, check)
+}
+
+// CheckReadiness implements Core for Checker
+func (checker *Checker) CheckReadiness(ctx context.Context) Report {
+	checker.lock.Lock()
+	checks := append([]registeredCheck{}, checker.checks...)
+	checker.lock.Unlock()
+
+	report := Report{Ready: true}
+	for _, check := range checks {
+		result := CheckResult{Name: check.name, Err: check.check(ctx), Advisory: check.advisory}
+		if result.Err != nil && !result.Advisory {
+			report.Ready = false
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	return report
+}
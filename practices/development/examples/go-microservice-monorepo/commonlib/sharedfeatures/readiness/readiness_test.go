@@ -0,0 +1,56 @@
+package readiness
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CheckerSuite struct {
+	suite.Suite
+}
+
+func TestCheckerSuite(t *testing.T) {
+	suite.Run(t, new(CheckerSuite))
+}
+
+func passingCheck(context.Context) error {
+	return nil
+}
+
+func failingCheck(context.Context) error {
+	return errors.New("not yet")
+}
+
+func (suite *CheckerSuite) TestReadyWithoutChecks() {
+	report := NewChecker().CheckReadiness(context.Background())
+
+	suite.Assert().True(report.Ready)
+	suite.Assert().Empty(report.Results)
+}
+
+func (suite *CheckerSuite) TestFailingCheckIsNotReady() {
+	checker := NewChecker()
+	checker.Register("passing", passingCheck)
+	checker.Register("failing", failingCheck)
+	report := checker.CheckReadiness(context.Background())
+
+	suite.Assert().False(report.Ready)
+	suite.Require().Len(report.Results, 2)
+	suite.Assert().NoError(report.Results[0].Err)
+	suite.Assert().Equal("failing", report.Results[1].Name)
+	suite.Assert().Error(report.Results[1].Err)
+}
+
+func (suite *CheckerSuite) TestFailingAdvisoryCheckIsStillReady() {
+	checker := NewChecker()
+	checker.RegisterAdvisory("advisory", failingCheck)
+	report := checker.CheckReadiness(context.Background())
+
+	suite.Assert().True(report.Ready)
+	suite.Require().Len(report.Results, 1)
+	suite.Assert().True(report.Results[0].Advisory)
+	suite.Assert().Error(report.Results[0].Err)
+}
@@ -1,29 +1,44 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"time"
 
+	"example.com/sample/commonlib/config"
+	"example.com/sample/commonlib/config/sharedoptions"
 	"example.com/sample/commonlib/database"
+	"example.com/sample/commonlib/database/migrate"
 	"example.com/sample/commonlib/logger"
+	"example.com/sample/commonlib/response/dtos"
 	"example.com/sample/commonlib/router"
 	"example.com/sample/commonlib/router/middleware"
 	loglevelcontroller "example.com/sample/commonlib/sharedfeatures/loglevel/controller"
+	"example.com/sample/commonlib/sharedfeatures/readiness"
+	readinesscontroller "example.com/sample/commonlib/sharedfeatures/readiness/controller"
+	dbfiles "example.com/sample/microsvc/db"
 	sampleadapter "example.com/sample/microsvc/features/sample/adapter"
 	samplecontroller "example.com/sample/microsvc/features/sample/controller"
 	swaggercontroller "example.com/sample/microsvc/features/swagger/controller"
 	"example.com/sample/microsvc/options"
 	"github.com/jmoiron/sqlx"
-	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 )
 
+// configFilePollInterval is how often the configuration files are checked for changes
+const configFilePollInterval = 30 * time.Second
+
+// configKVPollInterval is how often configuration is reloaded to pick up changes in the key/value store
+const configKVPollInterval = time.Minute
+
+// readinessChecker collects the checks reported by the readiness endpoint
+var readinessChecker = readiness.NewChecker()
+
 // PrepareSubsystems prepares the set of global systems that other parts of the microservice depend on,
 // namely the global logger (logger.Log) and the global configuration registry (options.Registry)
-func PrepareSubsystems() *sqlx.DB {
-	// Attempt to load .env file, errors are inconsequential
-	_ = godotenv.Load()
-
+func PrepareSubsystems() *database.Cluster {
 	// Set up config registry
 	configSetupErr := options.InitRegistry()
 	if configSetupErr != nil {
@@ -35,24 +50,153 @@ func PrepareSubsystems() *sqlx.DB {
 	if loggerSetupErr != nil {
 		log.Fatal("Could not set up logger!", loggerSetupErr)
 	}
+	logEffectiveConfiguration()
+
+	// Keep internal error messages out of responses in production
+	dtos.HideServerErrorDetails(options.Registry.Profile().IsProduction())
 
-	// Set up database connection
-	db, dbConnectErr := database.ConnectFromConfig(*options.Registry)
+	// Set up database connections to the primary and any read replicas
+	cluster, dbConnectErr := database.ConnectClusterFromConfig(*options.Registry)
 	if dbConnectErr != nil {
 		logger.Log.Fatal("Database connection irreparably failed!", zap.Error(dbConnectErr))
 	}
+	db := cluster.Primary
+
+	// Verify the connections are established, then keep checking they are
+	waitForDatabases(cluster)
+
+	// Bring the schema up to date if configured to, then make sure it is
+	if config.GetRequiredTyped(*options.Registry, sharedoptions.DBMigrateOnStartup) {
+		applyMigrations()
+	}
+	guardSchemaVersion(db)
+
+	// Apply configuration changes while the service runs
+	watchConfiguration(cluster)
+
+	return cluster
+}
+
+// waitForDatabases waits until the primary database and every replica can be reached, shutting down if one can't be
+// within sharedoptions.DBConnectMaxWait. Each database is then monitored for the rest of the microservice's life, which
+// the readiness endpoint reports.
+func waitForDatabases(cluster *database.Cluster) {
+	maxWait := config.GetRequiredTyped(*options.Registry, sharedoptions.DBConnectMaxWait)
+	for dbIndex, db := range cluster.All() {
+		checkName := "database"
+		if dbIndex > 0 {
+			checkName = fmt.Sprintf("database replica %v", dbIndex)
+		}
+
+		checker := database.NewConnectivityChecker(db)
+		checker.MaxWait = maxWait
+		checker.OnEvent(database.LogConnectivityEvent)
+		if connectErr := checker.WaitUntilConnected(context.Background()); connectErr != nil {
+			logger.Log.Fatal("Database could not be reached! Shutting down.", zap.String("database", checkName), zap.Error(connectErr))
+		}
+
+		readinessChecker.Register(checkName, checker.Check)
+		go checker.Monitor(context.Background())
+	}
+}
+
+// applyMigrations applies the microservice's pending database migrations, shutting down if any of them fail
+func applyMigrations() {
+	runner, openErr := migrate.Open(database.ConfigFromGroup(*options.Registry, sharedoptions.DefaultDB), dbfiles.Migrations())
+	if openErr != nil {
+		logger.Log.Fatal("Could not prepare database migrations!", zap.Error(openErr))
+	}
+	defer runner.Close()
+
+	applied, upErr := runner.Up(context.Background())
+	for _, migration := range applied {
+		logger.Log.Info("Applied database migration", zap.String("version", migration.Version), zap.String("name", migration.Name))
+	}
+	if upErr != nil {
+		logger.Log.Fatal("Database migration failed!", zap.Error(upErr))
+	}
+}
+
+// guardSchemaVersion checks every migration shipped with the microservice has been applied to the database, as
+// configured by sharedoptions.DBSchemaCheck. In enforce mode, the microservice shuts down if one hasn't. Otherwise, a
+// warning is logged. The check is also registered with the readiness endpoint, so a mismatch is reported there.
+func guardSchemaVersion(db *sqlx.DB) {
+	checkMode := config.GetRequiredTyped(*options.Registry, sharedoptions.DBSchemaCheck)
+	if checkMode == sharedoptions.SchemaCheckOff {
+		return
+	}
+
+	runner, runnerErr := migrate.NewRunner(db, dbfiles.Migrations())
+	if runnerErr != nil {
+		logger.Log.Fatal("Could not read the database migrations shipped with the microservice!", zap.Error(runnerErr))
+	}
+	if checkMode == sharedoptions.SchemaCheckEnforce {
+		readinessChecker.Register("database schema", runner.SchemaUpToDate)
+	} else {
+		readinessChecker.RegisterAdvisory("database schema", runner.SchemaUpToDate)
+	}
 
-	// Verify the connection is established
-	database.MustBeConnected(db)
+	report, checkErr := runner.CheckSchema(context.Background())
+	switch {
+	case checkErr != nil && checkMode == sharedoptions.SchemaCheckEnforce:
+		logger.Log.Fatal("Could not check the database schema version!", zap.Error(checkErr))
+	case checkErr != nil:
+		logger.Log.Warn("Could not check the database schema version", zap.Error(checkErr))
+	case !report.UpToDate() && checkMode == sharedoptions.SchemaCheckEnforce:
+		logger.Log.Fatal("Database schema is behind the microservice's migrations! Apply them, or set DB_SCHEMA_CHECK to warn to start anyway.",
+			zap.Error(report.Err()))
+	case !report.UpToDate():
+		logger.Log.Warn("Database schema is behind the microservice's migrations, queries may fail", zap.Error(report.Err()))
+	case len(report.Unrecognized) > 0:
+		logger.Log.Info("Database has migrations this build doesn't know about, it was probably migrated by a newer build",
+			zap.Strings("versions", report.Unrecognized))
+	}
+}
 
-	return db
+// logEffectiveConfiguration logs the value and source of every configuration option at debug level, with secrets
+// redacted, to help diagnose which source a surprising value came from
+func logEffectiveConfiguration() {
+	var fields []zap.Field
+	for _, effectiveValue := range options.Registry.Dump() {
+		if effectiveValue.Present {
+			fields = append(fields, zap.String(effectiveValue.Name, fmt.Sprintf("%v (from %v)", effectiveValue.Value, effectiveValue.Source)))
+		}
+	}
+	logger.Log.Debug("Effective configuration", fields...)
+}
+
+// watchConfiguration applies configuration changes to the running microservice whenever the configuration is reloaded,
+// which happens when the process receives SIGHUP, when one of the configuration files changes, or periodically when
+// shared configuration is read from a key/value store
+func watchConfiguration(cluster *database.Cluster) {
+	logger.WatchLevel(options.Reloadable)
+	for _, db := range cluster.All() {
+		database.WatchPoolSettings(options.Reloadable, db)
+	}
+
+	onReload := func(reloadErr error) {
+		if reloadErr != nil {
+			logger.Log.Error("Configuration reload failed, keeping the previous configuration", zap.Error(reloadErr))
+		} else {
+			logger.Log.Info("Configuration reloaded")
+		}
+	}
+	go options.Reloadable.WatchSignals(context.Background(), onReload)
+	go options.Reloadable.WatchFiles(context.Background(), configFilePollInterval, onReload)
+
+	if options.KVSource != nil {
+		if kvErr := options.KVSource.LastError(); kvErr != nil {
+			logger.Log.Warn("Could not read shared configuration from the key/value store, using other sources", zap.Error(kvErr))
+		}
+		go options.Reloadable.ReloadPeriodically(context.Background(), configKVPollInterval, onReload)
+	}
 }
 
 // Bootstrap constructs the microservice's controllers and middleware, then creates a router and attaches
 // the controllers and middleware to it
-func Bootstrap(db *sqlx.DB) router.Router {
+func Bootstrap(cluster *database.Cluster) router.Router {
 	controllers := CreateControllers()
-	appMiddleware := CreateMiddleware(db)
+	appMiddleware := CreateMiddleware(cluster)
 
 	appRouter := router.New()
 	appRouter.AttachMiddleware(appMiddleware)
@@ -61,19 +205,24 @@ func Bootstrap(db *sqlx.DB) router.Router {
 	return appRouter
 }
 
-// CreateControllers constructs all the rest controllers in the microservice
+// CreateControllers constructs all the rest controllers in the microservice. The swagger UI is left out in production.
 func CreateControllers() []router.Controller {
-	return []router.Controller{
+	controllers := []router.Controller{
 		sample(),
 		logLevelAdjust(),
-		swagger(),
+		readinessProbe(),
 	}
+	if !options.Registry.Profile().IsProduction() {
+		controllers = append(controllers, swagger())
+	}
+
+	return controllers
 }
 
 // CreateMiddleware constructs all the middleware the microservice will use
-func CreateMiddleware(db *sqlx.DB) []echo.MiddlewareFunc {
+func CreateMiddleware(cluster *database.Cluster) []echo.MiddlewareFunc {
 	var appMiddleware []echo.MiddlewareFunc
-	appMiddleware = append(appMiddleware, middleware.StandardMiddleware(*options.Registry, db)...)
+	appMiddleware = append(appMiddleware, middleware.StandardMiddleware(options.Reloadable, cluster)...)
 
 	return appMiddleware
 }
@@ -90,6 +239,12 @@ func swagger() swaggercontroller.SwaggerController {
 	return swaggercontroller.New()
 }
 
+// readinessProbe constructs the shared readiness controller (controller.ReadinessController), reporting the checks
+// registered while preparing subsystems
+func readinessProbe() readinesscontroller.ReadinessController {
+	return readinesscontroller.New(readinessChecker)
+}
+
 // logLevelAdjust constructs the shared log level adjustment controller (controller.LogLevelController)
 func logLevelAdjust() loglevelcontroller.LogLevelController {
 	return loglevelcontroller.New()
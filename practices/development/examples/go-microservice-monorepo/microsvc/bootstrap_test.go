@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"example.com/sample/commonlib/config"
+	"example.com/sample/commonlib/config/testhelper"
+	swaggercontroller "example.com/sample/microsvc/features/swagger/controller"
+	"example.com/sample/microsvc/options"
+	"github.com/stretchr/testify/suite"
+)
+
+type BootstrapSuite struct {
+	suite.Suite
+	fixture *testhelper.RegistryFixture
+}
+
+func TestBootstrapSuite(t *testing.T) {
+	suite.Run(t, new(BootstrapSuite))
+}
+
+func (suite *BootstrapSuite) SetupTest() {
+	suite.fixture = testhelper.NewRegistryFixture(suite.T(), options.NewRegistryBuilder, map[string]string{
+		"APP_ENV":              string(config.ProfileTest),
+		"ALLOWED_CORS_ORIGINS": "https://example.com",
+		"DB_USER":              "root",
+		"DB_PASSWORD":          "mypass",
+		"DB_HOST":              "localhost",
+		"DB_SCHEMA":            "test",
+	})
+}
+
+func (suite *BootstrapSuite) TestSwaggerIsOnlyServedOutsideProduction() {
+	subtests := []struct {
+		profile        config.Profile
+		expectsSwagger bool
+	}{
+		{profile: config.ProfileLocal, expectsSwagger: true},
+		{profile: config.ProfileProduction, expectsSwagger: false},
+	}
+
+	for _, subtest := range subtests {
+		suite.Run(string(subtest.profile), func() {
+			suite.fixture.Override(suite.T(), map[string]string{"APP_ENV": string(subtest.profile)})
+			testhelper.SwapRegistry(suite.T(), &options.Registry, suite.fixture.Registry(suite.T()))
+
+			servesSwagger := false
+			for _, controller := range CreateControllers() {
+				if _, isSwagger := controller.(swaggercontroller.SwaggerController); isSwagger {
+					servesSwagger = true
+				}
+			}
+			suite.Assert().Equal(subtest.expectsSwagger, servesSwagger)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Command configtool inspects the configuration the microservice accepts without starting the microservice. Run it from
+// the microsvc directory:
+//
+//	go run ./cmd/configtool docs [markdown|jsonschema]  prints a reference of every configuration variable
+//	go run ./cmd/configtool check [path]                 checks a .env, YAML, or JSON file (.env by default) on its own
+//	go run ./cmd/configtool dump [flags...]              prints the effective configuration with secrets redacted
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"example.com/sample/commonlib/config"
+	"example.com/sample/microsvc/options"
+)
+
+// usage describes how to invoke the tool
+const usage = `usage:
+  configtool docs [markdown|jsonschema]
+  configtool check [path]
+  configtool dump [flags...]`
+
+// main is the entrypoint of the configuration tool
+func main() {
+	if len(os.Args) < 2 {
+		exitWithError(usage)
+	}
+
+	arguments := os.Args[2:]
+	switch os.Args[1] {
+	case "docs":
+		printDocs(arguments)
+	case "check":
+		checkFile(arguments)
+	case "dump":
+		dumpConfiguration(arguments)
+	default:
+		exitWithError(usage)
+	}
+}
+
+// printDocs prints a markdown table or JSON Schema document describing every configuration variable
+func printDocs(arguments []string) {
+	format := "markdown"
+	if len(arguments) > 0 {
+		format = arguments[0]
+	}
+
+	regBuilder := options.NewRegistryBuilder()
+	switch format {
+	case "markdown":
+		fmt.Print(config.GenerateMarkdown(regBuilder.Schema()))
+	case "jsonschema":
+		jsonSchema, generateErr := config.GenerateJSONSchema(regBuilder.Schema())
+		if generateErr != nil {
+			exitWithError(fmt.Sprintf("Could not generate JSON Schema: %v", generateErr))
+		}
+		fmt.Println(string(jsonSchema))
+	default:
+		exitWithError(usage)
+	}
+}
+
+// checkFile verifies a configuration file satisfies the configuration schema on its own, exiting with an error status
+// if it doesn't. Variables in the file which the microservice doesn't recognize are reported as warnings.
+func checkFile(arguments []string) {
+	path := ".env"
+	if len(arguments) > 0 {
+		path = arguments[0]
+	}
+
+	var source config.Source
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		source = config.NewFileSource(path, true)
+	default:
+		source = config.NewDotEnvSource(path, true)
+	}
+
+	regBuilder := options.NewRegistryBuilder()
+	unrecognizedNames, checkErr := regBuilder.CheckSource(source)
+	for _, name := range unrecognizedNames {
+		fmt.Printf("warning: %v is not a configuration variable of this microservice\n", name)
+	}
+	if checkErr != nil {
+		exitWithError(fmt.Sprintf("%v is invalid: %v", path, checkErr))
+	}
+
+	fmt.Printf("%v is valid\n", path)
+}
+
+// dumpConfiguration prints the configuration the microservice would start with if it were started from the current
+// directory with the passed flags, along with the source of each value
+func dumpConfiguration(arguments []string) {
+	var remoteSource config.Source
+	if kvSource, kvConfigured := config.KVSourceFromEnvironment(); kvConfigured {
+		remoteSource = kvSource
+	}
+	regBuilder := options.NewRegistryBuilder(config.StandardSourcesWithRemote("config.yaml", ".env", remoteSource, arguments)...)
+	registry, buildErr := regBuilder.VerifyAndBuild()
+	if buildErr != nil {
+		exitWithError(fmt.Sprintf("Configuration is invalid: %v", buildErr))
+	}
+
+	for _, effectiveValue := range registry.Dump() {
+		if effectiveValue.Present {
+			fmt.Printf("%v=%v (from %v)\n", effectiveValue.Name, effectiveValue.Value, effectiveValue.Source)
+		} else {
+			fmt.Printf("%v is not set\n", effectiveValue.Name)
+		}
+	}
+}
+
+// exitWithError prints a message to standard error and exits with an error status
+func exitWithError(message string) {
+	fmt.Fprintln(os.Stderr, message)
+	os.Exit(1)
+}
@@ -0,0 +1,122 @@
+// Command migrate applies and rolls back the microservice's database migrations, using the same database configuration
+// as the microservice itself. Run it from the microsvc directory:
+//
+//	go run ./cmd/migrate up [flags...]      applies every pending migration
+//	go run ./cmd/migrate down [flags...]    rolls back the most recently applied migration
+//	go run ./cmd/migrate status [flags...]  lists every migration and whether it's applied
+//
+// The migrations are the files in db/migrations, which are compatible with dbmate.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"example.com/sample/commonlib/config"
+	"example.com/sample/commonlib/config/sharedoptions"
+	"example.com/sample/commonlib/database"
+	"example.com/sample/commonlib/database/migrate"
+	dbfiles "example.com/sample/microsvc/db"
+	"example.com/sample/microsvc/options"
+)
+
+// usage describes how to invoke the tool
+const usage = `usage:
+  migrate up [flags...]
+  migrate down [flags...]
+  migrate status [flags...]`
+
+// main is the entrypoint of the migration tool
+func main() {
+	if len(os.Args) < 2 {
+		exitWithError(usage)
+	}
+
+	runner := openRunner(os.Args[2:])
+	defer runner.Close()
+
+	ctx := context.Background()
+	switch os.Args[1] {
+	case "up":
+		migrateUp(ctx, runner)
+	case "down":
+		migrateDown(ctx, runner)
+	case "status":
+		printStatus(ctx, runner)
+	default:
+		exitWithError(usage)
+	}
+}
+
+// openRunner reads the database configuration the microservice would start with if it were started from the current
+// directory with the passed flags, and connects a migration runner with it
+func openRunner(arguments []string) *migrate.Runner {
+	var remoteSource config.Source
+	if kvSource, kvConfigured := config.KVSourceFromEnvironment(); kvConfigured {
+		remoteSource = kvSource
+	}
+	regBuilder := options.NewRegistryBuilder(config.StandardSourcesWithRemote("config.yaml", ".env", remoteSource, arguments)...)
+	registry, buildErr := regBuilder.VerifyAndBuild()
+	if buildErr != nil {
+		exitWithError(fmt.Sprintf("Configuration is invalid: %v", buildErr))
+	}
+
+	runner, openErr := migrate.Open(database.ConfigFromGroup(registry, sharedoptions.DefaultDB), dbfiles.Migrations())
+	if openErr != nil {
+		exitWithError(fmt.Sprintf("Could not prepare migrations: %v", openErr))
+	}
+	return runner
+}
+
+// migrateUp applies every pending migration
+func migrateUp(ctx context.Context, runner *migrate.Runner) {
+	applied, upErr := runner.Up(ctx)
+	for _, migration := range applied {
+		fmt.Printf("Applied %v_%v\n", migration.Version, migration.Name)
+	}
+	if upErr != nil {
+		exitWithError(upErr.Error())
+	}
+	if len(applied) == 0 {
+		fmt.Println("No pending migrations")
+	}
+}
+
+// migrateDown rolls back the most recently applied migration
+func migrateDown(ctx context.Context, runner *migrate.Runner) {
+	migration, found, downErr := runner.Down(ctx)
+	if downErr != nil {
+		exitWithError(downErr.Error())
+	}
+	if !found {
+		fmt.Println("No migrations are applied")
+		return
+	}
+	fmt.Printf("Rolled back %v_%v\n", migration.Version, migration.Name)
+}
+
+// printStatus lists every migration and whether it's applied
+func printStatus(ctx context.Context, runner *migrate.Runner) {
+	statuses, statusErr := runner.Status(ctx)
+	if statusErr != nil {
+		exitWithError(statusErr.Error())
+	}
+
+	pendingCount := 0
+	for _, status := range statuses {
+		marker := "[X]"
+		if !status.Applied {
+			marker = "[ ]"
+			pendingCount++
+		}
+		fmt.Printf("%v %v_%v\n", marker, status.Version, status.Name)
+	}
+	fmt.Printf("\nApplied: %v\nPending: %v\n", len(statuses)-pendingCount, pendingCount)
+}
+
+// exitWithError prints a message to standard error and exits with an error status
+func exitWithError(message string) {
+	fmt.Fprintln(os.Stderr, message)
+	os.Exit(1)
+}
@@ -0,0 +1,22 @@
+// Package db holds the microservice's database migrations, which are embedded in the binary so the microservice can
+// apply them itself with the migrate package. They can still be managed with dbmate, see the README.
+package db
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations returns the microservice's migration files, for use with migrate.Open
+func Migrations() fs.FS {
+	migrations, subErr := fs.Sub(migrationFiles, "migrations")
+	if subErr != nil {
+		// The directory name is a constant, so this only happens if it's invalid
+		panic(subErr)
+	}
+
+	return migrations
+}
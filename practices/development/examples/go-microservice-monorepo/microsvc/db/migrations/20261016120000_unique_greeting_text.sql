@@ -0,0 +1,9 @@
+-- migrate:up
+
+--
+-- Reject duplicate greetings in the database, so concurrent requests can't add the same one twice
+--
+CREATE UNIQUE INDEX greetings_greetingText_unique ON greetings (greetingText);
+
+-- migrate:down
+DROP INDEX greetings_greetingText_unique ON greetings;
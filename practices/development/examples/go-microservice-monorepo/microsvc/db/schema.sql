@@ -18,7 +18,8 @@
 CREATE TABLE `greetings` (
   `id` int(11) NOT NULL AUTO_INCREMENT,
   `greetingText` varchar(32) NOT NULL,
-  PRIMARY KEY (`id`)
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `greetings_greetingText_unique` (`greetingText`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
@@ -55,5 +56,6 @@ CREATE TABLE `schema_migrations` (
 
 LOCK TABLES `schema_migrations` WRITE;
 INSERT INTO `schema_migrations` (version) VALUES
-  ('20240122162558');
+  ('20240122162558'),
+  ('20261016120000');
 UNLOCK TABLES;
@@ -2,7 +2,9 @@ package adapter
 
 import (
 	"context"
+	"errors"
 	"example.com/sample/commonlib/database"
+	"example.com/sample/microsvc/features/sample"
 	"fmt"
 )
 
@@ -12,11 +14,15 @@ type DatabaseGreetingWriter struct{}
 // AddGreeting implements GreetingWriter for DatabaseGreetingWriter
 func (DatabaseGreetingWriter) AddGreeting(ctx context.Context, newGreeting string) error {
 	db := database.RetrieveFromContext(ctx)
-	_, insertErr := db.Exec(`
+	_, insertErr := db.Exec(db.Rebind(`
 		insert into greetings(greetingText) values (?)
-	`, newGreeting)
+	`), newGreeting)
 
-	if insertErr != nil {
+	// The unique index on greetingText rejects duplicates, even when two requests add the same greeting at once
+	insertErr = database.ClassifyError(insertErr)
+	if errors.Is(insertErr, database.ErrDuplicateKey) {
+		return fmt.Errorf("%w: %v: %w", sample.ErrGreetingAlreadyExists, newGreeting, insertErr)
+	} else if insertErr != nil {
 		return fmt.Errorf("failed to add greeting \"%v\": %w", newGreeting, insertErr)
 	}
 
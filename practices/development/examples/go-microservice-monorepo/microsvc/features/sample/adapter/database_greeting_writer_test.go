@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"example.com/sample/commonlib/database"
+	"example.com/sample/microsvc/features/sample"
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/mock/gomock"
 	"testing"
@@ -24,6 +26,8 @@ func (suite *DatabaseGreetingWriterSuite) SetupTest() {
 	suite.mockController = gomock.NewController(suite.T())
 	suite.mockConnection = database.NewMockConnection(suite.mockController)
 	suite.connContext = database.CreateDerivativeMockContext(context.Background(), suite.mockConnection)
+	// Leave queries as written, like the MySQL driver does
+	suite.mockConnection.EXPECT().Rebind(gomock.Any()).DoAndReturn(func(query string) string { return query }).AnyTimes()
 }
 
 func (suite *DatabaseGreetingWriterSuite) TearDownTest() {
@@ -46,3 +50,12 @@ func (suite *DatabaseGreetingWriterSuite) TestAddGreetingFailsOnDbFail() {
 	addErr := DatabaseGreetingWriter{}.AddGreeting(suite.connContext, "G'day")
 	suite.Assert().ErrorIs(addErr, expectedErr)
 }
+
+func (suite *DatabaseGreetingWriterSuite) TestAddGreetingFailsOnDuplicate() {
+	duplicateErr := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'Hi' for key 'greetings.greetings_greetingText_unique'"}
+	suite.mockConnection.EXPECT().Exec(gomock.Any(), "Hi").Return(nil, duplicateErr)
+
+	addErr := DatabaseGreetingWriter{}.AddGreeting(suite.connContext, "Hi")
+	suite.Assert().ErrorIs(addErr, sample.ErrGreetingAlreadyExists)
+	suite.Assert().ErrorIs(addErr, duplicateErr)
+}
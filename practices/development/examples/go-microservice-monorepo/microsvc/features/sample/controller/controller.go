@@ -92,8 +92,10 @@ func (t SampleController) ProduceGreeting(ctx echo.Context, requestedGreeting Sa
 // @Router       /api/v1/sample/greetings [post]
 func (t SampleController) AddGreeting(ctx echo.Context, newGreeting NewGreetingRequest) error {
 	requestCtx := request.ExtractContext(ctx)
-	addErr := database.WithTransaction(requestCtx, func(dbCtx context.Context) error {
-		return t.sampleLogic.AddGreeting(dbCtx, newGreeting.Greeting, t.greetingReader, t.greetingWriter)
+	// Adding a greeting can be re-run safely, so retry it if it deadlocks with another request
+	txOptions := database.TxOptions{Retry: database.DefaultRetryPolicy}
+	addErr := database.WithTransactionOptions(requestCtx, txOptions, func(dbCtx context.Context) error {
+		return t.sampleLogic.AddGreeting(dbCtx, newGreeting.Greeting, t.greetingWriter)
 	})
 
 	// Decide how to respond based on business logic error
@@ -66,7 +66,7 @@ func (suite *SampleControllerSuite) TestProduceGreeting() {
 }
 
 func (suite *SampleControllerSuite) TestAddGreeting() {
-	suite.mockCore.EXPECT().AddGreeting(gomock.Any(), "G'day", gomock.Any(), gomock.Any()).Return(nil)
+	suite.mockCore.EXPECT().AddGreeting(gomock.Any(), "G'day", gomock.Any()).Return(nil)
 	requestBody := NewGreetingRequest{
 		Greeting: "G'day",
 	}
@@ -86,7 +86,7 @@ func (suite *SampleControllerSuite) TestAddGreeting() {
 
 func (suite *SampleControllerSuite) TestAddGreetingRespondsConflictOnExistingGreeting() {
 	suite.mockCore.EXPECT().
-		AddGreeting(gomock.Any(), "G'day", gomock.Any(), gomock.Any()).
+		AddGreeting(gomock.Any(), "G'day", gomock.Any()).
 		Return(sample.ErrGreetingAlreadyExists)
 	requestBody := NewGreetingRequest{
 		Greeting: "G'day",
@@ -6,7 +6,6 @@ import (
 	"example.com/sample/commonlib/logger"
 	"fmt"
 	"go.uber.org/zap"
-	"slices"
 )
 
 // The following comment instructs the "go generate" command to run mockgen to generate our test mocks
@@ -25,7 +24,8 @@ type GreetingReader interface {
 // GreetingWriter is a driven port for something that writes to the collection of greetings. It is "driven" by the business
 // logic, and represents information that is fetched from outside the application
 type GreetingWriter interface {
-	// AddGreeting adds a new greeting to the set of available greetings
+	// AddGreeting adds a new greeting to the set of available greetings. It returns ErrGreetingAlreadyExists if the
+	// greeting is already in the set.
 	AddGreeting(ctx context.Context, newGreeting string) error
 }
 
@@ -39,7 +39,7 @@ type Core interface {
 	GiveGreeting(ctx context.Context, name string, greetingReader GreetingReader) (string, error)
 	// AddGreeting adds a new greeting to the set of greetings used in GiveGreeting. It returns ErrGreetingAlreadyExists if
 	// trying to insert a duplicate.
-	AddGreeting(ctx context.Context, newGreeting string, greetingReader GreetingReader, greetingWriter GreetingWriter) error
+	AddGreeting(ctx context.Context, newGreeting string, greetingWriter GreetingWriter) error
 }
 
 // CoreLogic implements the core business logic of the sample feature which is plugged into the input adapter of
@@ -59,15 +59,7 @@ func (CoreLogic) GiveGreeting(ctx context.Context, name string, greetingReader G
 }
 
 // AddGreeting implements Core for CoreLogic
-func (CoreLogic) AddGreeting(ctx context.Context, newGreeting string, greetingReader GreetingReader, greetingWriter GreetingWriter) error {
-	greetings, greetingReadErr := greetingReader.List(ctx)
-	if greetingReadErr != nil {
-		return fmt.Errorf("failed to get the list of greetings: %w", greetingReadErr)
-	}
-
-	if slices.Contains(greetings, newGreeting) {
-		return fmt.Errorf("%w: %v", ErrGreetingAlreadyExists, newGreeting)
-	}
-
+func (CoreLogic) AddGreeting(ctx context.Context, newGreeting string, greetingWriter GreetingWriter) error {
+	// The writer detects duplicates itself, since checking for one before writing would race with other requests
 	return greetingWriter.AddGreeting(ctx, newGreeting)
 }
@@ -129,17 +129,17 @@ func (m *MockCore) EXPECT() *MockCoreMockRecorder {
 }
 
 // AddGreeting mocks base method.
-func (m *MockCore) AddGreeting(arg0 context.Context, arg1 string, arg2 GreetingReader, arg3 GreetingWriter) error {
+func (m *MockCore) AddGreeting(arg0 context.Context, arg1 string, arg2 GreetingWriter) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddGreeting", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "AddGreeting", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // AddGreeting indicates an expected call of AddGreeting.
-func (mr *MockCoreMockRecorder) AddGreeting(arg0, arg1, arg2, arg3 any) *gomock.Call {
+func (mr *MockCoreMockRecorder) AddGreeting(arg0, arg1, arg2 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddGreeting", reflect.TypeOf((*MockCore)(nil).AddGreeting), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddGreeting", reflect.TypeOf((*MockCore)(nil).AddGreeting), arg0, arg1, arg2)
 }
 
 // GiveGreeting mocks base method.
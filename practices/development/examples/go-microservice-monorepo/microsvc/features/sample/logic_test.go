@@ -53,46 +53,35 @@ func (suite *SampleLogicSuite) TestGiveGreetingFailsWhenReaderFails() {
 }
 
 func (suite *SampleLogicSuite) TestAddGreetingAddsGreeting() {
-	greetingReader := NewMockGreetingReader(suite.controller)
 	greetingWriter := NewMockGreetingWriter(suite.controller)
 
-	listCall := greetingReader.EXPECT().
-		List(gomock.Any()).
-		Return([]string{"Hi", "Hello", "Howdy"}, nil)
 	greetingWriter.EXPECT().
 		AddGreeting(gomock.Any(), "G'day").
-		After(listCall).
 		Return(nil)
 
-	addErr := CoreLogic{}.AddGreeting(context.Background(), "G'day", greetingReader, greetingWriter)
+	addErr := CoreLogic{}.AddGreeting(context.Background(), "G'day", greetingWriter)
 	suite.Assert().NoError(addErr)
 }
 
 func (suite *SampleLogicSuite) TestAddingDuplicateGreetingFails() {
-	greetingReader := NewMockGreetingReader(suite.controller)
 	greetingWriter := NewMockGreetingWriter(suite.controller)
 
-	greetingReader.EXPECT().
-		List(gomock.Any()).
-		Return([]string{"Hi", "Hello", "Howdy"}, nil)
+	greetingWriter.EXPECT().
+		AddGreeting(gomock.Any(), "Hi").
+		Return(ErrGreetingAlreadyExists)
 
-	addErr := CoreLogic{}.AddGreeting(context.Background(), "Hi", greetingReader, greetingWriter)
+	addErr := CoreLogic{}.AddGreeting(context.Background(), "Hi", greetingWriter)
 	suite.Assert().ErrorIs(addErr, ErrGreetingAlreadyExists)
 }
 
 func (suite *SampleLogicSuite) TestAddingGreetingFailsOnDbFail() {
-	greetingReader := NewMockGreetingReader(suite.controller)
 	greetingWriter := NewMockGreetingWriter(suite.controller)
 	expectedErr := errors.New("oops")
 
-	readerCall := greetingReader.EXPECT().
-		List(gomock.Any()).
-		Return([]string{"Hi", "Hello", "Howdy"}, nil)
 	greetingWriter.EXPECT().
 		AddGreeting(gomock.Any(), "G'day").
-		After(readerCall).
 		Return(expectedErr)
 
-	addErr := CoreLogic{}.AddGreeting(context.Background(), "G'day", greetingReader, greetingWriter)
+	addErr := CoreLogic{}.AddGreeting(context.Background(), "G'day", greetingWriter)
 	suite.Assert().ErrorIs(addErr, expectedErr)
 }
@@ -21,9 +21,9 @@ import (
 
 // main is the entrypoint of the microservice
 func main() {
-	db := PrepareSubsystems()
+	cluster := PrepareSubsystems()
 
 	logger.Log.Info("Starting example microservice...")
-	router := Bootstrap(db)
+	router := Bootstrap(cluster)
 	router.Listen(options.Registry)
 }
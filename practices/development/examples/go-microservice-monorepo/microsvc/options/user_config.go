@@ -1,36 +1,66 @@
 package options
 
 import (
+	"os"
+
 	"example.com/sample/commonlib/config"
 	"example.com/sample/commonlib/config/sharedoptions"
 )
 
-// Registry is the global configuration registry of verified environment variables
+// Registry is the global configuration registry of verified environment variables, as they were loaded at startup
 var Registry *config.Registry
 
-// buildRegistry attaches configuration options to the passed config.RegistryBuilder and attempts the verification
-// and build process to verify environment variables are what we expect. It returns an error if required variables
-// aren't present or some environment variables didn't pass validation
-func buildRegistry(regBuilder config.RegistryBuilder) error {
-	regBuilder.AddOptions([]config.Option{
-		sharedoptions.IsInProduction,
+// Reloadable is the global reloadable configuration registry. Its current snapshot starts out identical to Registry,
+// but it is reloaded while the service runs, so components which should pick up configuration changes without a
+// restart should use it instead of Registry.
+var Reloadable *config.ReloadableRegistry
+
+// KVSource is the key/value store shared configuration is read from, or nil if CONFIG_KV_ADDRESS isn't set
+var KVSource *config.KVSource
+
+// NewRegistryBuilder constructs a config.RegistryBuilder reading from the passed sources, with every configuration
+// option the microservice accepts and every rule relating them registered. Besides building the registry at startup,
+// it's the source of the configuration schema used by cmd/configtool to generate documentation and check files.
+func NewRegistryBuilder(sources ...config.Source) config.RegistryBuilder {
+	regBuilder := config.NewLayeredRegistryBuilder(sources...)
+	regBuilder.AddOptions([]config.AnyOption{
+		sharedoptions.AppEnv,
 		sharedoptions.LogLevel,
 		sharedoptions.AllowedOrigins,
 		sharedoptions.ListenPort,
 	})
 	regBuilder.AddOptions(sharedoptions.DBOptions)
+	regBuilder.AddOptions([]config.AnyOption{sharedoptions.DBMigrateOnStartup, sharedoptions.DBSchemaCheck})
+	regBuilder.AddRule(sharedoptions.ProductionCorsRule)
+	regBuilder.AddRules(sharedoptions.DBRules)
 
-	registry, buildErr := regBuilder.VerifyAndBuild()
+	return regBuilder
+}
+
+// buildRegistry attempts the verification and build process on the passed config.RegistryBuilder to verify
+// environment variables are what we expect. It returns an error if required variables aren't present or some
+// environment variables didn't pass validation
+func buildRegistry(regBuilder config.RegistryBuilder) error {
+	reloadableRegistry, buildErr := config.NewReloadableRegistry(regBuilder)
 	if buildErr != nil {
 		return buildErr
 	}
 
-	Registry = &registry
+	initialRegistry := reloadableRegistry.Current()
+	Registry = &initialRegistry
+	Reloadable = reloadableRegistry
 	return nil
 }
 
-// InitRegistry initializes the global configuration registry, Registry
+// InitRegistry initializes the global configuration registries, Registry and Reloadable. Options are read from an optional config.yaml
+// file, an optional .env file, a key/value store if CONFIG_KV_ADDRESS is set, the environment, and command-line flags,
+// with each source overriding the ones before it.
 func InitRegistry() error {
-	regBuilder := config.NewRegistryBuilder()
-	return buildRegistry(regBuilder)
+	var remoteSource config.Source
+	if kvSource, kvConfigured := config.KVSourceFromEnvironment(); kvConfigured {
+		KVSource = kvSource
+		remoteSource = kvSource
+	}
+
+	return buildRegistry(NewRegistryBuilder(config.StandardSourcesWithRemote("config.yaml", ".env", remoteSource, os.Args[1:])...))
 }